@@ -17,10 +17,15 @@ import (
 	echoSwagger "github.com/swaggo/echo-swagger"
 
 	"crontab/internal/config"
+	"crontab/internal/migrations"
 	"crontab/internal/routes"
 	"crontab/internal/models"
+	"crontab/pkg/executor"
 	"crontab/pkg/logger"
+	"crontab/pkg/metrics"
+	"crontab/pkg/notify"
 	"crontab/pkg/scheduler"
+	"crontab/pkg/tracing"
 )
 
 // @title CronTab API
@@ -38,6 +43,9 @@ import (
 // @BasePath /api
 // @schemes http https
 func main() {
+	// Shell jobs with resource limits start through this executable, see executor.Init
+	executor.Init()
+	
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
@@ -49,25 +57,50 @@ func main() {
 	// Initialize logger
 	logger := logger.New(cfg)
 	
+	// Initialize OpenTelemetry tracing
+	shutdownTracing, err := tracing.Setup(cfg)
+	if err != nil {
+		logger.Fatal("Failed to set up tracing: %v", err)
+	}
+	
+	// Accounts jobs may run as
+	models.ConfigureRunAs(cfg)
+	
 	// Connect to database
 	db, err := models.SetupDatabase(cfg)
 	if err != nil {
-		logger.Fatal("Failed to connect to database", "error", err)
+		logger.Fatal("Failed to connect to database: %v", err)
 	}
 	
-	// Auto migrate database models
-	if err := models.MigrateDatabase(db); err != nil {
-		logger.Fatal("Failed to migrate database", "error", err)
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		logger.Fatal("Failed to register database tracing: %v", err)
+	}
+	
+	// Apply pending schema migrations, or refuse to start when they are managed separately
+	migrator := migrations.New(db, logger.Info)
+	if cfg.GetBool("DB_AUTO_MIGRATE", true) {
+		if _, err := migrator.Up(); err != nil {
+			logger.Fatal("Failed to migrate database: %v", err)
+		}
+	} else if err := migrator.RequireCurrent(); err != nil {
+		logger.Fatal("Refusing to start: %v", err)
 	}
 
+	// Initialize Prometheus metrics
+	metrics := metrics.New(cfg)
+	
 	// Initialize Echo server
 	e := echo.New()
 	e.HideBanner = true
 	
 	// Middleware
+	e.Use(tracing.EchoMiddleware())
+	e.Use(metrics.EchoMiddleware())
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
-	e.Use(middleware.CORS())
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		ExposeHeaders: []string{"ETag"}, // Browser clients need it for If-Match
+	}))
 	e.Use(middleware.SecureWithConfig(middleware.SecureConfig{
 		XSSProtection:         "1; mode=block",
 		ContentTypeNosniff:    "nosniff",
@@ -79,19 +112,25 @@ func main() {
 	// Swagger documentation
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 	
+	// Initialize notifications
+	dispatcher := notify.NewDispatcher(db, logger, cfg)
+	
 	// Initialize job scheduler
-	scheduler := scheduler.New(db, logger)
+	scheduler := scheduler.New(db, logger, metrics)
+	scheduler.SetMaxConcurrent(cfg.GetInt("SCHEDULER_MAX_CONCURRENT", 0))
+	scheduler.SetNotifier(dispatcher)
+	scheduler.SetTrashRetention(time.Duration(cfg.GetInt("TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour)
 	go scheduler.Start()
 	defer scheduler.Stop()
 	
 	// Setup routes
-	routes.SetupRoutes(e, db, cfg, scheduler)
+	routes.SetupRoutes(e, db, cfg, logger, scheduler, metrics, dispatcher)
 	
 	// Start server
 	go func() {
 		port := cfg.GetString("PORT", "3000")
 		if err := e.Start(fmt.Sprintf(":%s", port)); err != nil && err != http.ErrServerClosed {
-			logger.Fatal("Failed to start server", "error", err)
+			logger.Fatal("Failed to start server: %v", err)
 		}
 	}()
 	
@@ -106,7 +145,11 @@ func main() {
 	defer cancel()
 	
 	if err := e.Shutdown(ctx); err != nil {
-		logger.Fatal("Server shutdown failed", "error", err)
+		logger.Fatal("Server shutdown failed: %v", err)
+	}
+	
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("Failed to flush traces: %v", err)
 	}
 	
 	logger.Info("Server gracefully stopped")
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/joho/godotenv"
+
+	"crontab/internal/config"
+	"crontab/internal/migrations"
+	"crontab/internal/models"
+)
+
+// backfill rebuilds the per-job run statistics from the job_logs table, and the search index
+// entries of jobs and their runs.
+//
+// Usage:
+//
+//	go run cmd/backfill/main.go            # rebuild every job
+//	go run cmd/backfill/main.go -job <id>  # rebuild a single job
+func main() {
+	jobID := flag.String("job", "", "only rebuild the statistics and search index of this job ID")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg := config.New()
+
+	db, err := models.SetupDatabase(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	if err := migrations.New(db, nil).RequireCurrent(); err != nil {
+		log.Fatalf("Refusing to backfill: %v", err)
+	}
+
+	var jobIDs []string
+	if *jobID != "" {
+		jobIDs = []string{*jobID}
+	} else if err := db.Model(&models.Job{}).Pluck("id", &jobIDs).Error; err != nil {
+		log.Fatalf("Failed to list jobs: %v", err)
+	}
+
+	failed := 0
+	for _, id := range jobIDs {
+		stats, err := models.RebuildJobRunStats(db, id)
+		if err != nil {
+			log.Printf("Failed to rebuild stats for job %s: %v", id, err)
+			failed++
+			continue
+		}
+		log.Printf("Rebuilt stats for job %s: %d runs, average %.2fs", id, stats.RunCount, stats.AverageDuration())
+
+		runs, err := models.RebuildSearchIndex(db, id)
+		if err != nil {
+			log.Printf("Failed to rebuild search index for job %s: %v", id, err)
+			failed++
+			continue
+		}
+		log.Printf("Indexed job %s and %d runs for search", id, runs)
+	}
+
+	log.Printf("Backfill finished: %d jobs rebuilt, %d failed", len(jobIDs)-failed, failed)
+	if failed > 0 {
+		log.Fatalf("Backfill incomplete")
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+
+	"crontab/internal/config"
+	"crontab/internal/migrations"
+	"crontab/internal/models"
+)
+
+// migrate applies and rolls back the versioned schema migrations.
+//
+// Usage:
+//
+//	go run cmd/migrate/main.go up            # apply every pending migration
+//	go run cmd/migrate/main.go down [n]      # roll back the last n migrations (default 1)
+//	go run cmd/migrate/main.go status        # list migrations and whether they are applied
+//	go run cmd/migrate/main.go to <version>  # migrate up or down to a version, 0 rolls back everything
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: migrate up | down [n] | status | to <version>")
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	cfg := config.New()
+
+	db, err := models.SetupDatabase(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	migrator := migrations.New(db, log.Printf)
+
+	switch flag.Arg(0) {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			log.Fatalf("Migration failed after %d applied: %v", applied, err)
+		}
+		log.Printf("%d migration(s) applied", applied)
+
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			if steps, err = strconv.Atoi(flag.Arg(1)); err != nil || steps < 1 {
+				log.Fatalf("Invalid number of migrations %q", flag.Arg(1))
+			}
+		}
+		rolledBack, err := migrator.Down(steps)
+		if err != nil {
+			log.Fatalf("Rollback failed after %d rolled back: %v", rolledBack, err)
+		}
+		log.Printf("%d migration(s) rolled back", rolledBack)
+
+	case "to":
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		version, err := strconv.ParseInt(flag.Arg(1), 10, 64)
+		if err != nil || version < 0 {
+			log.Fatalf("Invalid version %q", flag.Arg(1))
+		}
+		changed, err := migrator.To(version)
+		if err != nil {
+			log.Fatalf("Migration failed after %d change(s): %v", changed, err)
+		}
+		log.Printf("%d migration(s) applied or rolled back, schema is at version %d", changed, version)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, appliedAt := "pending", ""
+			if s.Applied {
+				state = "applied"
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Unknown {
+				state = "unknown"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		w.Flush()
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	
+	"crontab/internal/models"
+)
+
+type AlertHandler struct {
+	db *gorm.DB
+}
+
+func NewAlertHandler(db *gorm.DB) *AlertHandler {
+	return &AlertHandler{db: db}
+}
+
+// alertRuleRequest is the body accepted when creating or updating an alert rule
+type alertRuleRequest struct {
+	Name      string               `json:"name"`
+	Type      models.AlertRuleType `json:"type"`
+	Threshold float64              `json:"threshold"`
+	Window    int                  `json:"window"`
+	Enabled   *bool                `json:"enabled"`
+}
+
+// GetJobAlertRules godoc
+// @Summary Get alert rules of a job
+// @Description Retrieves the alert rules evaluated after every run of a job
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Router /jobs/{id}/alert-rules [get]
+func (h *AlertHandler) GetJobAlertRules(c echo.Context) error {
+	jobID := c.Param("id")
+	
+	var rules []models.AlertRule
+	if err := h.db.Where("job_id = ?", jobID).Order("created_at").Find(&rules).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to fetch alert rules: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    rules,
+	})
+}
+
+// CreateAlertRule godoc
+// @Summary Create an alert rule
+// @Description Creates a consecutive_failures, success_rate or slow_run alert rule for a job
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param rule body alertRuleRequest true "Alert rule details"
+// @Success 201 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /jobs/{id}/alert-rules [post]
+func (h *AlertHandler) CreateAlertRule(c echo.Context) error {
+	jobID := c.Param("id")
+	
+	var job models.Job
+	if err := h.db.First(&job, "id = ?", jobID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Job not found",
+		})
+	}
+
+	req := new(alertRuleRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid request data: " + err.Error(),
+		})
+	}
+
+	rule := models.AlertRule{
+		JobID:     job.ID,
+		Name:      req.Name,
+		Type:      req.Type,
+		Threshold: req.Threshold,
+		Window:    req.Window,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+	}
+	if err := rule.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid alert rule: " + err.Error(),
+		})
+	}
+
+	if err := h.db.Create(&rule).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to create alert rule: " + err.Error(),
+		})
+	}
+	recordAudit(c, h.db, models.AuditAlertRuleCreate, "alert_rule", rule.ID, nil, rule)
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    rule,
+		"message": "Alert rule created successfully",
+	})
+}
+
+// UpdateAlertRule godoc
+// @Summary Update an alert rule
+// @Description Updates an alert rule. Disabling a rule resolves its open alert.
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Alert rule ID"
+// @Param rule body alertRuleRequest true "Alert rule details"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /alert-rules/{id} [put]
+func (h *AlertHandler) UpdateAlertRule(c echo.Context) error {
+	id := c.Param("id")
+	
+	var rule models.AlertRule
+	if err := h.db.First(&rule, "id = ?", id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Alert rule not found",
+		})
+	}
+
+	req := new(alertRuleRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid request data: " + err.Error(),
+		})
+	}
+
+	before := rule
+	rule.Name = req.Name
+	rule.Type = req.Type
+	rule.Threshold = req.Threshold
+	rule.Window = req.Window
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if err := rule.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid alert rule: " + err.Error(),
+		})
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&rule).Error; err != nil {
+			return err
+		}
+		if !rule.Enabled {
+			return models.ResolveRuleAlerts(tx, rule.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to update alert rule: " + err.Error(),
+		})
+	}
+	recordAudit(c, h.db, models.AuditAlertRuleUpdate, "alert_rule", rule.ID, before, rule)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    rule,
+		"message": "Alert rule updated successfully",
+	})
+}
+
+// DeleteAlertRule godoc
+// @Summary Delete an alert rule
+// @Description Deletes an alert rule and resolves its open alert. Past alerts are kept.
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Alert rule ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /alert-rules/{id} [delete]
+func (h *AlertHandler) DeleteAlertRule(c echo.Context) error {
+	id := c.Param("id")
+	
+	var rule models.AlertRule
+	if err := h.db.First(&rule, "id = ?", id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Alert rule not found",
+		})
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := models.ResolveRuleAlerts(tx, rule.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&rule).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to delete alert rule: " + err.Error(),
+		})
+	}
+	recordAudit(c, h.db, models.AuditAlertRuleDelete, "alert_rule", rule.ID, rule, nil)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Alert rule deleted successfully",
+	})
+}
+
+// GetAlerts godoc
+// @Summary Get alerts
+// @Description Retrieves alerts, newest first, optionally filtered by status, job or project
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param status query string false "Filter by status (firing, resolved)"
+// @Param jobId query string false "Filter by job ID"
+// @Param projectId query string false "Filter by project ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Router /alerts [get]
+func (h *AlertHandler) GetAlerts(c echo.Context) error {
+	query := h.db.Model(&models.Alert{})
+	if status := c.QueryParam("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if jobID := c.QueryParam("jobId"); jobID != "" {
+		query = query.Where("job_id = ?", jobID)
+	}
+	if projectID := c.QueryParam("projectId"); projectID != "" {
+		query = query.Where("job_id IN (?)", h.db.Model(&models.Job{}).Select("id").Where("project_id = ?", projectID))
+	}
+
+	return h.listAlerts(c, query)
+}
+
+// GetJobAlerts godoc
+// @Summary Get alerts of a job
+// @Description Retrieves the alerts raised for a job, newest first
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param status query string false "Filter by status (firing, resolved)"
+// @Success 200 {object} map[string]interface{} "success"
+// @Router /jobs/{id}/alerts [get]
+func (h *AlertHandler) GetJobAlerts(c echo.Context) error {
+	query := h.db.Model(&models.Alert{}).Where("job_id = ?", c.Param("id"))
+	if status := c.QueryParam("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	return h.listAlerts(c, query)
+}
+
+func (h *AlertHandler) listAlerts(c echo.Context, query *gorm.DB) error {
+	var alerts []models.Alert
+	if err := query.Order("fired_at DESC").Limit(200).Find(&alerts).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to fetch alerts: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    alerts,
+	})
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"crontab/internal/models"
+	"crontab/internal/repository"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+type AuditHandler struct {
+	audit repository.AuditRepository
+}
+
+func NewAuditHandler(audit repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{audit: audit}
+}
+
+// GetAuditEntries godoc
+// @Summary Get the audit log
+// @Description Retrieves audit entries, newest first. Admin only.
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Param actor query string false "Filter by actor email or user ID"
+// @Param action query string false "Filter by action, e.g. job.update"
+// @Param resourceType query string false "Filter by resource type, e.g. job"
+// @Param resourceId query string false "Filter by resource ID"
+// @Param from query string false "Start of the range (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "End of the range (RFC 3339 or YYYY-MM-DD)"
+// @Param limit query int false "Maximum number of entries (default 100, max 1000)"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Router /audit [get]
+func (h *AuditHandler) GetAuditEntries(c echo.Context) error {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	limit := defaultAuditLimit
+	if value := c.QueryParam("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"error":   "limit must be between 1 and " + strconv.Itoa(maxAuditLimit),
+			})
+		}
+	}
+
+	entries, err := h.audit.List(c.Request().Context(), filter, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to fetch audit entries: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    entries,
+	})
+}
+
+// ExportAuditEntries godoc
+// @Summary Export the audit log
+// @Description Streams the audit entries matching the filters as CSV, newest first. Admin only.
+// @Tags audit
+// @Produce text/csv
+// @Param actor query string false "Filter by actor email or user ID"
+// @Param action query string false "Filter by action, e.g. job.update"
+// @Param resourceType query string false "Filter by resource type, e.g. job"
+// @Param resourceId query string false "Filter by resource ID"
+// @Param from query string false "Start of the range (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "End of the range (RFC 3339 or YYYY-MM-DD)"
+// @Success 200 {string} string "CSV"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Router /audit/export [get]
+func (h *AuditHandler) ExportAuditEntries(c echo.Context) error {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	// The response starts with the first entry, so that errors found before can still be answered with JSON
+	res := c.Response()
+	w := csv.NewWriter(res)
+	started := false
+	start := func() {
+		started = true
+		res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="audit.csv"`)
+		res.WriteHeader(http.StatusOK)
+		w.Write([]string{"time", "actor", "actorId", "action", "resourceType", "resourceId", "ip", "userAgent", "before", "after"})
+	}
+
+	err = h.audit.Each(c.Request().Context(), filter, func(entry *models.AuditEntry) error {
+		if !started {
+			start()
+		}
+		w.Write([]string{
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+			entry.Actor,
+			entry.ActorID,
+			string(entry.Action),
+			entry.ResourceType,
+			entry.ResourceID,
+			entry.IP,
+			entry.UserAgent,
+			entry.BeforeJSON,
+			entry.AfterJSON,
+		})
+		w.Flush()
+		return w.Error()
+	})
+	if err != nil && !started {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to fetch audit entries: " + err.Error(),
+		})
+	}
+	if err != nil {
+		// Too late for an error response; the export ends early
+		c.Logger().Errorf("Failed to export audit entries: %v", err)
+		return nil
+	}
+	if !started {
+		start()
+		w.Flush()
+	}
+	return w.Error()
+}
+
+// parseAuditFilter reads the audit filters from the query string
+func parseAuditFilter(c echo.Context) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		Actor:        c.QueryParam("actor"),
+		Action:       c.QueryParam("action"),
+		ResourceType: c.QueryParam("resourceType"),
+		ResourceID:   c.QueryParam("resourceId"),
+	}
+	if value := c.QueryParam("from"); value != "" {
+		t, err := parseTimeParam(value)
+		if err != nil {
+			return filter, err
+		}
+		filter.From = t
+	}
+	if value := c.QueryParam("to"); value != "" {
+		t, err := parseTimeParam(value)
+		if err != nil {
+			return filter, err
+		}
+		filter.To = t
+	}
+	return filter, nil
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+
+	"crontab/internal/models"
+)
+
+func TestAuditHandlerFiltersAndExportsEntries(t *testing.T) {
+	s := newTestServer(t)
+	first, second := s.job(t, "", "Backup"), s.job(t, "", "Cleanup")
+	s.project(t, "Ops")
+
+	var entries []models.AuditEntry
+	decode(t, s.do(t, http.MethodGet, "/audit?action=job.create", nil), &entries)
+	if len(entries) != 2 || entries[0].ResourceID != second.ID || entries[1].ResourceID != first.ID {
+		t.Errorf("entries = %+v, want both job creations newest first", entries)
+	}
+	decode(t, s.do(t, http.MethodGet, "/audit?limit=1", nil), &entries)
+	if len(entries) != 1 || entries[0].ResourceType != "project" {
+		t.Errorf("entries = %+v, want only the newest entry", entries)
+	}
+	if rec := s.do(t, http.MethodGet, "/audit?limit=0", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("limit 0 = %d, want 400", rec.Code)
+	}
+
+	rec := s.do(t, http.MethodGet, "/audit/export?resourceId="+first.ID, nil)
+	rows, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0][0] != "time" || rows[1][3] != string(models.AuditJobCreate) || rows[1][5] != first.ID {
+		t.Errorf("export = %v, want the header and the creation of the first job", rows)
+	}
+}
@@ -112,6 +112,10 @@ func (h *AuthHandler) Register(c echo.Context) error {
 	// Remove password from response
 	user.Password = ""
 
+	entry := newAuditEntry(c, models.AuditRegister, "user", user.ID, nil, user)
+	entry.Actor, entry.ActorID = user.Email, user.ID
+	saveAuditEntry(c, h.db, entry)
+
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"success": true,
 		"data":    user,
@@ -141,6 +145,7 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	// Find user by email
 	var user models.User
 	if err := h.db.Preload("Role").Where("email = ?", req.Email).First(&user).Error; err != nil {
+		h.auditLogin(c, models.AuditLoginFailed, req.Email, "")
 		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
 			"success": false,
 			"error":   "User not found",
@@ -150,6 +155,7 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	// Verify password
 	hashedPassword := h.hashPassword(req.Password)
 	if hashedPassword != user.Password {
+		h.auditLogin(c, models.AuditLoginFailed, user.Email, user.ID)
 		return c.JSON(http.StatusUnauthorized, map[string]interface{}{
 			"success": false,
 			"error":   "Invalid password",
@@ -158,6 +164,7 @@ func (h *AuthHandler) Login(c echo.Context) error {
 
 	// Generate token
 	token := h.generateToken(user)
+	h.auditLogin(c, models.AuditLogin, user.Email, user.ID)
 
 	// Remove password from response
 	user.Password = ""
@@ -224,6 +231,13 @@ func (h *AuthHandler) ValidateToken(c echo.Context) error {
 
 // Helper functions
 
+// auditLogin records a login attempt. The request is not authenticated yet, so the actor is taken from the credentials.
+func (h *AuthHandler) auditLogin(c echo.Context, action models.AuditAction, email, userID string) {
+	entry := newAuditEntry(c, action, "user", userID, nil, nil)
+	entry.Actor, entry.ActorID = email, userID
+	saveAuditEntry(c, h.db, entry)
+}
+
 // hashPassword creates a SHA-256 hash of a password
 func (h *AuthHandler) hashPassword(password string) string {
 	hash := sha256.Sum256([]byte(password))
@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	
+	"crontab/internal/models"
+	"crontab/pkg/notify"
+)
+
+type ChannelHandler struct {
+	db         *gorm.DB
+	dispatcher *notify.Dispatcher
+}
+
+func NewChannelHandler(db *gorm.DB, dispatcher *notify.Dispatcher) *ChannelHandler {
+	return &ChannelHandler{
+		db:         db,
+		dispatcher: dispatcher,
+	}
+}
+
+// channelRequest is the body accepted when creating or updating a channel
+type channelRequest struct {
+	Name     string                 `json:"name"`
+	Type     models.ChannelType     `json:"type"`
+	Enabled  *bool                  `json:"enabled"`
+	Settings models.ChannelSettings `json:"settings"`
+}
+
+// subscriptionRequest is one entry of the body accepted when replacing a job's subscriptions
+type subscriptionRequest struct {
+	ChannelID string   `json:"channelId"`
+	Events    []string `json:"events"`
+}
+
+// GetProjectChannels godoc
+// @Summary Get notification channels of a project
+// @Description Retrieves the notification channels owned by a project
+// @Tags channels
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Router /projects/{id}/channels [get]
+func (h *ChannelHandler) GetProjectChannels(c echo.Context) error {
+	projectID := c.Param("id")
+	
+	var channels []models.NotificationChannel
+	if err := h.db.Where("project_id = ?", projectID).Order("name").Find(&channels).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to fetch channels: " + err.Error(),
+		})
+	}
+
+	for i := range channels {
+		channels[i] = redactChannel(channels[i])
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    channels,
+	})
+}
+
+// CreateChannel godoc
+// @Summary Create a notification channel
+// @Description Creates an email, webhook, Slack, Teams or Discord channel for a project
+// @Tags channels
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param channel body channelRequest true "Channel details"
+// @Success 201 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /projects/{id}/channels [post]
+func (h *ChannelHandler) CreateChannel(c echo.Context) error {
+	projectID := c.Param("id")
+	
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", projectID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Project not found",
+		})
+	}
+
+	req := new(channelRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid request data: " + err.Error(),
+		})
+	}
+
+	channel := models.NotificationChannel{
+		ProjectID: project.ID,
+		Name:      req.Name,
+		Type:      req.Type,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		Settings:  req.Settings,
+	}
+	if err := channel.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid channel: " + err.Error(),
+		})
+	}
+
+	if err := h.db.Create(&channel).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to create channel: " + err.Error(),
+		})
+	}
+	recordAudit(c, h.db, models.AuditChannelCreate, "channel", channel.ID, nil, redactChannel(channel))
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    redactChannel(channel),
+		"message": "Channel created successfully",
+	}
+	// The signing secret is only shown here
+	if channel.Settings.Secret != "" {
+		response["secret"] = channel.Settings.Secret
+	}
+	return c.JSON(http.StatusCreated, response)
+}
+
+// GetChannelByID godoc
+// @Summary Get notification channel by ID
+// @Description Retrieves a notification channel by its ID
+// @Tags channels
+// @Accept json
+// @Produce json
+// @Param id path string true "Channel ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /channels/{id} [get]
+func (h *ChannelHandler) GetChannelByID(c echo.Context) error {
+	id := c.Param("id")
+	
+	var channel models.NotificationChannel
+	if err := h.db.First(&channel, "id = ?", id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Channel not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    redactChannel(channel),
+	})
+}
+
+// UpdateChannel godoc
+// @Summary Update a notification channel
+// @Description Updates an existing notification channel. The webhook secret is kept when none is given, and header values and URLs sent back as redacted keep their stored values.
+// @Tags channels
+// @Accept json
+// @Produce json
+// @Param id path string true "Channel ID"
+// @Param channel body channelRequest true "Channel details"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /channels/{id} [put]
+func (h *ChannelHandler) UpdateChannel(c echo.Context) error {
+	id := c.Param("id")
+	
+	var channel models.NotificationChannel
+	if err := h.db.First(&channel, "id = ?", id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Channel not found",
+		})
+	}
+
+	req := new(channelRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid request data: " + err.Error(),
+		})
+	}
+
+	before := redactChannel(channel)
+	stored := channel.Settings
+	channel.Name = req.Name
+	channel.Type = req.Type
+	if req.Enabled != nil {
+		channel.Enabled = *req.Enabled
+	}
+	channel.Settings = req.Settings
+	keepChannelCredentials(&channel.Settings, stored)
+
+	if err := channel.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid channel: " + err.Error(),
+		})
+	}
+
+	if err := h.db.Save(&channel).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to update channel: " + err.Error(),
+		})
+	}
+	recordAudit(c, h.db, models.AuditChannelUpdate, "channel", channel.ID, before, redactChannel(channel))
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    redactChannel(channel),
+		"message": "Channel updated successfully",
+	})
+}
+
+// DeleteChannel godoc
+// @Summary Delete a notification channel
+// @Description Deletes a notification channel and every job subscription to it
+// @Tags channels
+// @Accept json
+// @Produce json
+// @Param id path string true "Channel ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /channels/{id} [delete]
+func (h *ChannelHandler) DeleteChannel(c echo.Context) error {
+	id := c.Param("id")
+	
+	var channel models.NotificationChannel
+	if err := h.db.First(&channel, "id = ?", id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Channel not found",
+		})
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("channel_id = ?", channel.ID).Delete(&models.JobChannelSubscription{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&channel).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to delete channel: " + err.Error(),
+		})
+	}
+	recordAudit(c, h.db, models.AuditChannelDelete, "channel", channel.ID, redactChannel(channel), nil)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Channel deleted successfully",
+	})
+}
+
+// TestChannel godoc
+// @Summary Send a test notification
+// @Description Sends a sample notification to the channel once and returns the recorded delivery
+// @Tags channels
+// @Accept json
+// @Produce json
+// @Param id path string true "Channel ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 502 {object} map[string]interface{} "error"
+// @Router /channels/{id}/test [post]
+func (h *ChannelHandler) TestChannel(c echo.Context) error {
+	id := c.Param("id")
+	
+	var channel models.NotificationChannel
+	if err := h.db.First(&channel, "id = ?", id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Channel not found",
+		})
+	}
+
+	delivery, err := h.dispatcher.SendTest(c.Request().Context(), channel)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]interface{}{
+			"success": false,
+			"error":   "Test notification failed: " + err.Error(),
+			"data":    delivery,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    delivery,
+		"message": "Test notification sent",
+	})
+}
+
+// GetJobSubscriptions godoc
+// @Summary Get channel subscriptions of a job
+// @Description Retrieves the channels a job notifies and the events each one receives
+// @Tags channels
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Router /jobs/{id}/subscriptions [get]
+func (h *ChannelHandler) GetJobSubscriptions(c echo.Context) error {
+	jobID := c.Param("id")
+	
+	var subscriptions []models.JobChannelSubscription
+	if err := h.db.Preload("Channel").Where("job_id = ?", jobID).Find(&subscriptions).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to fetch subscriptions: " + err.Error(),
+		})
+	}
+
+	for i := range subscriptions {
+		if subscriptions[i].Channel != nil {
+			channel := redactChannel(*subscriptions[i].Channel)
+			subscriptions[i].Channel = &channel
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    subscriptions,
+	})
+}
+
+// UpdateJobSubscriptions godoc
+// @Summary Replace channel subscriptions of a job
+// @Description Replaces the set of channels a job notifies. Channels must belong to the job's project.
+// @Tags channels
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param subscriptions body []subscriptionRequest true "Subscriptions"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /jobs/{id}/subscriptions [put]
+func (h *ChannelHandler) UpdateJobSubscriptions(c echo.Context) error {
+	jobID := c.Param("id")
+	
+	var job models.Job
+	if err := h.db.First(&job, "id = ?", jobID).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Job not found",
+		})
+	}
+
+	var req []subscriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid request data: " + err.Error(),
+		})
+	}
+
+	var before []models.JobChannelSubscription
+	h.db.Where("job_id = ?", job.ID).Find(&before)
+
+	subscriptions := make([]models.JobChannelSubscription, 0, len(req))
+	seen := map[string]bool{}
+	for _, r := range req {
+		if seen[r.ChannelID] {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"error":   "Channel " + r.ChannelID + " is listed more than once",
+			})
+		}
+		seen[r.ChannelID] = true
+
+		var channel models.NotificationChannel
+		if err := h.db.First(&channel, "id = ? AND project_id = ?", r.ChannelID, job.ProjectID).Error; err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"error":   "Channel " + r.ChannelID + " does not exist in the job's project",
+			})
+		}
+
+		subscription := models.JobChannelSubscription{
+			JobID:     job.ID,
+			ChannelID: channel.ID,
+			Events:    r.Events,
+		}
+		if err := subscription.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"error":   "Invalid subscription: " + err.Error(),
+			})
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("job_id = ?", job.ID).Delete(&models.JobChannelSubscription{}).Error; err != nil {
+			return err
+		}
+		if len(subscriptions) == 0 {
+			return nil
+		}
+		return tx.Create(&subscriptions).Error
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to update subscriptions: " + err.Error(),
+		})
+	}
+	recordAudit(c, h.db, models.AuditJobSubscribe, "job", job.ID, before, subscriptions)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    subscriptions,
+		"message": "Subscriptions updated successfully",
+	})
+}
+
+// redactChannel returns the channel without its credentials, which must not be returned or end up
+// in the audit log: the signing secret, the values of custom headers such as Authorization, and
+// the URLs of Slack, Teams and Discord incoming webhooks, which let anyone post to the chat
+func redactChannel(channel models.NotificationChannel) models.NotificationChannel {
+	channel.Settings.Secret = ""
+	if len(channel.Settings.Headers) > 0 {
+		headers := make(map[string]string, len(channel.Settings.Headers))
+		for name := range channel.Settings.Headers {
+			headers[name] = models.RedactedValue
+		}
+		channel.Settings.Headers = headers
+	}
+	if channel.Type != models.ChannelTypeWebhook && channel.Settings.URL != "" {
+		channel.Settings.URL = models.RedactURL(channel.Settings.URL)
+	}
+	return channel
+}
+
+// keepChannelCredentials restores the stored credentials that settings sends back as redacted by
+// redactChannel, so that a channel can be read, edited and saved without entering them again
+func keepChannelCredentials(settings *models.ChannelSettings, stored models.ChannelSettings) {
+	if settings.Secret == "" {
+		settings.Secret = stored.Secret
+	}
+	if settings.URL != "" && settings.URL == models.RedactURL(stored.URL) {
+		settings.URL = stored.URL
+	}
+	for name, value := range settings.Headers {
+		if storedValue, ok := stored.Headers[name]; ok && value == models.RedactedValue {
+			settings.Headers[name] = storedValue
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"testing"
+
+	"crontab/internal/models"
+)
+
+func TestRedactChannelHidesCredentials(t *testing.T) {
+	stored := models.NotificationChannel{
+		Type: models.ChannelTypeSlack,
+		Settings: models.ChannelSettings{
+			URL:     "https://hooks.slack.com/services/T000/B000/XXXX",
+			Headers: map[string]string{"Authorization": "Bearer abc"},
+			Secret:  "signing-key",
+		},
+	}
+
+	redacted := redactChannel(stored)
+	if redacted.Settings.URL != "https://hooks.slack.com/"+models.RedactedValue {
+		t.Errorf("url = %q, want the host only", redacted.Settings.URL)
+	}
+	if redacted.Settings.Headers["Authorization"] != models.RedactedValue || redacted.Settings.Secret != "" {
+		t.Errorf("settings = %+v, want header values and secret hidden", redacted.Settings)
+	}
+	if stored.Settings.Headers["Authorization"] != "Bearer abc" {
+		t.Error("redactChannel changed the headers of the stored channel")
+	}
+
+	// Saving the redacted settings keeps the credentials; new values replace them
+	settings := redacted.Settings
+	settings.Headers = map[string]string{"Authorization": models.RedactedValue, "X-Team": "ops"}
+	keepChannelCredentials(&settings, stored.Settings)
+	if settings.URL != stored.Settings.URL || settings.Headers["Authorization"] != "Bearer abc" || settings.Secret != "signing-key" {
+		t.Errorf("settings after keeping credentials = %+v", settings)
+	}
+	settings = models.ChannelSettings{URL: "https://hooks.slack.com/services/T000/B000/YYYY", Headers: map[string]string{"Authorization": "Bearer new"}}
+	keepChannelCredentials(&settings, stored.Settings)
+	if settings.URL != "https://hooks.slack.com/services/T000/B000/YYYY" || settings.Headers["Authorization"] != "Bearer new" {
+		t.Errorf("new credentials were replaced: %+v", settings)
+	}
+}
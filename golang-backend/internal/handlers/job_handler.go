@@ -2,58 +2,63 @@
 package handlers
 
 import (
+	"errors"
+	"strconv"
 	"net/http"
 	"time"
 
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
 	
 	"crontab/internal/models"
-	"crontab/pkg/scheduler"
+	"crontab/internal/repository"
+	"crontab/internal/services"
+	"crontab/pkg/tracing"
 )
 
 type JobHandler struct {
-	db        *gorm.DB
-	scheduler *scheduler.Scheduler
+	jobs *services.JobService
+	runs *services.RunService
 }
 
-func NewJobHandler(db *gorm.DB, scheduler *scheduler.Scheduler) *JobHandler {
+func NewJobHandler(jobs *services.JobService, runs *services.RunService) *JobHandler {
 	return &JobHandler{
-		db:        db,
-		scheduler: scheduler,
+		jobs: jobs,
+		runs: runs,
 	}
 }
 
 // GetAllJobs godoc
 // @Summary Get all jobs
-// @Description Retrieves all jobs
+// @Description Retrieves a page of jobs
 // @Tags jobs
 // @Accept json
 // @Produce json
+// @Param projectId query string false "Only jobs of this project"
+// @Param status query []string false "Only jobs with these statuses (repeatable)"
+// @Param tag query []string false "Only jobs with these tags (repeatable)"
+// @Param tagMatch query string false "any (default) or all of the given tags"
+// @Param namePrefix query string false "Only jobs whose name starts with this, ignoring case"
+// @Param lastRunFrom query string false "Only jobs last run at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param lastRunTo query string false "Only jobs last run before this time"
+// @Param nextRunFrom query string false "Only jobs next running at or after this time"
+// @Param nextRunTo query string false "Only jobs next running before this time"
+// @Param sort query string false "name, status, createdAt (default), updatedAt, lastRun or nextRun; prefix with - for descending order"
+// @Param limit query int false "Jobs per page (default 100, max 1000)"
+// @Param cursor query string false "nextCursor of the previous page"
 // @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
 // @Router /jobs [get]
 func (h *JobHandler) GetAllJobs(c echo.Context) error {
-	var jobs []models.Job
-	
-	// Check if filtering by project ID
-	projectID := c.QueryParam("projectId")
-	query := h.db
-	
-	if projectID != "" {
-		query = query.Where("project_id = ?", projectID)
-	}
-	
-	if err := query.Find(&jobs).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+	filter, err := parseJobFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
 			"success": false,
-			"error":   "Failed to fetch jobs: " + err.Error(),
+			"error":   err.Error(),
 		})
 	}
-
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"success": true,
-		"data":    jobs,
-	})
+	filter.ProjectID = c.QueryParam("projectId")
+	
+	return h.listJobs(c, filter)
 }
 
 // GetJobByID godoc
@@ -67,18 +72,19 @@ func (h *JobHandler) GetAllJobs(c echo.Context) error {
 // @Failure 404 {object} map[string]interface{} "error"
 // @Router /jobs/{id} [get]
 func (h *JobHandler) GetJobByID(c echo.Context) error {
-	id := c.Param("id")
+	ctx := c.Request().Context()
 	
-	var job models.Job
-	if err := h.db.Preload("Logs", func(db *gorm.DB) *gorm.DB {
-		return db.Order("start_time DESC").Limit(10)
-	}).First(&job, "id = ?", id).Error; err != nil {
-		return c.JSON(http.StatusNotFound, map[string]interface{}{
-			"success": false,
-			"error":   "Job not found",
-		})
+	job, err := h.jobs.Get(ctx, actorOf(c), c.Param("id"))
+	if err != nil {
+		return h.jobFailure(c, err, "Failed to fetch job")
+	}
+	
+	// Include the latest runs
+	if job.Logs, _, err = h.runs.List(ctx, actorOf(c), repository.RunFilter{JobID: job.ID}, repository.Page{Limit: 10}); err != nil {
+		return h.jobFailure(c, err, "Failed to fetch logs")
 	}
 
+	setETag(c, job.Version)
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
 		"data":    job,
@@ -87,28 +93,95 @@ func (h *JobHandler) GetJobByID(c echo.Context) error {
 
 // GetJobsByProject godoc
 // @Summary Get jobs by project ID
-// @Description Retrieves all jobs for a specific project
+// @Description Retrieves a page of the jobs of a project; takes the filters of GET /jobs
 // @Tags jobs
 // @Accept json
 // @Produce json
 // @Param projectId path string true "Project ID"
+// @Param status query []string false "Only jobs with these statuses (repeatable)"
+// @Param tag query []string false "Only jobs with these tags (repeatable)"
+// @Param tagMatch query string false "any (default) or all of the given tags"
+// @Param namePrefix query string false "Only jobs whose name starts with this, ignoring case"
+// @Param lastRunFrom query string false "Only jobs last run at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param lastRunTo query string false "Only jobs last run before this time"
+// @Param nextRunFrom query string false "Only jobs next running at or after this time"
+// @Param nextRunTo query string false "Only jobs next running before this time"
+// @Param sort query string false "name, status, createdAt (default), updatedAt, lastRun or nextRun; prefix with - for descending order"
+// @Param limit query int false "Jobs per page (default 100, max 1000)"
+// @Param cursor query string false "nextCursor of the previous page"
 // @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
 // @Router /jobs/project/{projectId} [get]
 func (h *JobHandler) GetJobsByProject(c echo.Context) error {
-	projectID := c.Param("projectId")
+	filter, err := parseJobFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	filter.ProjectID = c.Param("projectId")
 	
-	var jobs []models.Job
-	if err := h.db.Where("project_id = ?", projectID).Find(&jobs).Error; err != nil {
+	return h.listJobs(c, filter)
+}
+
+// listJobs answers with the page of jobs matching filter selected by the query parameters
+func (h *JobHandler) listJobs(c echo.Context, filter repository.JobFilter) error {
+	page, err := parsePage(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	
+	jobs, info, err := h.jobs.List(c.Request().Context(), actorOf(c), filter, page)
+	if services.IsValidationError(err) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"success": false,
 			"error":   "Failed to fetch jobs: " + err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"success": true,
-		"data":    jobs,
-	})
+	return paginated(c, jobs, info)
+}
+
+// parseJobFilter reads the job filters shared by the job list endpoints
+func parseJobFilter(c echo.Context) (repository.JobFilter, error) {
+	// Filter by tags, matching any of them unless tagMatch=all
+	tagMatch := c.QueryParam("tagMatch")
+	if tagMatch != "" && tagMatch != "any" && tagMatch != "all" {
+		return repository.JobFilter{}, errors.New("tagMatch must be \"any\" or \"all\"")
+	}
+	
+	filter := repository.JobFilter{
+		Statuses:     parseStatuses(c),
+		Tags:         c.QueryParams()["tag"],
+		MatchAllTags: tagMatch == "all",
+		NamePrefix:   c.QueryParam("namePrefix"),
+	}
+	for _, param := range []struct {
+		name string
+		t    *time.Time
+	}{
+		{"lastRunFrom", &filter.LastRunFrom},
+		{"lastRunTo", &filter.LastRunTo},
+		{"nextRunFrom", &filter.NextRunFrom},
+		{"nextRunTo", &filter.NextRunTo},
+	} {
+		t, err := parseTimeQuery(c, param.name)
+		if err != nil {
+			return filter, err
+		}
+		*param.t = t
+	}
+	return filter, nil
 }
 
 // CreateJob godoc
@@ -130,58 +203,46 @@ func (h *JobHandler) CreateJob(c echo.Context) error {
 		})
 	}
 
-	// Set default values
-	now := time.Now()
-	job.CreatedAt = now
-	job.UpdatedAt = now
-	
-	if job.Status == "" {
-		job.Status = models.JobStatusIdle
-	}
-	
-	if job.Timezone == "" {
-		job.Timezone = "UTC"
+	if err := h.jobs.Create(c.Request().Context(), actorOf(c), job); err != nil {
+		return h.jobFailure(c, err, "Failed to create job")
 	}
+	setETag(c, job.Version)
 
-	if err := h.db.Create(job).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"success": false,
-			"error":   "Failed to create job: " + err.Error(),
-		})
-	}
-	
-	// If the job is active, schedule it
-	if job.Status != models.JobStatusPaused {
-		h.scheduler.ScheduleJob(job)
-	}
-
-	return c.JSON(http.StatusCreated, map[string]interface{}{
+	response := map[string]interface{}{
 		"success": true,
 		"data":    job,
 		"message": "Job created successfully",
-	})
+	}
+	// Webhook secrets are only shown here and when they are rotated
+	if secrets := models.NewWebhookSecrets(job.Webhooks, nil); len(secrets) > 0 {
+		response["webhookSecrets"] = secrets
+	}
+	return c.JSON(http.StatusCreated, response)
 }
 
 // UpdateJob godoc
-// @Summary Update a job
-// @Description Updates an existing job
+// @Summary Replace a job
+// @Description Replaces the configuration of a job. Fields left out of the body are cleared; use PATCH to change only some fields.
 // @Tags jobs
 // @Accept json
 // @Produce json
 // @Param id path string true "Job ID"
+// @Param If-Match header string true "ETag of the job"
 // @Param job body models.Job true "Job details"
 // @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
 // @Failure 404 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Failure 412 {object} map[string]interface{} "error"
+// @Failure 428 {object} map[string]interface{} "error"
 // @Router /jobs/{id} [put]
 func (h *JobHandler) UpdateJob(c echo.Context) error {
-	id := c.Param("id")
-	
-	var existingJob models.Job
-	if err := h.db.First(&existingJob, "id = ?", id).Error; err != nil {
-		return c.JSON(http.StatusNotFound, map[string]interface{}{
-			"success": false,
-			"error":   "Job not found",
-		})
+	existingJob, err := h.jobs.Get(c.Request().Context(), actorOf(c), c.Param("id"))
+	if err != nil {
+		return h.jobFailure(c, err, "Failed to fetch job")
+	}
+	if status := checkIfMatch(c, existingJob.Version); status != 0 {
+		return versionMismatch(c, status, *existingJob, existingJob.Version)
 	}
 
 	// Bind the request body to update the job
@@ -193,99 +254,172 @@ func (h *JobHandler) UpdateJob(c echo.Context) error {
 		})
 	}
 
-	// Preserve fields that shouldn't be updated directly
-	originalStatus := existingJob.Status
-	
-	// Update allowed fields
-	existingJob.Name = updatedJob.Name
-	existingJob.Command = updatedJob.Command
-	existingJob.Schedule = updatedJob.Schedule
-	existingJob.Description = updatedJob.Description
-	existingJob.Status = updatedJob.Status
-	existingJob.Timezone = updatedJob.Timezone
-	existingJob.UseLocalTime = updatedJob.UseLocalTime
-	existingJob.UpdatedAt = time.Now()
-
-	if err := h.db.Save(&existingJob).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+	return h.updateJob(c, *existingJob, updatedJob)
+}
+
+// PatchJob godoc
+// @Summary Update fields of a job
+// @Description Applies a JSON Merge Patch (RFC 7396) to a job: only the fields in the body change and null clears a field. The patched job is validated as a whole.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param If-Match header string false "ETag of the job"
+// @Param patch body object true "Fields to change"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Failure 412 {object} map[string]interface{} "error"
+// @Failure 415 {object} map[string]interface{} "error"
+// @Router /jobs/{id} [patch]
+func (h *JobHandler) PatchJob(c echo.Context) error {
+	existingJob, err := h.jobs.Get(c.Request().Context(), actorOf(c), c.Param("id"))
+	if err != nil {
+		return h.jobFailure(c, err, "Failed to fetch job")
+	}
+	if c.Request().Header.Get("If-Match") != "" {
+		if status := checkIfMatch(c, existingJob.Version); status != 0 {
+			return versionMismatch(c, status, *existingJob, existingJob.Version)
+		}
+	}
+
+	updatedJob := new(models.Job)
+	if status, err := bindMergePatch(c, *existingJob, updatedJob); err != nil {
+		return c.JSON(status, map[string]interface{}{
 			"success": false,
-			"error":   "Failed to update job: " + err.Error(),
+			"error":   "Invalid patch: " + err.Error(),
 		})
 	}
-	
-	// If schedule or status changed, update the scheduler
-	if updatedJob.Schedule != "" || originalStatus != updatedJob.Status {
-		h.scheduler.ScheduleJob(&existingJob)
+
+	return h.updateJob(c, *existingJob, updatedJob)
+}
+
+// updateJob stores the user-defined fields of updatedJob in existingJob and reschedules it
+func (h *JobHandler) updateJob(c echo.Context, existingJob models.Job, updatedJob *models.Job) error {
+	job, err := h.jobs.Update(c.Request().Context(), actorOf(c), existingJob, updatedJob)
+	if err != nil {
+		return h.jobFailure(c, err, "Failed to update job")
 	}
+	setETag(c, job.Version)
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"success": true,
-		"data":    existingJob,
+		"data":    job,
 		"message": "Job updated successfully",
-	})
+	}
+	// Secrets of added webhooks are only shown here; existing webhooks keep theirs
+	if secrets := models.NewWebhookSecrets(job.Webhooks, existingJob.Webhooks); len(secrets) > 0 {
+		response["webhookSecrets"] = secrets
+	}
+	return c.JSON(http.StatusOK, response)
 }
 
-// DeleteJob godoc
-// @Summary Delete a job
-// @Description Deletes an existing job
+// RotateWebhookSecret godoc
+// @Summary Rotate the signing secret of a webhook
+// @Description Replaces the HMAC-SHA256 signing secret of a webhook of a job. The new secret is only returned by this call.
 // @Tags jobs
 // @Accept json
 // @Produce json
 // @Param id path string true "Job ID"
+// @Param index path int true "Position of the webhook in the webhooks of the job, starting at 0"
 // @Success 200 {object} map[string]interface{} "success"
 // @Failure 404 {object} map[string]interface{} "error"
-// @Router /jobs/{id} [delete]
-func (h *JobHandler) DeleteJob(c echo.Context) error {
-	id := c.Param("id")
-	
-	var job models.Job
-	if err := h.db.First(&job, "id = ?", id).Error; err != nil {
+// @Router /jobs/{id}/webhooks/{index}/secret [post]
+func (h *JobHandler) RotateWebhookSecret(c echo.Context) error {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
 		return c.JSON(http.StatusNotFound, map[string]interface{}{
 			"success": false,
-			"error":   "Job not found",
+			"error":   "Webhook not found",
 		})
 	}
 
-	if err := h.db.Delete(&job).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"success": false,
-			"error":   "Failed to delete job: " + err.Error(),
-		})
+	job, secret, err := h.jobs.RotateWebhookSecret(c.Request().Context(), actorOf(c), c.Param("id"), index)
+	if err != nil {
+		return h.jobFailure(c, err, "Failed to rotate webhook secret")
 	}
-	
-	// Note: The scheduler will automatically remove the job in its next refresh
+	setETag(c, job.Version)
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
-		"message": "Job deleted successfully",
+		"data":    secret,
+		"message": "Webhook secret rotated",
+	})
+}
+
+// DeleteJob godoc
+// @Summary Delete a job
+// @Description Moves a job to the trash
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /jobs/{id} [delete]
+func (h *JobHandler) DeleteJob(c echo.Context) error {
+	if err := h.jobs.Delete(c.Request().Context(), actorOf(c), c.Param("id")); err != nil {
+		return h.jobFailure(c, err, "Failed to delete job")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Job moved to the trash",
 	})
 }
 
 // GetJobLogs godoc
 // @Summary Get logs for a job
-// @Description Retrieves logs for a specific job
+// @Description Retrieves a page of the logs of a job, newest first by default
 // @Tags jobs
 // @Accept json
 // @Produce json
 // @Param id path string true "Job ID"
+// @Param status query []string false "Only logs with these statuses (repeatable)"
+// @Param from query string false "Only logs of runs started at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "Only logs of runs started before this time"
+// @Param trigger query []string false "Only logs of runs started this way: schedule, manual or external (repeatable)"
+// @Param minDuration query number false "Only logs of runs lasting at least this many seconds"
+// @Param maxDuration query number false "Only logs of runs lasting at most this many seconds"
+// @Param sort query string false "startTime, endTime, duration or status; prefix with - for descending order (default -startTime)"
+// @Param limit query int false "Logs per page (default 100, max 1000)"
+// @Param cursor query string false "nextCursor of the previous page"
 // @Success 200 {object} map[string]interface{} "success"
-// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 400 {object} map[string]interface{} "error"
 // @Router /jobs/{id}/logs [get]
 func (h *JobHandler) GetJobLogs(c echo.Context) error {
-	jobID := c.Param("id")
+	page, err := parsePage(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	filter, err := parseRunFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	filter.JobID = c.Param("id")
 	
-	var logs []models.JobLog
-	if err := h.db.Where("job_id = ?", jobID).Order("start_time DESC").Find(&logs).Error; err != nil {
+	logs, info, err := h.runs.List(c.Request().Context(), actorOf(c), filter, page)
+	if services.IsValidationError(err) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"success": false,
 			"error":   "Failed to fetch logs: " + err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"success": true,
-		"data":    logs,
-	})
+	return paginated(c, logs, info)
 }
 
 // CreateJobLog godoc
@@ -300,17 +434,6 @@ func (h *JobHandler) GetJobLogs(c echo.Context) error {
 // @Failure 400 {object} map[string]interface{} "error"
 // @Router /jobs/{id}/logs [post]
 func (h *JobHandler) CreateJobLog(c echo.Context) error {
-	jobID := c.Param("id")
-	
-	// Check if job exists
-	var job models.Job
-	if err := h.db.First(&job, "id = ?", jobID).Error; err != nil {
-		return c.JSON(http.StatusNotFound, map[string]interface{}{
-			"success": false,
-			"error":   "Job not found",
-		})
-	}
-
 	// Bind the log data
 	log := new(models.JobLog)
 	if err := c.Bind(log); err != nil {
@@ -320,19 +443,174 @@ func (h *JobHandler) CreateJobLog(c echo.Context) error {
 		})
 	}
 
-	// Set the job ID
-	log.JobID = jobID
-	log.CreatedAt = time.Now()
+	if err := h.runs.Report(c.Request().Context(), actorOf(c), c.Param("id"), log); err != nil {
+		return h.jobFailure(c, err, "Failed to create log")
+	}
 
-	if err := h.db.Create(log).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    log,
+	})
+}
+
+// GetJobStats godoc
+// @Summary Get statistics for a job
+// @Description Retrieves success rate, duration percentiles, failure streaks and hourly/daily buckets for a job
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param from query string false "Start of the range (RFC 3339 or YYYY-MM-DD), defaults to 30 days before 'to'"
+// @Param to query string false "End of the range (RFC 3339 or YYYY-MM-DD), defaults to now"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /jobs/{id}/stats [get]
+func (h *JobHandler) GetJobStats(c echo.Context) error {
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
 			"success": false,
-			"error":   "Failed to create log: " + err.Error(),
+			"error":   "Invalid time range: " + err.Error(),
 		})
 	}
+	
+	stats, err := h.jobs.Stats(c.Request().Context(), actorOf(c), c.Param("id"), from, to)
+	if err != nil {
+		return h.jobFailure(c, err, "Failed to compute stats")
+	}
 
-	return c.JSON(http.StatusCreated, map[string]interface{}{
+	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
-		"data":    log,
+		"data":    stats,
+	})
+}
+
+// PauseJob godoc
+// @Summary Pause a job
+// @Description Removes a job from the schedule and records why and by whom it was paused
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param body body map[string]string false "Optional reason"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Router /jobs/{id}/pause [post]
+func (h *JobHandler) PauseJob(c echo.Context) error {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid request data: " + err.Error(),
+		})
+	}
+
+	job, err := h.jobs.Pause(c.Request().Context(), actorOf(c), c.Param("id"), req.Reason)
+	if err != nil {
+		return h.jobFailure(c, err, "Failed to pause job")
+	}
+	setETag(c, job.Version)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    job,
+		"message": "Job paused successfully",
+	})
+}
+
+// ResumeJob godoc
+// @Summary Resume a paused job
+// @Description Schedules a paused job again and resets its consecutive failure counter
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Router /jobs/{id}/resume [post]
+func (h *JobHandler) ResumeJob(c echo.Context) error {
+	job, err := h.jobs.Resume(c.Request().Context(), actorOf(c), c.Param("id"))
+	if err != nil {
+		return h.jobFailure(c, err, "Failed to resume job")
+	}
+	setETag(c, job.Version)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    job,
+		"message": "Job resumed successfully",
+	})
+}
+
+// RunJob godoc
+// @Summary Run a job now
+// @Description Triggers an immediate execution of a job outside of its schedule
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 202 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /jobs/{id}/run [post]
+func (h *JobHandler) RunJob(c echo.Context) error {
+	ctx := c.Request().Context()
+	
+	job, err := h.jobs.Run(ctx, actorOf(c), c.Param("id"))
+	if err != nil {
+		return h.jobFailure(c, err, "Failed to run job")
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"jobId":   job.ID,
+			"traceId": tracing.TraceID(ctx),
+		},
+		"message": "Job execution started",
+	})
+}
+
+// jobConflict answers a write that lost against a concurrent edit with the job as it is now
+func jobConflict(c echo.Context, jobs *services.JobService, id string) error {
+	current, err := jobs.Get(c.Request().Context(), actorOf(c), id)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Job not found",
+		})
+	}
+	return versionMismatch(c, http.StatusConflict, current, current.Version)
+}
+
+// jobFailure answers an error of the job service. failure describes the attempted operation
+// and prefixes unexpected errors.
+func (h *JobHandler) jobFailure(c echo.Context, err error, failure string) error {
+	status, message := http.StatusInternalServerError, failure+": "+err.Error()
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		status, message = http.StatusNotFound, "Job not found"
+	case services.IsValidationError(err):
+		status, message = http.StatusBadRequest, "Invalid job: "+err.Error()
+	case errors.Is(err, models.ErrVersionConflict):
+		return jobConflict(c, h.jobs, c.Param("id"))
+	case errors.Is(err, services.ErrJobPaused):
+		status, message = http.StatusConflict, "Job is already paused"
+	case errors.Is(err, services.ErrJobNotPaused):
+		status, message = http.StatusConflict, "Job is not paused"
+	case errors.Is(err, services.ErrWebhookNotFound):
+		status, message = http.StatusNotFound, "Webhook not found"
+	case errors.Is(err, services.ErrRunAsForbidden):
+		status, message = http.StatusForbidden, "Only admins may change runAsUser and runAsGroup"
+	case errors.Is(err, services.ErrHeartbeatRun):
+		status, message = http.StatusBadRequest, "Heartbeat jobs run elsewhere and report through their ping URL"
+	}
+	return c.JSON(status, map[string]interface{}{
+		"success": false,
+		"error":   message,
 	})
 }
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"crontab/internal/config"
+	"crontab/internal/models"
+	"crontab/internal/repository"
+	"crontab/internal/services"
+	"crontab/pkg/logger"
+)
+
+// testServer serves the handlers over an in-memory store. Requests are made as user, or
+// without a user when it is nil.
+type testServer struct {
+	echo     *echo.Echo
+	store    repository.Store
+	jobs     *services.JobService
+	projects *services.ProjectService
+	user     *models.User
+}
+
+// admin may access every project
+var admin = services.Actor{Email: "admin@example.com", Admin: true}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	logs := logger.New(config.New())
+	s := &testServer{
+		echo:     echo.New(),
+		store:    store,
+		jobs:     services.NewJobService(store, nil, logs),
+		projects: services.NewProjectService(store, nil, logs),
+	}
+	s.echo.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if s.user != nil {
+				c.Set("user", *s.user)
+			}
+			return next(c)
+		}
+	})
+
+	jobHandler := NewJobHandler(s.jobs, services.NewRunService(store))
+	s.echo.GET("/jobs/:id", jobHandler.GetJobByID)
+	s.echo.PUT("/jobs/:id", jobHandler.UpdateJob)
+	s.echo.GET("/jobs/:id/stats", jobHandler.GetJobStats)
+
+	revisionHandler := NewRevisionHandler(s.jobs)
+	s.echo.GET("/jobs/:id/revisions", revisionHandler.GetJobRevisions)
+	s.echo.GET("/jobs/:id/revisions/diff", revisionHandler.DiffJobRevisions)
+	s.echo.GET("/jobs/:id/revisions/:number", revisionHandler.GetJobRevision)
+	s.echo.POST("/jobs/:id/revisions/:number/rollback", revisionHandler.RollbackJob)
+
+	trashHandler := NewTrashHandler(services.NewTrashService(store, nil, logs))
+	s.echo.GET("/trash", trashHandler.GetTrash)
+	s.echo.POST("/trash/:type/:id/restore", trashHandler.RestoreItem)
+
+	// Without a dispatcher, redeliveries fail unless they are refused before sending
+	notificationHandler := NewNotificationHandler(store, s.jobs, nil)
+	s.echo.GET("/jobs/:id/deliveries", notificationHandler.GetJobDeliveries)
+	s.echo.POST("/deliveries/:id/redeliver", notificationHandler.RedeliverDelivery)
+
+	auditHandler := NewAuditHandler(store.Audit)
+	s.echo.GET("/audit", auditHandler.GetAuditEntries)
+	s.echo.GET("/audit/export", auditHandler.ExportAuditEntries)
+	return s
+}
+
+// restrictTo makes the following requests as a user whose role only grants the given projects
+func (s *testServer) restrictTo(t *testing.T, projects ...string) {
+	t.Helper()
+	var permissions []string
+	for _, id := range projects {
+		permissions = append(permissions, models.ProjectPermissionPrefix+id)
+	}
+	encoded, err := models.MarshalPermissions(permissions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.user = &models.User{ID: "user-1", Email: "ops@example.com", Role: models.Role{Name: "user", PermissionsJSON: encoded}}
+}
+
+// do sends a request with an optional JSON body and headers given as name, value pairs
+func (s *testServer) do(t *testing.T, method, path string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	var payload string
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload = string(data)
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(payload))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.echo.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode reads the data of a successful response into v
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	var res struct {
+		Success bool            `json:"success"`
+		Error   string          `json:"error"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("invalid response %s: %v", rec.Body, err)
+	}
+	if !res.Success {
+		t.Fatalf("request failed with %d: %s", rec.Code, res.Error)
+	}
+	if err := json.Unmarshal(res.Data, v); err != nil {
+		t.Fatalf("invalid data %s: %v", res.Data, err)
+	}
+}
+
+func (s *testServer) project(t *testing.T, name string) *models.Project {
+	t.Helper()
+	project := &models.Project{Name: name}
+	if err := s.projects.Create(context.Background(), admin, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	return project
+}
+
+func (s *testServer) job(t *testing.T, projectID, name string) *models.Job {
+	t.Helper()
+	job := &models.Job{
+		ProjectID: projectID,
+		Name:      name,
+		Type:      models.JobTypeShell,
+		Command:   "echo hello",
+		Schedule:  "0 0 * * * *",
+	}
+	if err := s.jobs.Create(context.Background(), admin, job); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	return job
+}
+
+func TestJobHandlerHidesJobsOfOtherProjects(t *testing.T) {
+	s := newTestServer(t)
+	ops, billing := s.project(t, "Ops"), s.project(t, "Billing")
+	visible, hidden := s.job(t, ops.ID, "Backup"), s.job(t, billing.ID, "Invoices")
+	for _, job := range []*models.Job{visible, hidden} {
+		run := &models.JobLog{JobID: job.ID, Status: models.JobStatusSuccess, StartTime: time.Now().Add(-time.Hour), Duration: 2}
+		if err := s.store.Runs.Create(context.Background(), run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.restrictTo(t, ops.ID)
+
+	for _, path := range []string{"/jobs/" + hidden.ID, "/jobs/" + hidden.ID + "/stats", "/jobs/" + hidden.ID + "/revisions"} {
+		if rec := s.do(t, http.MethodGet, path, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", path, rec.Code)
+		}
+	}
+
+	var stats models.JobStats
+	decode(t, s.do(t, http.MethodGet, "/jobs/"+visible.ID+"/stats", nil), &stats)
+	if stats.JobID != visible.ID || stats.TotalRuns != 1 || stats.SuccessRate != 100 {
+		t.Errorf("stats = %+v, want the one successful run of the job", stats)
+	}
+}
+
+func TestJobHandlerRequiresCurrentVersion(t *testing.T) {
+	s := newTestServer(t)
+	job := s.job(t, "", "Backup")
+	update := *job
+	update.Command = "echo changed"
+
+	if rec := s.do(t, http.MethodPut, "/jobs/"+job.ID, update); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("PUT without If-Match = %d, want 428", rec.Code)
+	}
+	rec := s.do(t, http.MethodPut, "/jobs/"+job.ID, update, "If-Match", etag(job.Version))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT = %d: %s", rec.Code, rec.Body)
+	}
+
+	// The job read before the first update is out of date now
+	rec = s.do(t, http.MethodPut, "/jobs/"+job.ID, update, "If-Match", etag(job.Version))
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("PUT with an outdated If-Match = %d, want 412", rec.Code)
+	}
+	if got := rec.Header().Get("ETag"); got != etag(job.Version+1) {
+		t.Errorf("ETag = %s, want the current version %s", got, etag(job.Version+1))
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	
+	"crontab/internal/models"
+	"crontab/internal/repository"
+	"crontab/internal/services"
+	"crontab/pkg/notify"
+)
+
+type NotificationHandler struct {
+	store      repository.Store
+	jobs       *services.JobService
+	dispatcher *notify.Dispatcher
+}
+
+func NewNotificationHandler(store repository.Store, jobs *services.JobService, dispatcher *notify.Dispatcher) *NotificationHandler {
+	return &NotificationHandler{
+		store:      store,
+		jobs:       jobs,
+		dispatcher: dispatcher,
+	}
+}
+
+// GetJobDeliveries godoc
+// @Summary Get notification deliveries for a job
+// @Description Retrieves the delivery log (emails and webhooks) of a job, newest first
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param channel query string false "Filter by channel (email, webhook)"
+// @Param status query string false "Filter by status (sent, failed)"
+// @Param sort query string false "createdAt, status or channel; prefix with - for descending order (default -createdAt)"
+// @Param limit query int false "Deliveries per page (default 100, max 1000)"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /jobs/{id}/deliveries [get]
+func (h *NotificationHandler) GetJobDeliveries(c echo.Context) error {
+	page, err := parsePage(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	
+	ctx := c.Request().Context()
+	job, err := h.jobs.Get(ctx, actorOf(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"error":   "Job not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to fetch job: " + err.Error(),
+		})
+	}
+	
+	filter := repository.DeliveryFilter{
+		JobID:   job.ID,
+		Channel: c.QueryParam("channel"),
+		Status:  models.DeliveryStatus(c.QueryParam("status")),
+	}
+	deliveries, info, err := h.store.Deliveries.List(ctx, filter, page)
+	if errors.Is(err, repository.ErrInvalidPage) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to fetch deliveries: " + err.Error(),
+		})
+	}
+
+	return paginated(c, deliveries, info)
+}
+
+// RedeliverDelivery godoc
+// @Summary Redeliver a webhook
+// @Description Sends the payload of a past webhook delivery again with a fresh signature
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param id path string true "Delivery ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /deliveries/{id}/redeliver [post]
+func (h *NotificationHandler) RedeliverDelivery(c echo.Context) error {
+	ctx, id := c.Request().Context(), c.Param("id")
+	
+	// Deliveries of jobs of projects the user may not access are not found
+	if original, err := h.store.Deliveries.Get(ctx, id); err == nil && original.JobID != "" {
+		if _, err := h.jobs.Get(ctx, actorOf(c), original.JobID); errors.Is(err, services.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"error":   notify.ErrDeliveryNotFound.Error(),
+			})
+		}
+	}
+	
+	delivery, err := h.dispatcher.Redeliver(ctx, id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, notify.ErrDeliveryNotFound), errors.Is(err, notify.ErrWebhookNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, notify.ErrNotRedeliverable):
+			status = http.StatusBadRequest
+		}
+		return c.JSON(status, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    delivery,
+		"message": "Webhook redelivered",
+	})
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"crontab/internal/models"
+)
+
+func TestNotificationHandlerHidesDeliveriesOfOtherProjects(t *testing.T) {
+	s := newTestServer(t)
+	ops, billing := s.project(t, "Ops"), s.project(t, "Billing")
+	visible, hidden := s.job(t, ops.ID, "Backup"), s.job(t, billing.ID, "Invoices")
+	var deliveries []models.NotificationDelivery
+	for _, job := range []*models.Job{visible, hidden} {
+		delivery := models.NotificationDelivery{JobID: job.ID, Channel: "webhook", Event: "failure", Status: models.DeliveryStatusFailed}
+		if err := s.store.Deliveries.Create(context.Background(), &delivery); err != nil {
+			t.Fatal(err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	s.restrictTo(t, ops.ID)
+
+	var page []models.NotificationDelivery
+	decode(t, s.do(t, http.MethodGet, "/jobs/"+visible.ID+"/deliveries", nil), &page)
+	if len(page) != 1 || page[0].ID != deliveries[0].ID {
+		t.Errorf("deliveries = %+v, want the one of the job", page)
+	}
+	if rec := s.do(t, http.MethodGet, "/jobs/"+hidden.ID+"/deliveries", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("deliveries of a job of another project = %d, want 404", rec.Code)
+	}
+	if rec := s.do(t, http.MethodPost, "/deliveries/"+deliveries[1].ID+"/redeliver", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("redelivery for a job of another project = %d, want 404", rec.Code)
+	}
+}
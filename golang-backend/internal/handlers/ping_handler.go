@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	
+	"crontab/pkg/scheduler"
+)
+
+// maxPingBody is how much of a ping's request body is stored as the run output
+const maxPingBody = 10 * 1024
+
+type PingHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewPingHandler(scheduler *scheduler.Scheduler) *PingHandler {
+	return &PingHandler{scheduler: scheduler}
+}
+
+// Ping godoc
+// @Summary Report a successful heartbeat job run
+// @Description Called by heartbeat jobs when they complete. The request body, if any, is stored as the run output. No authentication is needed: the token identifies the job.
+// @Tags heartbeat
+// @Accept plain
+// @Produce json
+// @Param token path string true "Ping token"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /ping/{token} [post]
+func (h *PingHandler) Ping(c echo.Context) error {
+	return h.ping(c, scheduler.PingSuccess)
+}
+
+// PingStart godoc
+// @Summary Report that a heartbeat job run started
+// @Description Called by heartbeat jobs when they start, so that the duration of the run is recorded
+// @Tags heartbeat
+// @Accept plain
+// @Produce json
+// @Param token path string true "Ping token"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /ping/{token}/start [post]
+func (h *PingHandler) PingStart(c echo.Context) error {
+	return h.ping(c, scheduler.PingStart)
+}
+
+// PingFail godoc
+// @Summary Report a failed heartbeat job run
+// @Description Called by heartbeat jobs when they fail. The request body, if any, is stored as the run output.
+// @Tags heartbeat
+// @Accept plain
+// @Produce json
+// @Param token path string true "Ping token"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /ping/{token}/fail [post]
+func (h *PingHandler) PingFail(c echo.Context) error {
+	return h.ping(c, scheduler.PingFail)
+}
+
+func (h *PingHandler) ping(c echo.Context, kind scheduler.PingKind) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxPingBody))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to read request body: " + err.Error(),
+		})
+	}
+
+	jobLog, err := h.scheduler.Ping(c.Request().Context(), c.Param("token"), kind, string(body))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, scheduler.ErrUnknownPingToken) {
+			status = http.StatusNotFound
+		}
+		return c.JSON(status, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"logId":  jobLog.ID,
+			"status": jobLog.Status,
+		},
+	})
+}
@@ -2,45 +2,64 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"time"
 
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
 	
 	"crontab/internal/models"
+	"crontab/internal/repository"
+	"crontab/internal/services"
 )
 
 type ProjectHandler struct {
-	db *gorm.DB
+	projects *services.ProjectService
 }
 
-func NewProjectHandler(db *gorm.DB) *ProjectHandler {
-	return &ProjectHandler{db: db}
+func NewProjectHandler(projects *services.ProjectService) *ProjectHandler {
+	return &ProjectHandler{
+		projects: projects,
+	}
 }
 
 // GetAllProjects godoc
 // @Summary Get all projects
-// @Description Retrieves all projects
+// @Description Retrieves a page of projects
 // @Tags projects
 // @Accept json
 // @Produce json
+// @Param namePrefix query string false "Only projects whose name starts with this, ignoring case"
+// @Param sort query string false "name, createdAt (default) or updatedAt; prefix with - for descending order"
+// @Param limit query int false "Projects per page (default 100, max 1000)"
+// @Param cursor query string false "nextCursor of the previous page"
 // @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
 // @Router /projects [get]
 func (h *ProjectHandler) GetAllProjects(c echo.Context) error {
-	var projects []models.Project
+	page, err := parsePage(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
 	
-	if err := h.db.Find(&projects).Error; err != nil {
+	filter := repository.ProjectFilter{NamePrefix: c.QueryParam("namePrefix")}
+	projects, info, err := h.projects.List(c.Request().Context(), actorOf(c), filter, page)
+	if services.IsValidationError(err) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"success": false,
 			"error":   "Failed to fetch projects: " + err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"success": true,
-		"data":    projects,
-	})
+	return paginated(c, projects, info)
 }
 
 // GetProjectByID godoc
@@ -54,16 +73,12 @@ func (h *ProjectHandler) GetAllProjects(c echo.Context) error {
 // @Failure 404 {object} map[string]interface{} "error"
 // @Router /projects/{id} [get]
 func (h *ProjectHandler) GetProjectByID(c echo.Context) error {
-	id := c.Param("id")
-	
-	var project models.Project
-	if err := h.db.Preload("Jobs").First(&project, "id = ?", id).Error; err != nil {
-		return c.JSON(http.StatusNotFound, map[string]interface{}{
-			"success": false,
-			"error":   "Project not found",
-		})
+	project, err := h.projects.Get(c.Request().Context(), actorOf(c), c.Param("id"))
+	if err != nil {
+		return h.projectFailure(c, err, "Failed to fetch project")
 	}
 
+	setETag(c, project.Version)
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
 		"data":    project,
@@ -89,17 +104,10 @@ func (h *ProjectHandler) CreateProject(c echo.Context) error {
 		})
 	}
 
-	// Set creation time
-	now := time.Now()
-	project.CreatedAt = now
-	project.UpdatedAt = now
-
-	if err := h.db.Create(project).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"success": false,
-			"error":   "Failed to create project: " + err.Error(),
-		})
+	if err := h.projects.Create(c.Request().Context(), actorOf(c), project); err != nil {
+		return h.projectFailure(c, err, "Failed to create project")
 	}
+	setETag(c, project.Version)
 
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"success": true,
@@ -109,25 +117,28 @@ func (h *ProjectHandler) CreateProject(c echo.Context) error {
 }
 
 // UpdateProject godoc
-// @Summary Update a project
-// @Description Updates an existing project
+// @Summary Replace a project
+// @Description Replaces the name and description of a project. Fields left out of the body are cleared; use PATCH to change only some fields.
 // @Tags projects
 // @Accept json
 // @Produce json
 // @Param id path string true "Project ID"
+// @Param If-Match header string true "ETag of the project"
 // @Param project body models.Project true "Project details"
 // @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
 // @Failure 404 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Failure 412 {object} map[string]interface{} "error"
+// @Failure 428 {object} map[string]interface{} "error"
 // @Router /projects/{id} [put]
 func (h *ProjectHandler) UpdateProject(c echo.Context) error {
-	id := c.Param("id")
-	
-	var existingProject models.Project
-	if err := h.db.First(&existingProject, "id = ?", id).Error; err != nil {
-		return c.JSON(http.StatusNotFound, map[string]interface{}{
-			"success": false,
-			"error":   "Project not found",
-		})
+	existingProject, err := h.projects.Get(c.Request().Context(), actorOf(c), c.Param("id"))
+	if err != nil {
+		return h.projectFailure(c, err, "Failed to fetch project")
+	}
+	if status := checkIfMatch(c, existingProject.Version); status != 0 {
+		return versionMismatch(c, status, *existingProject, existingProject.Version)
 	}
 
 	// Bind the request body to update the project
@@ -139,55 +150,152 @@ func (h *ProjectHandler) UpdateProject(c echo.Context) error {
 		})
 	}
 
-	// Update only allowed fields
-	existingProject.Name = updatedProject.Name
-	existingProject.Description = updatedProject.Description
-	existingProject.UpdatedAt = time.Now()
+	return h.updateProject(c, *existingProject, updatedProject)
+}
 
-	if err := h.db.Save(&existingProject).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+// PatchProject godoc
+// @Summary Update fields of a project
+// @Description Applies a JSON Merge Patch (RFC 7396) to a project: only the fields in the body change and null clears a field. The patched project is validated as a whole.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param If-Match header string false "ETag of the project"
+// @Param patch body object true "Fields to change"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Failure 412 {object} map[string]interface{} "error"
+// @Failure 415 {object} map[string]interface{} "error"
+// @Router /projects/{id} [patch]
+func (h *ProjectHandler) PatchProject(c echo.Context) error {
+	existingProject, err := h.projects.Get(c.Request().Context(), actorOf(c), c.Param("id"))
+	if err != nil {
+		return h.projectFailure(c, err, "Failed to fetch project")
+	}
+	if c.Request().Header.Get("If-Match") != "" {
+		if status := checkIfMatch(c, existingProject.Version); status != 0 {
+			return versionMismatch(c, status, *existingProject, existingProject.Version)
+		}
+	}
+
+	updatedProject := new(models.Project)
+	if status, err := bindMergePatch(c, *existingProject, updatedProject); err != nil {
+		return c.JSON(status, map[string]interface{}{
 			"success": false,
-			"error":   "Failed to update project: " + err.Error(),
+			"error":   "Invalid patch: " + err.Error(),
 		})
 	}
 
+	return h.updateProject(c, *existingProject, updatedProject)
+}
+
+// updateProject stores the user-defined fields of updatedProject in existingProject
+func (h *ProjectHandler) updateProject(c echo.Context, existingProject models.Project, updatedProject *models.Project) error {
+	project, err := h.projects.Update(c.Request().Context(), actorOf(c), existingProject, updatedProject)
+	if err != nil {
+		return h.projectFailure(c, err, "Failed to update project")
+	}
+	setETag(c, project.Version)
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
-		"data":    existingProject,
+		"data":    project,
 		"message": "Project updated successfully",
 	})
 }
 
 // DeleteProject godoc
 // @Summary Delete a project
-// @Description Deletes an existing project
+// @Description Moves a project to the trash. mode decides what happens to its jobs: "refuse" (default) fails when it has jobs, "cascade" moves them to the trash as well, keeping their logs until they are purged, and "move" moves them to targetProjectId.
 // @Tags projects
 // @Accept json
 // @Produce json
 // @Param id path string true "Project ID"
+// @Param mode query string false "refuse, cascade or move"
+// @Param targetProjectId query string false "Project receiving the jobs in move mode"
 // @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
 // @Failure 404 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
 // @Router /projects/{id} [delete]
 func (h *ProjectHandler) DeleteProject(c echo.Context) error {
-	id := c.Param("id")
-	
-	var project models.Project
-	if err := h.db.First(&project, "id = ?", id).Error; err != nil {
-		return c.JSON(http.StatusNotFound, map[string]interface{}{
+	mode := models.ProjectDeleteMode(c.QueryParam("mode"))
+	deletion, err := h.projects.Delete(c.Request().Context(), actorOf(c), c.Param("id"), mode, c.QueryParam("targetProjectId"))
+	if services.IsValidationError(err) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
 			"success": false,
-			"error":   "Project not found",
+			"error":   err.Error(),
 		})
 	}
+	if err != nil {
+		return h.projectFailure(c, err, "Failed to delete project")
+	}
 
-	if err := h.db.Delete(&project).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"mode": deletion.Mode,
+			"jobs": len(deletion.JobIDs),
+		},
+		"message": "Project moved to the trash",
+	})
+}
+
+// GetProjectStats godoc
+// @Summary Get statistics for a project
+// @Description Rolls up job counts by status and run statistics for all jobs of a project
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param from query string false "Start of the range (RFC 3339 or YYYY-MM-DD), defaults to 30 days before 'to'"
+// @Param to query string false "End of the range (RFC 3339 or YYYY-MM-DD), defaults to now"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /projects/{id}/stats [get]
+func (h *ProjectHandler) GetProjectStats(c echo.Context) error {
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
 			"success": false,
-			"error":   "Failed to delete project: " + err.Error(),
+			"error":   "Invalid time range: " + err.Error(),
 		})
 	}
+	
+	stats, err := h.projects.Stats(c.Request().Context(), actorOf(c), c.Param("id"), from, to)
+	if err != nil {
+		return h.projectFailure(c, err, "Failed to compute stats")
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
-		"message": "Project deleted successfully",
+		"data":    stats,
+	})
+}
+
+// projectFailure answers an error of the project service. failure describes the attempted
+// operation and prefixes unexpected errors.
+func (h *ProjectHandler) projectFailure(c echo.Context, err error, failure string) error {
+	status, message := http.StatusInternalServerError, failure+": "+err.Error()
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		status, message = http.StatusNotFound, "Project not found"
+	case services.IsValidationError(err):
+		status, message = http.StatusBadRequest, "Invalid project: "+err.Error()
+	case errors.Is(err, models.ErrVersionConflict):
+		current, err := h.projects.Get(c.Request().Context(), actorOf(c), c.Param("id"))
+		if err != nil {
+			return h.projectFailure(c, err, "Failed to fetch project")
+		}
+		return versionMismatch(c, http.StatusConflict, *current, current.Version)
+	case errors.Is(err, models.ErrProjectHasJobs):
+		status, message = http.StatusConflict, "The project still has jobs, delete them first or use mode=cascade or mode=move"
+	}
+	return c.JSON(status, map[string]interface{}{
+		"success": false,
+		"error":   message,
 	})
 }
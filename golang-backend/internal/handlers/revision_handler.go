@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	
+	"crontab/internal/models"
+	"crontab/internal/services"
+)
+
+type RevisionHandler struct {
+	jobs *services.JobService
+}
+
+func NewRevisionHandler(jobs *services.JobService) *RevisionHandler {
+	return &RevisionHandler{
+		jobs: jobs,
+	}
+}
+
+// GetJobRevisions godoc
+// @Summary Get revisions of a job
+// @Description Retrieves the configuration history of a job, newest first
+// @Tags revisions
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /jobs/{id}/revisions [get]
+func (h *RevisionHandler) GetJobRevisions(c echo.Context) error {
+	revisions, err := h.jobs.Revisions(c.Request().Context(), actorOf(c), c.Param("id"))
+	if errors.Is(err, services.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Job not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to fetch revisions: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    revisions,
+	})
+}
+
+// GetJobRevision godoc
+// @Summary Get a revision of a job
+// @Description Retrieves the configuration of a job at a revision
+// @Tags revisions
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param number path int true "Revision number"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /jobs/{id}/revisions/{number} [get]
+func (h *RevisionHandler) GetJobRevision(c echo.Context) error {
+	revision, err := h.findRevision(c, c.Param("number"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Revision not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    revision,
+	})
+}
+
+// DiffJobRevisions godoc
+// @Summary Compare two revisions of a job
+// @Description Lists the fields that differ between two revisions of a job
+// @Tags revisions
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param from query int true "Older revision number"
+// @Param to query int false "Newer revision number, defaults to the latest"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /jobs/{id}/revisions/diff [get]
+func (h *RevisionHandler) DiffJobRevisions(c echo.Context) error {
+	from, err := h.findRevision(c, c.QueryParam("from"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Revision \"from\" not found",
+		})
+	}
+	
+	var to *models.JobRevision
+	if c.QueryParam("to") == "" {
+		to, err = h.latestRevision(c)
+	} else {
+		to, err = h.findRevision(c, c.QueryParam("to"))
+	}
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Revision \"to\" not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"from":    from.Number,
+			"to":      to.Number,
+			"changes": models.DiffJobSpecs(from.Spec, to.Spec),
+		},
+	})
+}
+
+// RollbackJob godoc
+// @Summary Roll a job back to a revision
+// @Description Restores the configuration of an earlier revision. The rollback is recorded as a new revision.
+// @Tags revisions
+// @Accept json
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param number path int true "Revision number"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Router /jobs/{id}/revisions/{number}/rollback [post]
+func (h *RevisionHandler) RollbackJob(c echo.Context) error {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Revision not found",
+		})
+	}
+	
+	job, current, err := h.jobs.Rollback(c.Request().Context(), actorOf(c), c.Param("id"), number)
+	if err != nil {
+		return h.rollbackFailure(c, err)
+	}
+	setETag(c, job.Version)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"job":      job,
+			"revision": current,
+		},
+		"message": "Job rolled back to revision " + strconv.Itoa(number),
+	})
+}
+
+// rollbackFailure answers an error of a rollback
+func (h *RevisionHandler) rollbackFailure(c echo.Context, err error) error {
+	status, message := http.StatusInternalServerError, "Failed to roll back job: "+err.Error()
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		status, message = http.StatusNotFound, "Revision not found"
+	case services.IsValidationError(err):
+		status, message = http.StatusBadRequest, "Invalid job: "+err.Error()
+	case errors.Is(err, models.ErrVersionConflict):
+		return jobConflict(c, h.jobs, c.Param("id"))
+	case errors.Is(err, services.ErrRunAsForbidden):
+		status, message = http.StatusForbidden, "Only admins may change runAsUser and runAsGroup"
+	}
+	return c.JSON(status, map[string]interface{}{
+		"success": false,
+		"error":   message,
+	})
+}
+
+// findRevision loads a revision of the job of the request by its number
+func (h *RevisionHandler) findRevision(c echo.Context, number string) (*models.JobRevision, error) {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, err
+	}
+	return h.jobs.Revision(c.Request().Context(), actorOf(c), c.Param("id"), n)
+}
+
+// latestRevision loads the newest revision of the job of the request
+func (h *RevisionHandler) latestRevision(c echo.Context) (*models.JobRevision, error) {
+	revisions, err := h.jobs.Revisions(c.Request().Context(), actorOf(c), c.Param("id"))
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, services.ErrNotFound
+	}
+	return &revisions[0], nil
+}
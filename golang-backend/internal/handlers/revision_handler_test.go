@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"crontab/internal/models"
+)
+
+func TestRevisionHandlerRollsBackJob(t *testing.T) {
+	s := newTestServer(t)
+	job := s.job(t, "", "Backup")
+	update := *job
+	update.Command = "echo changed"
+	if _, err := s.jobs.Update(context.Background(), admin, *job, &update); err != nil {
+		t.Fatal(err)
+	}
+
+	var diff struct {
+		From    int                  `json:"from"`
+		To      int                  `json:"to"`
+		Changes []models.FieldChange `json:"changes"`
+	}
+	decode(t, s.do(t, http.MethodGet, "/jobs/"+job.ID+"/revisions/diff?from=1", nil), &diff)
+	if diff.From != 1 || diff.To != 2 || len(diff.Changes) != 1 || diff.Changes[0].Field != "command" {
+		t.Errorf("diff = %+v, want the command changed from 1 to the latest revision 2", diff)
+	}
+
+	var rollback struct {
+		Job      models.Job         `json:"job"`
+		Revision models.JobRevision `json:"revision"`
+	}
+	decode(t, s.do(t, http.MethodPost, "/jobs/"+job.ID+"/revisions/1/rollback", nil), &rollback)
+	if rollback.Job.Command != "echo hello" || rollback.Revision.Number != 3 || rollback.Revision.Reason != "Rolled back to revision 1" {
+		t.Errorf("rollback = %+v, want the first command recorded as revision 3", rollback)
+	}
+
+	var revisions []models.JobRevision
+	decode(t, s.do(t, http.MethodGet, "/jobs/"+job.ID+"/revisions", nil), &revisions)
+	if len(revisions) != 3 || revisions[0].Number != 3 {
+		t.Errorf("revisions = %+v, want 3 newest first", revisions)
+	}
+	if rec := s.do(t, http.MethodPost, "/jobs/"+job.ID+"/revisions/9/rollback", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("rollback to a missing revision = %d, want 404", rec.Code)
+	}
+
+	s.restrictTo(t, "other-project")
+	if rec := s.do(t, http.MethodGet, "/jobs/"+job.ID+"/revisions/1", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("revision of a job of another project = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"crontab/internal/models"
+	"crontab/internal/repository"
+	"crontab/internal/services"
+)
+
+type RunHandler struct {
+	runs *services.RunService
+}
+
+func NewRunHandler(runs *services.RunService) *RunHandler {
+	return &RunHandler{runs: runs}
+}
+
+// GetRuns godoc
+// @Summary Get the run history
+// @Description Retrieves a page of the runs of all jobs, newest first by default, with the names of their jobs and projects. Runs of jobs in the trash are left out.
+// @Tags runs
+// @Accept json
+// @Produce json
+// @Param projectId query string false "Only runs of jobs of this project"
+// @Param jobId query string false "Only runs of this job"
+// @Param status query []string false "Only runs with these statuses (repeatable)"
+// @Param trigger query []string false "Only runs started this way: schedule, manual or external (repeatable)"
+// @Param from query string false "Only runs started at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "Only runs started before this time"
+// @Param minDuration query number false "Only runs lasting at least this many seconds"
+// @Param maxDuration query number false "Only runs lasting at most this many seconds"
+// @Param sort query string false "startTime, endTime, duration, status, jobName or projectName; prefix with - for descending order (default -startTime)"
+// @Param limit query int false "Runs per page (default 100, max 1000)"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Router /runs [get]
+func (h *RunHandler) GetRuns(c echo.Context) error {
+	filter, err := parseRunFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	page, err := parsePage(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	runs, info, err := h.runs.History(c.Request().Context(), actorOf(c), filter, page)
+	if services.IsValidationError(err) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to fetch runs: " + err.Error(),
+		})
+	}
+
+	return paginated(c, runs, info)
+}
+
+// ExportRuns godoc
+// @Summary Export the run history
+// @Description Streams all runs matching the filters of GET /runs as CSV or newline-delimited JSON, without paging.
+// @Tags runs
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "csv (default) or ndjson"
+// @Param projectId query string false "Only runs of jobs of this project"
+// @Param jobId query string false "Only runs of this job"
+// @Param status query []string false "Only runs with these statuses (repeatable)"
+// @Param trigger query []string false "Only runs started this way (repeatable)"
+// @Param from query string false "Only runs started at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "Only runs started before this time"
+// @Param minDuration query number false "Only runs lasting at least this many seconds"
+// @Param maxDuration query number false "Only runs lasting at most this many seconds"
+// @Param sort query string false "Order of the runs, as for GET /runs"
+// @Success 200 {string} string "CSV or NDJSON"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Router /runs/export [get]
+func (h *RunHandler) ExportRuns(c echo.Context) error {
+	filter, err := parseRunFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+
+	var w runWriter
+	switch format := c.QueryParam("format"); format {
+	case "", "csv":
+		w = &csvRunWriter{}
+	case "ndjson":
+		w = &ndjsonRunWriter{}
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "format must be \"csv\" or \"ndjson\"",
+		})
+	}
+
+	// The response starts with the first run, so that errors found before can still be answered with JSON
+	res := c.Response()
+	started := false
+	start := func() {
+		started = true
+		res.Header().Set(echo.HeaderContentType, w.contentType())
+		res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="runs.`+w.extension()+`"`)
+		res.WriteHeader(http.StatusOK)
+		w.start(res)
+	}
+
+	err = h.runs.Export(c.Request().Context(), actorOf(c), filter, c.QueryParam("sort"), func(run *repository.RunWithJob) error {
+		if !started {
+			start()
+		}
+		return w.write(run)
+	})
+	if err != nil && !started {
+		status, message := http.StatusInternalServerError, "Failed to export runs: "+err.Error()
+		if services.IsValidationError(err) {
+			status, message = http.StatusBadRequest, err.Error()
+		}
+		return c.JSON(status, map[string]interface{}{
+			"success": false,
+			"error":   message,
+		})
+	}
+	if err != nil {
+		// Too late for an error response; the export ends early
+		c.Logger().Errorf("Failed to export runs: %v", err)
+		return nil
+	}
+	if !started {
+		start()
+	}
+	return nil
+}
+
+// parseRunFilter reads the run filters shared by the run and job log endpoints
+func parseRunFilter(c echo.Context) (repository.RunFilter, error) {
+	filter := repository.RunFilter{
+		JobID:     c.QueryParam("jobId"),
+		ProjectID: c.QueryParam("projectId"),
+		Statuses:  parseStatuses(c),
+	}
+	for _, trigger := range c.QueryParams()["trigger"] {
+		filter.Triggers = append(filter.Triggers, models.RunTrigger(trigger))
+	}
+
+	var err error
+	if filter.From, err = parseTimeQuery(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseTimeQuery(c, "to"); err != nil {
+		return filter, err
+	}
+	for _, param := range []struct {
+		name  string
+		value *float64
+	}{
+		{"minDuration", &filter.MinDuration},
+		{"maxDuration", &filter.MaxDuration},
+	} {
+		value := c.QueryParam(param.name)
+		if value == "" {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds < 0 {
+			return filter, fmt.Errorf("%s must be a number of seconds", param.name)
+		}
+		*param.value = seconds
+	}
+	return filter, nil
+}
+
+// runWriter writes exported runs in one format
+type runWriter interface {
+	contentType() string
+	extension() string
+	start(res *echo.Response)
+	write(run *repository.RunWithJob) error
+}
+
+type csvRunWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvRunWriter) contentType() string { return "text/csv; charset=utf-8" }
+func (w *csvRunWriter) extension() string   { return "csv" }
+
+func (w *csvRunWriter) start(res *echo.Response) {
+	w.w = csv.NewWriter(res)
+	w.w.Write([]string{"id", "jobId", "jobName", "projectId", "projectName", "status", "trigger", "startTime", "endTime", "duration", "slaBreached", "violation", "revisionId", "traceId", "error", "output"})
+	w.w.Flush()
+}
+
+func (w *csvRunWriter) write(run *repository.RunWithJob) error {
+	endTime := ""
+	if !run.EndTime.IsZero() {
+		endTime = run.EndTime.UTC().Format(time.RFC3339Nano)
+	}
+	w.w.Write([]string{
+		run.ID,
+		run.JobID,
+		run.JobName,
+		run.ProjectID,
+		run.ProjectName,
+		string(run.Status),
+		string(run.Trigger),
+		run.StartTime.UTC().Format(time.RFC3339Nano),
+		endTime,
+		strconv.FormatFloat(run.Duration, 'f', -1, 64),
+		strconv.FormatBool(run.SLABreached),
+		run.Violation,
+		run.RevisionID,
+		run.TraceID,
+		run.Error,
+		run.Output,
+	})
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type ndjsonRunWriter struct {
+	enc *json.Encoder
+}
+
+func (w *ndjsonRunWriter) contentType() string { return "application/x-ndjson" }
+func (w *ndjsonRunWriter) extension() string   { return "ndjson" }
+
+func (w *ndjsonRunWriter) start(res *echo.Response) {
+	w.enc = json.NewEncoder(res)
+}
+
+func (w *ndjsonRunWriter) write(run *repository.RunWithJob) error {
+	// Encode ends every run with a newline
+	return w.enc.Encode(run)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"crontab/internal/services"
+)
+
+type SearchHandler struct {
+	search *services.SearchService
+}
+
+func NewSearchHandler(search *services.SearchService) *SearchHandler {
+	return &SearchHandler{search: search}
+}
+
+// Search godoc
+// @Summary Search jobs and run output
+// @Description Finds the jobs containing every word of q in their name, tags, endpoint, command or description, and optionally the runs containing them in their output or error. Results are ranked best first and the matching words are wrapped in <mark> in HTML-escaped snippets. Jobs in the trash, jobs of projects the role is not allowed to access and their runs are left out.
+// @Tags search
+// @Accept json
+// @Produce json
+// @Param q query string true "Words to search for; punctuation separates words"
+// @Param includeLogs query bool false "Also search the output and errors of runs"
+// @Param projectId query string false "Only jobs of this project and their runs"
+// @Param limit query int false "Maximum number of results (default 20, max 100)"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 403 {object} map[string]interface{} "error"
+// @Router /search [get]
+func (h *SearchHandler) Search(c echo.Context) error {
+	options := services.SearchOptions{ProjectID: c.QueryParam("projectId")}
+	if value := c.QueryParam("includeLogs"); value != "" {
+		includeLogs, err := strconv.ParseBool(value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"error":   "includeLogs must be true or false",
+			})
+		}
+		options.IncludeRuns = includeLogs
+	}
+	if value := c.QueryParam("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"success": false,
+				"error":   "limit must be between 1 and 100",
+			})
+		}
+		options.Limit = limit
+	}
+
+	hits, err := h.search.Search(c.Request().Context(), actorOf(c), c.QueryParam("q"), options)
+	if services.IsValidationError(err) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to search: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    hits,
+	})
+}
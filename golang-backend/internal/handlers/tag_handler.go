@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	
+	"crontab/internal/models"
+)
+
+type TagHandler struct {
+	db *gorm.DB
+}
+
+func NewTagHandler(db *gorm.DB) *TagHandler {
+	return &TagHandler{db: db}
+}
+
+// tagRequest is the body accepted when creating or updating a tag
+type tagRequest struct {
+	ProjectID string `json:"projectId"`
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+}
+
+// GetTags godoc
+// @Summary Get tags
+// @Description Retrieves tags with the number of jobs using each, optionally for a single project
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param projectId query string false "Only tags of this project"
+// @Success 200 {object} map[string]interface{} "success"
+// @Router /tags [get]
+func (h *TagHandler) GetTags(c echo.Context) error {
+	tags, err := models.ListTags(h.db, c.QueryParam("projectId"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to fetch tags: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    tags,
+	})
+}
+
+// CreateTag godoc
+// @Summary Create a tag
+// @Description Creates a tag in a project. Tags are also created when a job uses a new tag name.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param tag body tagRequest true "Tag details"
+// @Success 201 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Router /tags [post]
+func (h *TagHandler) CreateTag(c echo.Context) error {
+	req := new(tagRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid request data: " + err.Error(),
+		})
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, "id = ?", req.ProjectID).Error; err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Project not found",
+		})
+	}
+
+	tag := models.Tag{
+		ProjectID: project.ID,
+		Name:      req.Name,
+		Color:     req.Color,
+	}
+	if err := tag.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid tag: " + err.Error(),
+		})
+	}
+
+	if h.nameTaken(tag) {
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"success": false,
+			"error":   "A tag with this name already exists in the project",
+		})
+	}
+
+	if err := h.db.Create(&tag).Error; err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to create tag: " + err.Error(),
+		})
+	}
+	recordAudit(c, h.db, models.AuditTagCreate, "tag", tag.ID, nil, tag)
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    tag,
+		"message": "Tag created successfully",
+	})
+}
+
+// UpdateTag godoc
+// @Summary Update a tag
+// @Description Renames or recolors a tag. Jobs using the tag follow the new name.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Param tag body tagRequest true "Tag details"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Router /tags/{id} [put]
+func (h *TagHandler) UpdateTag(c echo.Context) error {
+	id := c.Param("id")
+	
+	var tag models.Tag
+	if err := h.db.First(&tag, "id = ?", id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Tag not found",
+		})
+	}
+
+	req := new(tagRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid request data: " + err.Error(),
+		})
+	}
+
+	before := tag
+	tag.Name = req.Name
+	tag.Color = req.Color
+	if err := tag.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"error":   "Invalid tag: " + err.Error(),
+		})
+	}
+
+	if h.nameTaken(tag) {
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"success": false,
+			"error":   "A tag with this name already exists in the project",
+		})
+	}
+
+	// Jobs are found by the names of their tags
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&tag).Error; err != nil {
+			return err
+		}
+		jobIDs, err := models.TaggedJobIDs(tx, tag.ID)
+		if err != nil {
+			return err
+		}
+		return models.ReindexJobs(tx, jobIDs)
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to update tag: " + err.Error(),
+		})
+	}
+	recordAudit(c, h.db, models.AuditTagUpdate, "tag", tag.ID, before, tag)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    tag,
+		"message": "Tag updated successfully",
+	})
+}
+
+// DeleteTag godoc
+// @Summary Delete a tag
+// @Description Deletes a tag and removes it from every job
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Router /tags/{id} [delete]
+func (h *TagHandler) DeleteTag(c echo.Context) error {
+	id := c.Param("id")
+	
+	var tag models.Tag
+	if err := h.db.First(&tag, "id = ?", id).Error; err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Tag not found",
+		})
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		jobIDs, err := models.TaggedJobIDs(tx, tag.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Table("job_tags").Where("tag_id = ?", tag.ID).Delete(nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&tag).Error; err != nil {
+			return err
+		}
+		return models.ReindexJobs(tx, jobIDs)
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to delete tag: " + err.Error(),
+		})
+	}
+	recordAudit(c, h.db, models.AuditTagDelete, "tag", tag.ID, tag, nil)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Tag deleted successfully",
+	})
+}
+
+// nameTaken reports whether another tag of the same project has the tag's name
+func (h *TagHandler) nameTaken(tag models.Tag) bool {
+	var count int64
+	h.db.Model(&models.Tag{}).
+		Where("project_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", tag.ProjectID, tag.Name, tag.ID).
+		Count(&count)
+	return count > 0
+}
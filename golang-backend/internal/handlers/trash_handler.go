@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	
+	"crontab/internal/models"
+	"crontab/internal/services"
+)
+
+type TrashHandler struct {
+	trash *services.TrashService
+}
+
+func NewTrashHandler(trash *services.TrashService) *TrashHandler {
+	return &TrashHandler{
+		trash: trash,
+	}
+}
+
+// GetTrash godoc
+// @Summary Get the trash
+// @Description Retrieves the deleted jobs and projects that can still be restored, of the projects the user may access
+// @Tags trash
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "success"
+// @Router /trash [get]
+func (h *TrashHandler) GetTrash(c echo.Context) error {
+	trash, err := h.trash.List(c.Request().Context(), actorOf(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to fetch trash: " + err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    trash,
+	})
+}
+
+// RestoreItem godoc
+// @Summary Restore from the trash
+// @Description Restores a deleted job, or a deleted project together with the jobs deleted with it
+// @Tags trash
+// @Accept json
+// @Produce json
+// @Param type path string true "job or project"
+// @Param id path string true "Job or project ID"
+// @Success 200 {object} map[string]interface{} "success"
+// @Failure 400 {object} map[string]interface{} "error"
+// @Failure 404 {object} map[string]interface{} "error"
+// @Failure 409 {object} map[string]interface{} "error"
+// @Router /trash/{type}/{id}/restore [post]
+func (h *TrashHandler) RestoreItem(c echo.Context) error {
+	ctx, id := c.Request().Context(), c.Param("id")
+	
+	switch c.Param("type") {
+	case "job":
+		job, err := h.trash.RestoreJob(ctx, actorOf(c), id)
+		if err != nil {
+			return h.restoreError(c, "Job", err)
+		}
+		
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    job,
+			"message": "Job restored successfully",
+		})
+		
+	case "project":
+		project, err := h.trash.RestoreProject(ctx, actorOf(c), id)
+		if err != nil {
+			return h.restoreError(c, "Project", err)
+		}
+		
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    project,
+			"message": "Project restored successfully",
+		})
+	}
+
+	return c.JSON(http.StatusBadRequest, map[string]interface{}{
+		"success": false,
+		"error":   "Type must be \"job\" or \"project\"",
+	})
+}
+
+// restoreError maps the errors of a restore to a response
+func (h *TrashHandler) restoreError(c echo.Context, kind string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   kind + " not found in the trash",
+		})
+	case errors.Is(err, models.ErrProjectInTrash):
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		"success": false,
+		"error":   "Failed to restore " + strings.ToLower(kind) + ": " + err.Error(),
+	})
+}
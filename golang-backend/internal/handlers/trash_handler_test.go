@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"crontab/internal/models"
+)
+
+func TestTrashHandlerRestoresJobsOfAccessibleProjects(t *testing.T) {
+	s := newTestServer(t)
+	ops, billing := s.project(t, "Ops"), s.project(t, "Billing")
+	backup, invoices := s.job(t, ops.ID, "Backup"), s.job(t, billing.ID, "Invoices")
+	for _, id := range []string{backup.ID, invoices.ID} {
+		if err := s.jobs.Delete(context.Background(), admin, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.restrictTo(t, ops.ID)
+
+	var trash models.Trash
+	decode(t, s.do(t, http.MethodGet, "/trash", nil), &trash)
+	if len(trash.Jobs) != 1 || trash.Jobs[0].ID != backup.ID {
+		t.Errorf("trash = %+v, want only the job of the accessible project", trash.Jobs)
+	}
+	if rec := s.do(t, http.MethodPost, "/trash/job/"+invoices.ID+"/restore", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("restoring a job of another project = %d, want 404", rec.Code)
+	}
+	if rec := s.do(t, http.MethodPost, "/trash/job/"+backup.ID+"/restore", nil); rec.Code != http.StatusOK {
+		t.Fatalf("restore = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(t, http.MethodGet, "/jobs/"+backup.ID, nil); rec.Code != http.StatusOK {
+		t.Errorf("restored job = %d, want 200", rec.Code)
+	}
+}
+
+func TestTrashHandlerRestoresProjectBeforeItsJobs(t *testing.T) {
+	s := newTestServer(t)
+	project := s.project(t, "Ops")
+	job := s.job(t, project.ID, "Backup")
+	if _, err := s.projects.Delete(context.Background(), admin, project.ID, models.ProjectDeleteCascade, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := s.do(t, http.MethodPost, "/trash/job/"+job.ID+"/restore", nil); rec.Code != http.StatusConflict {
+		t.Errorf("restoring a job of a deleted project = %d, want 409", rec.Code)
+	}
+
+	var restored models.Project
+	decode(t, s.do(t, http.MethodPost, "/trash/project/"+project.ID+"/restore", nil), &restored)
+	if len(restored.Jobs) != 1 || restored.Jobs[0].ID != job.ID {
+		t.Errorf("restored jobs = %+v, want the job deleted with the project", restored.Jobs)
+	}
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	
+	"crontab/internal/models"
+	"crontab/internal/repository"
+	"crontab/internal/services"
+	"crontab/pkg/mergepatch"
+)
+
+// defaultStatsWindow is the range used when no "from" query parameter is given
+const defaultStatsWindow = 30 * 24 * time.Hour
+
+// parseTimeParam parses an RFC 3339 timestamp or a YYYY-MM-DD date
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or YYYY-MM-DD", value)
+}
+
+// parseTimeRange reads the "from" and "to" query parameters, defaulting to the last 30 days
+func parseTimeRange(c echo.Context) (time.Time, time.Time, error) {
+	to := time.Now()
+	if value := c.QueryParam("to"); value != "" {
+		t, err := parseTimeParam(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultStatsWindow)
+	if value := c.QueryParam("from"); value != "" {
+		t, err := parseTimeParam(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = t
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("\"from\" must be before \"to\"")
+	}
+
+	return from, to, nil
+}
+
+// Page sizes of list endpoints
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// parsePage reads the "limit", "cursor" and "sort" query parameters of a list endpoint
+func parsePage(c echo.Context) (repository.Page, error) {
+	page := repository.Page{
+		Limit:  defaultPageLimit,
+		Cursor: c.QueryParam("cursor"),
+		Sort:   c.QueryParam("sort"),
+	}
+	if value := c.QueryParam("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return page, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		page.Limit = limit
+	}
+	return page, nil
+}
+
+// parseStatuses reads the repeatable "status" query parameter
+func parseStatuses(c echo.Context) []models.JobStatus {
+	var statuses []models.JobStatus
+	for _, status := range c.QueryParams()["status"] {
+		statuses = append(statuses, models.JobStatus(status))
+	}
+	return statuses
+}
+
+// parseTimeQuery reads an optional time query parameter, returning the zero time when it is missing
+func parseTimeQuery(c echo.Context, name string) (time.Time, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := parseTimeParam(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %v", name, err)
+	}
+	return t, nil
+}
+
+// paginated answers a list request with one page of records
+func paginated(c echo.Context, data interface{}, info repository.PageInfo) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"data":       data,
+		"pagination": info,
+	})
+}
+
+// currentActor returns the email of the authenticated user, or "api" when the request is not authenticated
+func currentActor(c echo.Context) string {
+	if user, ok := c.Get("user").(models.User); ok {
+		return user.Email
+	}
+	return "api"
+}
+
+// actorOf describes the caller of the current request to the services
+func actorOf(c echo.Context) services.Actor {
+	actor := services.Actor{
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+	if user, ok := c.Get("user").(models.User); ok {
+		actor.UserID = user.ID
+		actor.Email = user.Email
+		permissions, _ := models.UnmarshalPermissions(user.Role.PermissionsJSON)
+		actor.Projects = models.ProjectScope(permissions)
+		actor.Admin = user.Role.Name == "admin"
+	}
+	return actor
+}
+
+// newAuditEntry describes an action of the current request for the audit log
+func newAuditEntry(c echo.Context, action models.AuditAction, resourceType, resourceID string, before, after interface{}) models.AuditEntry {
+	entry := models.AuditEntry{
+		Actor:        currentActor(c),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Before:       models.AuditSnapshot(before),
+		After:        models.AuditSnapshot(after),
+		IP:           c.RealIP(),
+		UserAgent:    c.Request().UserAgent(),
+	}
+	if user, ok := c.Get("user").(models.User); ok {
+		entry.ActorID = user.ID
+	}
+	return entry
+}
+
+// recordAudit appends an entry to the audit log. Failures are logged but never fail the request.
+func recordAudit(c echo.Context, db *gorm.DB, action models.AuditAction, resourceType, resourceID string, before, after interface{}) {
+	saveAuditEntry(c, db, newAuditEntry(c, action, resourceType, resourceID, before, after))
+}
+
+// saveAuditEntry stores an entry built with newAuditEntry
+func saveAuditEntry(c echo.Context, db *gorm.DB, entry models.AuditEntry) {
+	if err := db.Create(&entry).Error; err != nil {
+		c.Logger().Errorf("Failed to record audit entry %s: %v", entry.Action, err)
+	}
+}
+
+// etag returns the entity tag of a version of a resource
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// setETag adds the ETag header of a version to the response
+func setETag(c echo.Context, version int64) {
+	c.Response().Header().Set("ETag", etag(version))
+}
+
+// checkIfMatch compares the If-Match header of a write with the current version of the resource.
+// It returns 428 when the header is missing, 412 when it names another version and 0 when the write may go ahead.
+func checkIfMatch(c echo.Context, version int64) int {
+	header := c.Request().Header.Get("If-Match")
+	if header == "" {
+		return http.StatusPreconditionRequired
+	}
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == current {
+			return 0
+		}
+	}
+	return http.StatusPreconditionFailed
+}
+
+// versionMismatch answers a write based on an outdated version with the current representation of the resource
+func versionMismatch(c echo.Context, status int, current interface{}, version int64) error {
+	message := "The resource was changed by someone else, reload it and apply your changes again"
+	if status == http.StatusPreconditionRequired {
+		message = "If-Match header with the ETag of the resource is required"
+	}
+	setETag(c, version)
+	return c.JSON(status, map[string]interface{}{
+		"success": false,
+		"error":   message,
+		"data":    current,
+	})
+}
+
+// bindMergePatch applies the JSON Merge Patch in the request body to the JSON representation of current
+// and decodes the result into patched. On failure it returns the status to answer with.
+func bindMergePatch(c echo.Context, current, patched interface{}) (int, error) {
+	if contentType := c.Request().Header.Get(echo.HeaderContentType); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != echo.MIMEApplicationJSON) {
+			return http.StatusUnsupportedMediaType, fmt.Errorf("content type must be application/merge-patch+json")
+		}
+	}
+
+	patch, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	original, err := json.Marshal(current)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	merged, err := mergepatch.ApplyObject(original, patch)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if err := json.Unmarshal(merged, patched); err != nil {
+		return http.StatusBadRequest, err
+	}
+	return 0, nil
+}
@@ -2,6 +2,7 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
 	"net/http"
@@ -80,3 +81,81 @@ func parseToken(token string) (map[string]string, error) {
 	
 	return payload, nil
 }
+
+// MetricsTokenMiddleware protects the metrics endpoint with a static bearer token
+func MetricsTokenMiddleware(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authHeader := c.Request().Header.Get("Authorization")
+			if subtle.ConstantTimeCompare([]byte(authHeader), []byte("Bearer "+token)) != 1 {
+				return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+					"success": false,
+					"error":   "Invalid metrics token",
+				})
+			}
+			
+			return next(c)
+		}
+	}
+}
+
+// RequireRole only lets authenticated users with one of the given roles through.
+// It must run after AuthMiddleware.
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user, ok := c.Get("user").(models.User)
+			if ok {
+				for _, role := range roles {
+					if user.Role.Name == role {
+						return next(c)
+					}
+				}
+			}
+			
+			return c.JSON(http.StatusForbidden, map[string]interface{}{
+				"success": false,
+				"error":   "Insufficient permissions",
+			})
+		}
+	}
+}
+
+// RequirePermission only lets authenticated users whose role grants all the given permissions through.
+// It must run after AuthMiddleware.
+func RequirePermission(permissions ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user, ok := c.Get("user").(models.User)
+			if ok {
+				granted, _ := models.UnmarshalPermissions(user.Role.PermissionsJSON)
+				ok = hasPermissions(granted, permissions)
+			}
+			if !ok {
+				return c.JSON(http.StatusForbidden, map[string]interface{}{
+					"success": false,
+					"error":   "Insufficient permissions",
+				})
+			}
+			
+			return next(c)
+		}
+	}
+}
+
+// hasPermissions reports whether granted includes every permission in wanted
+func hasPermissions(granted, wanted []string) bool {
+	for _, permission := range wanted {
+		found := false
+		for _, g := range granted {
+			if g == permission {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
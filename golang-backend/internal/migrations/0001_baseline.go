@@ -0,0 +1,236 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// baseline creates the schema as it was before versioned migrations were introduced.
+// It uses AutoMigrate so that databases created by earlier releases, which already have
+// these tables, are adopted without changes.
+var baseline = Migration{
+	Version: 1,
+	Name:    "baseline",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(baselineTables()...)
+	},
+	Down: func(tx *gorm.DB) error {
+		tables := baselineTables()
+		// Drop in reverse order so that referencing tables go first
+		for i := len(tables) - 1; i >= 0; i-- {
+			if err := tx.Migrator().DropTable(tables[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func baselineTables() []interface{} {
+	return []interface{}{
+		&baselineRole{},
+		&baselineUser{},
+		&baselineProject{},
+		&baselineJob{},
+		&baselineJobLog{},
+		&baselineJobRunStats{},
+		&baselineNotificationDelivery{},
+		&baselineNotificationChannel{},
+		&baselineJobChannelSubscription{},
+		&baselineAlertRule{},
+		&baselineAlert{},
+	}
+}
+
+type baselineProject struct {
+	ID          string        `gorm:"primaryKey;type:varchar(36)"`
+	Name        string        `gorm:"type:varchar(100);not null"`
+	Description string        `gorm:"type:varchar(500)"`
+	CreatedAt   time.Time     `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time     `gorm:"autoUpdateTime"`
+	Jobs        []baselineJob `gorm:"foreignKey:ProjectID"`
+}
+
+func (baselineProject) TableName() string { return "projects" }
+
+type baselineJob struct {
+	ID                     string           `gorm:"primaryKey;type:varchar(36)"`
+	Name                   string           `gorm:"type:varchar(100);not null"`
+	Type                   string           `gorm:"type:varchar(10);default:'shell'"`
+	Command                string           `gorm:"type:varchar(500);not null"`
+	Endpoint               string           `gorm:"type:varchar(500)"`
+	HTTPMethod             string           `gorm:"column:http_method;type:varchar(10)"`
+	RequestBody            string           `gorm:"type:text"`
+	HeadersJSON            string           `gorm:"column:headers;type:text"`
+	Schedule               string           `gorm:"type:varchar(100);not null"`
+	Description            string           `gorm:"type:varchar(500)"`
+	Status                 string           `gorm:"type:varchar(10);default:'idle'"`
+	LastRun                time.Time        `gorm:"default:null"`
+	NextRun                time.Time        `gorm:"default:null"`
+	Tags                   string           `gorm:"type:varchar(255)"`
+	FailCount              int              `gorm:"default:0"`
+	SuccessCount           int              `gorm:"default:0"`
+	ProjectID              string           `gorm:"type:varchar(36);not null"`
+	CreatedAt              time.Time        `gorm:"autoCreateTime"`
+	UpdatedAt              time.Time        `gorm:"autoUpdateTime"`
+	Timezone               string           `gorm:"type:varchar(50);default:'UTC'"`
+	UseLocalTime           bool             `gorm:"default:false"`
+	WorkingDir             string           `gorm:"type:varchar(255)"`
+	RunAsUser              string           `gorm:"type:varchar(64)"`
+	RunAsGroup             string           `gorm:"type:varchar(64)"`
+	CPULimit               int              `gorm:"default:0"`
+	MemoryLimit            int              `gorm:"default:0"`
+	MaxOpenFiles           int              `gorm:"default:0"`
+	MaxProcesses           int              `gorm:"default:0"`
+	EmailNotificationsJSON string           `gorm:"column:email_notifications;type:text"`
+	WebhooksJSON           string           `gorm:"column:webhooks;type:text"`
+	Logs                   []baselineJobLog `gorm:"foreignKey:JobID"`
+	AverageRuntime         float64          `gorm:"default:0"`
+	AutoPauseAfterFailures int              `gorm:"default:0"`
+	ConsecutiveFailures    int              `gorm:"default:0"`
+	PausedReason           string           `gorm:"type:varchar(255)"`
+	PausedBy               string           `gorm:"type:varchar(100)"`
+	PausedAt               *time.Time
+	PingToken              string `gorm:"type:varchar(64);index"`
+	GracePeriod            int    `gorm:"default:0"`
+	ExpectedDuration       int    `gorm:"default:0"`
+}
+
+func (baselineJob) TableName() string { return "jobs" }
+
+type baselineJobLog struct {
+	ID          string    `gorm:"primaryKey;type:varchar(36)"`
+	JobID       string    `gorm:"type:varchar(36);not null"`
+	Status      string    `gorm:"type:varchar(10);not null"`
+	StartTime   time.Time `gorm:"not null"`
+	EndTime     time.Time
+	Duration    float64
+	Output      string    `gorm:"type:text"`
+	Error       string    `gorm:"type:text"`
+	Violation   string    `gorm:"type:varchar(255)"`
+	Trigger     string    `gorm:"type:varchar(20);default:'schedule'"`
+	TraceID     string    `gorm:"type:varchar(32)"`
+	SLABreached bool      `gorm:"default:false"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+func (baselineJobLog) TableName() string { return "job_logs" }
+
+type baselineJobRunStats struct {
+	JobID             string    `gorm:"primaryKey;type:varchar(36)"`
+	RunCount          int64     `gorm:"default:0"`
+	SuccessCount      int64     `gorm:"default:0"`
+	FailCount         int64     `gorm:"default:0"`
+	DurationSum       float64   `gorm:"default:0"`
+	DurationMin       float64   `gorm:"default:0"`
+	DurationMax       float64   `gorm:"default:0"`
+	DurationEWMA      float64   `gorm:"column:duration_ewma;default:0"`
+	LastDurationsJSON string    `gorm:"column:last_durations;type:varchar(500)"`
+	LastRunAt         time.Time `gorm:"default:null"`
+	Version           int64     `gorm:"default:0"`
+	UpdatedAt         time.Time `gorm:"autoUpdateTime"`
+}
+
+func (baselineJobRunStats) TableName() string { return "job_run_stats" }
+
+type baselineNotificationDelivery struct {
+	ID             string `gorm:"primaryKey;type:varchar(36)"`
+	EventID        string `gorm:"type:varchar(36);index"`
+	JobID          string `gorm:"type:varchar(36);index;not null"`
+	ChannelID      string `gorm:"type:varchar(36);index"`
+	JobLogID       string `gorm:"type:varchar(36);index"`
+	Channel        string `gorm:"type:varchar(20);not null"`
+	Event          string `gorm:"type:varchar(20);not null"`
+	Target         string `gorm:"type:varchar(1000)"`
+	Status         string `gorm:"type:varchar(10);not null"`
+	Attempt        int    `gorm:"default:1"`
+	Payload        string `gorm:"type:text"`
+	ResponseStatus int
+	ResponseBody   string    `gorm:"type:text"`
+	Error          string    `gorm:"type:text"`
+	RedeliveryOf   string    `gorm:"type:varchar(36)"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+func (baselineNotificationDelivery) TableName() string { return "notification_deliveries" }
+
+type baselineNotificationChannel struct {
+	ID           string `gorm:"primaryKey;type:varchar(36)"`
+	ProjectID    string `gorm:"type:varchar(36);index;not null"`
+	Name         string `gorm:"type:varchar(100);not null"`
+	Type         string `gorm:"type:varchar(10);not null"`
+	Enabled      bool
+	SettingsJSON string    `gorm:"column:settings;type:text"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
+
+func (baselineNotificationChannel) TableName() string { return "notification_channels" }
+
+type baselineJobChannelSubscription struct {
+	ID         string                       `gorm:"primaryKey;type:varchar(36)"`
+	JobID      string                       `gorm:"type:varchar(36);index;not null"`
+	ChannelID  string                       `gorm:"type:varchar(36);index;not null"`
+	EventsList string                       `gorm:"column:events;type:varchar(100)"`
+	Channel    *baselineNotificationChannel `gorm:"foreignKey:ChannelID"`
+	CreatedAt  time.Time                    `gorm:"autoCreateTime"`
+}
+
+func (baselineJobChannelSubscription) TableName() string { return "job_channel_subscriptions" }
+
+type baselineAlertRule struct {
+	ID        string `gorm:"primaryKey;type:varchar(36)"`
+	JobID     string `gorm:"type:varchar(36);index;not null"`
+	Name      string `gorm:"type:varchar(100)"`
+	Type      string `gorm:"type:varchar(30);not null"`
+	Threshold float64
+	Window    int
+	Enabled   bool
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (baselineAlertRule) TableName() string { return "alert_rules" }
+
+type baselineAlert struct {
+	ID            string `gorm:"primaryKey;type:varchar(36)"`
+	RuleID        string `gorm:"type:varchar(36);index;not null"`
+	JobID         string `gorm:"type:varchar(36);index;not null"`
+	RuleType      string `gorm:"type:varchar(30)"`
+	Status        string `gorm:"type:varchar(10);index;not null"`
+	Message       string `gorm:"type:varchar(255)"`
+	Value         float64
+	FiredLogID    string `gorm:"type:varchar(36)"`
+	ResolvedLogID string `gorm:"type:varchar(36)"`
+	FiredAt       time.Time
+	ResolvedAt    *time.Time
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+func (baselineAlert) TableName() string { return "alerts" }
+
+type baselineUser struct {
+	ID        string       `gorm:"primaryKey;type:varchar(36)"`
+	Name      string       `gorm:"type:varchar(100);not null"`
+	Email     string       `gorm:"type:varchar(100);uniqueIndex;not null"`
+	Password  string       `gorm:"type:varchar(100);not null"`
+	Avatar    string       `gorm:"type:varchar(255)"`
+	RoleID    string       `gorm:"type:varchar(36);not null"`
+	Role      baselineRole `gorm:"foreignKey:RoleID"`
+	CreatedAt time.Time    `gorm:"autoCreateTime"`
+	UpdatedAt time.Time    `gorm:"autoUpdateTime"`
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineRole struct {
+	ID              string         `gorm:"primaryKey;type:varchar(36)"`
+	Name            string         `gorm:"type:varchar(50);uniqueIndex;not null"`
+	PermissionsJSON string         `gorm:"column:permissions;type:text"`
+	CreatedAt       time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt       time.Time      `gorm:"autoUpdateTime"`
+	Users           []baselineUser `gorm:"foreignKey:RoleID"`
+}
+
+func (baselineRole) TableName() string { return "roles" }
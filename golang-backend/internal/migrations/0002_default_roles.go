@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"crontab/internal/models"
+)
+
+// defaultRoles seeds the roles every installation starts with. Existing roles of the same
+// name are left untouched.
+var defaultRoles = Migration{
+	Version: 2,
+	Name:    "default roles",
+	Up: func(tx *gorm.DB) error {
+		for _, r := range defaultRoleSeeds {
+			var count int64
+			if err := tx.Model(&seedRole{}).Where("name = ?", r.name).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+
+			permissions, err := json.Marshal(r.permissions)
+			if err != nil {
+				return fmt.Errorf("failed to marshal permissions: %v", err)
+			}
+
+			role := seedRole{
+				ID:              models.NewID(),
+				Name:            r.name,
+				PermissionsJSON: string(permissions),
+			}
+			if err := tx.Create(&role).Error; err != nil {
+				return fmt.Errorf("failed to create role %s: %v", r.name, err)
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		names := make([]string, 0, len(defaultRoleSeeds))
+		for _, r := range defaultRoleSeeds {
+			names = append(names, r.name)
+		}
+		return tx.Where("name IN ?", names).Delete(&seedRole{}).Error
+	},
+}
+
+var defaultRoleSeeds = []struct {
+	name        string
+	permissions []string
+}{
+	{name: "admin", permissions: []string{"view", "create", "update", "delete", "manage_users"}},
+	{name: "user", permissions: []string{"view", "create", "update"}},
+	{name: "viewer", permissions: []string{"view"}},
+}
+
+type seedRole struct {
+	ID              string
+	Name            string
+	PermissionsJSON string `gorm:"column:permissions"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (seedRole) TableName() string { return "roles" }
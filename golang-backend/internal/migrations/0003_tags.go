@@ -0,0 +1,123 @@
+package migrations
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"crontab/internal/models"
+)
+
+// tags replaces the comma separated jobs.tags column with per-project tags linked to
+// jobs through the job_tags table. Existing values are split into tags of the job's project.
+var tags = Migration{
+	Version: 3,
+	Name:    "tags",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&tagsTag{}, &tagsJobTag{}); err != nil {
+			return err
+		}
+
+		var jobs []tagsLegacyJob
+		if err := tx.Where("tags IS NOT NULL AND tags <> ''").Find(&jobs).Error; err != nil {
+			return err
+		}
+
+		for _, job := range jobs {
+			seen := map[string]bool{}
+			for _, name := range strings.Split(job.Tags, ",") {
+				name = strings.TrimSpace(name)
+				if len(name) > models.MaxTagNameLength {
+					name = name[:models.MaxTagNameLength]
+				}
+				if name == "" || seen[strings.ToLower(name)] {
+					continue
+				}
+				seen[strings.ToLower(name)] = true
+
+				var tag tagsTag
+				if err := tx.Where(tagsTag{ProjectID: job.ProjectID, Name: name}).
+					Attrs(tagsTag{ID: models.NewID()}).
+					FirstOrCreate(&tag).Error; err != nil {
+					return err
+				}
+				if err := tx.Create(&tagsJobTag{JobID: job.ID, TagID: tag.ID}).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Exec("ALTER TABLE jobs DROP COLUMN tags").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&tagsLegacyJob{}, "Tags"); err != nil {
+			return err
+		}
+
+		var links []struct {
+			JobID string
+			Name  string
+		}
+		if err := tx.Table("job_tags").
+			Select("job_tags.job_id, tags.name").
+			Joins("JOIN tags ON tags.id = job_tags.tag_id").
+			Scan(&links).Error; err != nil {
+			return err
+		}
+
+		names := map[string][]string{}
+		for _, link := range links {
+			names[link.JobID] = append(names[link.JobID], link.Name)
+		}
+		for jobID, jobTags := range names {
+			sort.Strings(jobTags)
+			joined := strings.Join(jobTags, ",")
+			if len(joined) > 255 {
+				joined = joined[:255]
+			}
+			if err := tx.Model(&tagsLegacyJob{}).Where("id = ?", jobID).Update("tags", joined).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Migrator().DropTable(&tagsJobTag{}, &tagsTag{})
+	},
+}
+
+type tagsTag struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)"`
+	ProjectID string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_tags_project_name"`
+	Name      string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_tags_project_name"`
+	Color     string    `gorm:"type:varchar(7)"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (tagsTag) TableName() string { return "tags" }
+
+type tagsJobTag struct {
+	JobID string     `gorm:"primaryKey;type:varchar(36)"`
+	TagID string     `gorm:"primaryKey;type:varchar(36);index"`
+	Job   tagsJobRef `gorm:"foreignKey:JobID;constraint:OnDelete:CASCADE"`
+	Tag   tagsTag    `gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE"`
+}
+
+func (tagsJobTag) TableName() string { return "job_tags" }
+
+// tagsJobRef is the referenced side of the job_tags foreign key. It only declares the
+// primary key, since AutoMigrate also migrates referenced tables.
+type tagsJobRef struct {
+	ID string `gorm:"primaryKey;type:varchar(36)"`
+}
+
+func (tagsJobRef) TableName() string { return "jobs" }
+
+type tagsLegacyJob struct {
+	ID        string `gorm:"primaryKey;type:varchar(36)"`
+	ProjectID string `gorm:"type:varchar(36)"`
+	Tags      string `gorm:"type:varchar(255)"`
+}
+
+func (tagsLegacyJob) TableName() string { return "jobs" }
@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// softDelete adds the deleted_at column that moves jobs and projects to the trash
+// instead of deleting them.
+var softDelete = Migration{
+	Version: 4,
+	Name:    "soft delete jobs and projects",
+	Up: func(tx *gorm.DB) error {
+		for _, table := range []interface{}{&softDeleteJob{}, &softDeleteProject{}} {
+			if err := tx.Migrator().AddColumn(table, "DeletedAt"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateIndex(table, "DeletedAt"); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		// Rows in the trash would silently reappear without the column
+		for _, table := range []string{"jobs", "projects"} {
+			var count int64
+			if err := tx.Table(table).Where("deleted_at IS NOT NULL").Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("%d %s are in the trash, restore or purge them first", count, table)
+			}
+		}
+		for _, table := range []interface{}{&softDeleteJob{}, &softDeleteProject{}} {
+			if err := tx.Migrator().DropIndex(table, "DeletedAt"); err != nil {
+				return err
+			}
+		}
+		for _, table := range []string{"jobs", "projects"} {
+			if err := tx.Exec("ALTER TABLE " + table + " DROP COLUMN deleted_at").Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+type softDeleteJob struct {
+	ID        string         `gorm:"primaryKey;type:varchar(36)"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (softDeleteJob) TableName() string { return "jobs" }
+
+type softDeleteProject struct {
+	ID        string         `gorm:"primaryKey;type:varchar(36)"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (softDeleteProject) TableName() string { return "projects" }
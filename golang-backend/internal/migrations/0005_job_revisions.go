@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// jobRevisions adds the job_revisions table and links jobs and their logs to revisions.
+// Existing jobs get their first revision when they next run or change.
+var jobRevisions = Migration{
+	Version: 5,
+	Name:    "job revisions",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&revisionsJobRevision{}); err != nil {
+			return err
+		}
+		if err := tx.Migrator().AddColumn(&revisionsJob{}, "RevisionID"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().AddColumn(&revisionsJobLog{}, "RevisionID"); err != nil {
+			return err
+		}
+		return tx.Migrator().CreateIndex(&revisionsJobLog{}, "RevisionID")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropIndex(&revisionsJobLog{}, "RevisionID"); err != nil {
+			return err
+		}
+		for _, table := range []string{"job_logs", "jobs"} {
+			if err := tx.Exec("ALTER TABLE " + table + " DROP COLUMN revision_id").Error; err != nil {
+				return err
+			}
+		}
+		return tx.Migrator().DropTable(&revisionsJobRevision{})
+	},
+}
+
+type revisionsJobRevision struct {
+	ID                string    `gorm:"primaryKey;type:varchar(36)"`
+	JobID             string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_job_revisions_job_number"`
+	Number            int       `gorm:"not null;uniqueIndex:idx_job_revisions_job_number"`
+	Author            string    `gorm:"type:varchar(100)"`
+	Reason            string    `gorm:"type:varchar(255)"`
+	ChangedFieldsList string    `gorm:"column:changed_fields;type:varchar(1000)"`
+	SpecJSON          string    `gorm:"column:spec;type:text"`
+	CreatedAt         time.Time `gorm:"autoCreateTime"`
+}
+
+func (revisionsJobRevision) TableName() string { return "job_revisions" }
+
+type revisionsJob struct {
+	ID         string `gorm:"primaryKey;type:varchar(36)"`
+	RevisionID string `gorm:"type:varchar(36)"`
+}
+
+func (revisionsJob) TableName() string { return "jobs" }
+
+type revisionsJobLog struct {
+	ID         string `gorm:"primaryKey;type:varchar(36)"`
+	RevisionID string `gorm:"type:varchar(36);index"`
+}
+
+func (revisionsJobLog) TableName() string { return "job_logs" }
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// auditLog adds the append-only audit_entries table
+var auditLog = Migration{
+	Version: 6,
+	Name:    "audit log",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&auditEntry{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&auditEntry{})
+	},
+}
+
+type auditEntry struct {
+	ID           string    `gorm:"primaryKey;type:varchar(36)"`
+	ActorID      string    `gorm:"type:varchar(36);index"`
+	Actor        string    `gorm:"type:varchar(100);index"`
+	Action       string    `gorm:"type:varchar(50);index;not null"`
+	ResourceType string    `gorm:"type:varchar(30);index:idx_audit_entries_resource"`
+	ResourceID   string    `gorm:"type:varchar(36);index:idx_audit_entries_resource"`
+	BeforeJSON   string    `gorm:"column:before;type:text"`
+	AfterJSON    string    `gorm:"column:after;type:text"`
+	IP           string    `gorm:"column:ip;type:varchar(45)"`
+	UserAgent    string    `gorm:"type:varchar(255)"`
+	CreatedAt    time.Time `gorm:"autoCreateTime;index"`
+}
+
+func (auditEntry) TableName() string { return "audit_entries" }
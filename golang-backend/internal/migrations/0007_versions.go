@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// versions adds the version column used to detect concurrent edits of jobs and projects
+var versions = Migration{
+	Version: 7,
+	Name:    "job and project versions",
+	Up: func(tx *gorm.DB) error {
+		for _, table := range []interface{}{&versionsJob{}, &versionsProject{}} {
+			if err := tx.Migrator().AddColumn(table, "Version"); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, table := range []string{"jobs", "projects"} {
+			if err := tx.Exec("ALTER TABLE " + table + " DROP COLUMN version").Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+type versionsJob struct {
+	ID      string `gorm:"primaryKey;type:varchar(36)"`
+	Version int64  `gorm:"not null;default:1"`
+}
+
+func (versionsJob) TableName() string { return "jobs" }
+
+type versionsProject struct {
+	ID      string `gorm:"primaryKey;type:varchar(36)"`
+	Version int64  `gorm:"not null;default:1"`
+}
+
+func (versionsProject) TableName() string { return "projects" }
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// searchIndex adds the search_terms table, an inverted index of the words in jobs and run output.
+// Existing jobs and runs are indexed by cmd/backfill.
+var searchIndex = Migration{
+	Version: 8,
+	Name:    "search index",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&searchTerm{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Exec("DROP TABLE search_terms").Error
+	},
+}
+
+type searchTerm struct {
+	Term        string `gorm:"primaryKey;type:varchar(64)"`
+	DocType     string `gorm:"primaryKey;type:varchar(10);index:idx_search_terms_doc,priority:1"`
+	DocID       string `gorm:"primaryKey;type:varchar(36);index:idx_search_terms_doc,priority:2"`
+	Field       string `gorm:"primaryKey;type:varchar(20)"`
+	JobID       string `gorm:"type:varchar(36);not null;index"`
+	Occurrences int    `gorm:"not null"`
+}
+
+func (searchTerm) TableName() string { return "search_terms" }
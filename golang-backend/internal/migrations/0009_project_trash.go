@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// projectTrash links the jobs moved to the trash with their project to that project, so that
+// restoring the project finds them without comparing deletion times
+var projectTrash = Migration{
+	Version: 9,
+	Name:    "jobs deleted with their project",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&projectTrashJob{}, "DeletedWithProject"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().CreateIndex(&projectTrashJob{}, "DeletedWithProject"); err != nil {
+			return err
+		}
+		// Jobs in the trash that share the deletion time of their project were deleted with it
+		return tx.Exec(`UPDATE jobs SET deleted_with_project = project_id
+			WHERE deleted_at IS NOT NULL
+			AND deleted_at = (SELECT projects.deleted_at FROM projects WHERE projects.id = jobs.project_id)`).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropIndex(&projectTrashJob{}, "DeletedWithProject"); err != nil {
+			return err
+		}
+		return tx.Exec("ALTER TABLE jobs DROP COLUMN deleted_with_project").Error
+	},
+}
+
+type projectTrashJob struct {
+	ID                 string `gorm:"primaryKey;type:varchar(36)"`
+	DeletedWithProject string `gorm:"type:varchar(36);index"`
+}
+
+func (projectTrashJob) TableName() string { return "jobs" }
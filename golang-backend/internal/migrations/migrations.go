@@ -0,0 +1,301 @@
+// Package migrations manages the database schema through versioned migrations.
+//
+// Every change to the schema is a Migration with an increasing version, registered in All.
+// Applied versions are tracked in the schema_migrations table. Migrations must not use the
+// live models in internal/models, which change over time: each one declares the structs or
+// SQL it needs as of its own version.
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one versioned change to the schema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration records an applied migration
+type SchemaMigration struct {
+	Version   int64     `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `json:"name" gorm:"type:varchar(255);not null"`
+	AppliedAt time.Time `json:"appliedAt" gorm:"not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status describes a migration known to this binary or recorded in the database
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+	Unknown   bool       `json:"unknown,omitempty"` // Applied but not known to this binary, e.g. by a newer release
+}
+
+// All returns the migrations of the application in version order
+func All() []Migration {
+	all := []Migration{
+		baseline,
+		defaultRoles,
+		tags,
+		softDelete,
+		jobRevisions,
+		auditLog,
+		versions,
+		searchIndex,
+		projectTrash,
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+// Migrator applies and rolls back migrations
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	logf       func(format string, args ...interface{})
+}
+
+// New returns a Migrator for the application's migrations.
+// logf receives a line per applied or rolled back migration and may be nil.
+func New(db *gorm.DB, logf func(format string, args ...interface{})) *Migrator {
+	if logf == nil {
+		logf = func(string, ...interface{}) {}
+	}
+	return &Migrator{
+		db:         db,
+		migrations: All(),
+		logf:       logf,
+	}
+}
+
+// ensureTable creates the schema_migrations table when missing
+func (m *Migrator) ensureTable() error {
+	if err := m.db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+	return nil
+}
+
+// applied returns the recorded migrations by version
+func (m *Migrator) applied() (map[int64]SchemaMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	var rows []SchemaMigration
+	if err := m.db.Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+
+	applied := make(map[int64]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// Latest returns the version of the newest migration known to this binary
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status lists every known migration and every applied one this binary does not know
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, Status{
+			Version:   row.Version,
+			Name:      row.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// Pending returns the known migrations that have not been applied yet
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []Migration{}
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration and returns how many were applied
+func (m *Migrator) Up() (int, error) {
+	return m.To(m.Latest())
+}
+
+// Down rolls back the given number of most recently applied migrations
+func (m *Migrator) Down(steps int) (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	rolledBack := 0
+	for i := len(m.migrations) - 1; i >= 0 && rolledBack < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if err := m.rollback(migration); err != nil {
+			return rolledBack, err
+		}
+		rolledBack++
+	}
+	return rolledBack, nil
+}
+
+// To migrates up or down so that exactly the migrations up to and including version are applied.
+// Version 0 rolls back everything.
+func (m *Migrator) To(version int64) (int, error) {
+	if version != 0 && !m.known(version) {
+		return 0, fmt.Errorf("unknown migration version %d", version)
+	}
+
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+
+	// Roll back newer migrations first, newest first
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+			continue
+		}
+		if err := m.rollback(migration); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+
+	// Then apply missing ones, oldest first
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok || migration.Version > version {
+			continue
+		}
+		if err := m.apply(migration); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+
+	return changed, nil
+}
+
+func (m *Migrator) known(version int64) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// apply runs a migration and records it in one transaction
+func (m *Migrator) apply(migration Migration) error {
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Up(tx); err != nil {
+			return err
+		}
+		return tx.Create(&SchemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %v", migration.Version, migration.Name, err)
+	}
+
+	m.logf("Applied migration %d: %s", migration.Version, migration.Name)
+	return nil
+}
+
+// rollback reverts a migration and removes its record in one transaction
+func (m *Migrator) rollback(migration Migration) error {
+	if migration.Down == nil {
+		return fmt.Errorf("migration %d (%s) cannot be rolled back", migration.Version, migration.Name)
+	}
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Down(tx); err != nil {
+			return err
+		}
+		return tx.Delete(&SchemaMigration{}, "version = ?", migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("rollback of migration %d (%s) failed: %v", migration.Version, migration.Name, err)
+	}
+
+	m.logf("Rolled back migration %d: %s", migration.Version, migration.Name)
+	return nil
+}
+
+// ErrSchemaBehind is returned by RequireCurrent when migrations are pending
+type ErrSchemaBehind struct {
+	Pending []Migration
+}
+
+func (e *ErrSchemaBehind) Error() string {
+	return fmt.Sprintf("database schema is behind: %d pending migration(s), starting with %d (%s); run the migrate command",
+		len(e.Pending), e.Pending[0].Version, e.Pending[0].Name)
+}
+
+// RequireCurrent returns an *ErrSchemaBehind when the database lacks migrations known to this binary
+func (m *Migrator) RequireCurrent() error {
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return &ErrSchemaBehind{Pending: pending}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package migrations
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"crontab/internal/config"
+	"crontab/internal/models"
+)
+
+// tables created by the migrations, besides schema_migrations
+var migratedTables = []string{
+	"projects", "jobs", "job_logs", "job_run_stats", "notification_deliveries", "notification_channels",
+	"job_channel_subscriptions", "alert_rules", "alerts", "users", "roles", "tags", "job_tags",
+	"job_revisions", "audit_entries", "search_terms",
+}
+
+// testDB opens an empty SQLite database the way the application does
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	t.Setenv("DB_DRIVER", models.DriverSQLite)
+	t.Setenv("DB_DSN", "")
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "crontab.db"))
+	db, err := models.SetupDatabase(config.New())
+	if err != nil {
+		t.Fatalf("SetupDatabase failed: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get connection pool: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestUpAppliesEveryMigration(t *testing.T) {
+	db := testDB(t)
+	migrator := New(db, t.Logf)
+
+	if err := migrator.RequireCurrent(); err == nil {
+		t.Error("RequireCurrent succeeded on an empty database")
+	}
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if applied != len(All()) {
+		t.Errorf("applied %d migrations, want %d", applied, len(All()))
+	}
+	if err := migrator.RequireCurrent(); err != nil {
+		t.Errorf("RequireCurrent after Up: %v", err)
+	}
+	for _, table := range migratedTables {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("table %s missing", table)
+		}
+	}
+
+	var roles []string
+	db.Table("roles").Order("name").Pluck("name", &roles)
+	if len(roles) != 3 || roles[0] != "admin" || roles[1] != "user" || roles[2] != "viewer" {
+		t.Errorf("roles = %v, want admin, user and viewer", roles)
+	}
+
+	// Running it again is a no-op
+	if applied, err := migrator.Up(); err != nil || applied != 0 {
+		t.Errorf("second Up applied %d migrations, err %v", applied, err)
+	}
+}
+
+func TestDownRollsBackEverything(t *testing.T) {
+	db := testDB(t)
+	migrator := New(db, t.Logf)
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	rolledBack, err := migrator.To(0)
+	if err != nil {
+		t.Fatalf("To(0) failed: %v", err)
+	}
+	if rolledBack != len(All()) {
+		t.Errorf("rolled back %d migrations, want %d", rolledBack, len(All()))
+	}
+	for _, table := range migratedTables {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("table %s left after rolling back everything", table)
+		}
+	}
+
+	// The schema can be built again from scratch
+	if applied, err := migrator.Up(); err != nil || applied != len(All()) {
+		t.Errorf("Up after To(0) applied %d migrations, err %v", applied, err)
+	}
+}
+
+func TestDownAndStatus(t *testing.T) {
+	db := testDB(t)
+	migrator := New(db, nil)
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	if rolledBack, err := migrator.Down(1); err != nil || rolledBack != 1 {
+		t.Fatalf("Down(1) rolled back %d migrations, err %v", rolledBack, err)
+	}
+	var behind *ErrSchemaBehind
+	if err := migrator.RequireCurrent(); !errors.As(err, &behind) || behind.Pending[0].Version != migrator.Latest() {
+		t.Errorf("RequireCurrent = %v, want the latest migration pending", err)
+	}
+
+	// A migration applied by a newer release is listed as unknown
+	db.Create(&SchemaMigration{Version: 999, Name: "from the future", AppliedAt: time.Now()})
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(statuses) != len(All())+1 {
+		t.Fatalf("%d statuses, want %d", len(statuses), len(All())+1)
+	}
+	for _, status := range statuses[:len(All())-1] {
+		if !status.Applied || status.AppliedAt == nil || status.Unknown {
+			t.Errorf("status of migration %d = %+v, want applied", status.Version, status)
+		}
+	}
+	if latest := statuses[len(All())-1]; latest.Applied {
+		t.Errorf("migration %d still applied after Down(1)", latest.Version)
+	}
+	if future := statuses[len(All())]; future.Version != 999 || !future.Applied || !future.Unknown {
+		t.Errorf("status of the unknown migration = %+v", future)
+	}
+}
+
+func TestToRejectsUnknownVersion(t *testing.T) {
+	if _, err := New(testDB(t), nil).To(999); err == nil {
+		t.Error("To(999) succeeded")
+	}
+}
@@ -0,0 +1,254 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AlertRuleType string
+
+const (
+	// AlertRuleConsecutiveFailures fires after Threshold failed runs in a row
+	AlertRuleConsecutiveFailures AlertRuleType = "consecutive_failures"
+	// AlertRuleSuccessRate fires when the success rate of the last Window runs drops below Threshold percent
+	AlertRuleSuccessRate AlertRuleType = "success_rate"
+	// AlertRuleSlowRun fires when a successful run takes longer than Threshold times the p95
+	// duration of the previous Window successful runs
+	AlertRuleSlowRun AlertRuleType = "slow_run"
+)
+
+const (
+	defaultSuccessRateWindow = 20
+	defaultSlowRunWindow     = 100
+	// minSlowRunSamples is how many earlier runs are needed before slow runs are detected
+	minSlowRunSamples = 10
+)
+
+type AlertStatus string
+
+const (
+	AlertStatusFiring   AlertStatus = "firing"
+	AlertStatusResolved AlertStatus = "resolved"
+)
+
+// AlertRule describes a condition on a job's recent runs that raises an alert
+type AlertRule struct {
+	ID        string        `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	JobID     string        `json:"jobId" gorm:"type:varchar(36);index;not null"`
+	Name      string        `json:"name" gorm:"type:varchar(100)"`
+	Type      AlertRuleType `json:"type" gorm:"type:varchar(30);not null"`
+	Threshold float64       `json:"threshold"`
+	Window    int           `json:"window"` // Number of runs considered, see AlertRuleType
+	Enabled   bool          `json:"enabled"`
+	CreatedAt time.Time     `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time     `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// Alert is raised when a rule's condition is met and resolved once it no longer is
+type Alert struct {
+	ID            string        `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	RuleID        string        `json:"ruleId" gorm:"type:varchar(36);index;not null"`
+	JobID         string        `json:"jobId" gorm:"type:varchar(36);index;not null"`
+	RuleType      AlertRuleType `json:"ruleType" gorm:"type:varchar(30)"`
+	Status        AlertStatus   `json:"status" gorm:"type:varchar(10);index;not null"`
+	Message       string        `json:"message" gorm:"type:varchar(255)"`
+	Value         float64       `json:"value"` // Observed value when the alert fired
+	FiredLogID    string        `json:"firedLogId" gorm:"type:varchar(36)"`
+	ResolvedLogID string        `json:"resolvedLogId,omitempty" gorm:"type:varchar(36)"`
+	FiredAt       time.Time     `json:"firedAt"`
+	ResolvedAt    *time.Time    `json:"resolvedAt,omitempty"`
+	UpdatedAt     time.Time     `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (r *AlertRule) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == "" {
+		r.ID = generateUUID()
+	}
+	return
+}
+
+func (a *Alert) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == "" {
+		a.ID = generateUUID()
+	}
+	return
+}
+
+// Validate checks the rule and fills in the default window
+func (r *AlertRule) Validate() error {
+	switch r.Type {
+	case AlertRuleConsecutiveFailures:
+		if r.Threshold < 1 || r.Threshold != float64(int(r.Threshold)) {
+			return fmt.Errorf("threshold must be a whole number of failures of at least 1")
+		}
+	case AlertRuleSuccessRate:
+		if r.Threshold <= 0 || r.Threshold > 100 {
+			return fmt.Errorf("threshold must be a percentage between 0 and 100")
+		}
+		if r.Window == 0 {
+			r.Window = defaultSuccessRateWindow
+		}
+	case AlertRuleSlowRun:
+		if r.Threshold < 1 {
+			return fmt.Errorf("threshold must be a multiplier of the p95 duration of at least 1")
+		}
+		if r.Window == 0 {
+			r.Window = defaultSlowRunWindow
+		}
+		if r.Window < minSlowRunSamples {
+			return fmt.Errorf("window must be at least %d runs", minSlowRunSamples)
+		}
+	default:
+		return fmt.Errorf("unknown rule type %q", r.Type)
+	}
+
+	if r.Window < 0 {
+		return fmt.Errorf("window must be zero or positive")
+	}
+	return nil
+}
+
+// alertCheck is the outcome of evaluating a rule against a run
+type alertCheck struct {
+	firing  bool
+	value   float64
+	message string
+}
+
+// check evaluates the rule after the given run. ok is false when there is not enough data to decide.
+func (r AlertRule) check(db *gorm.DB, jobLog JobLog) (result alertCheck, ok bool, err error) {
+	switch r.Type {
+	case AlertRuleConsecutiveFailures:
+		samples, err := recentRuns(db, jobLog, int(r.Threshold), false)
+		if err != nil {
+			return result, false, err
+		}
+		streak := 0
+		for _, s := range samples {
+			if s.Status == JobStatusSuccess {
+				break
+			}
+			streak++
+		}
+		result.value = float64(streak)
+		result.firing = streak >= int(r.Threshold)
+		result.message = fmt.Sprintf("%d consecutive failures", streak)
+		return result, true, nil
+
+	case AlertRuleSuccessRate:
+		samples, err := recentRuns(db, jobLog, r.Window, false)
+		if err != nil || len(samples) < r.Window {
+			return result, false, err
+		}
+		rate := summarise(samples).successRate()
+		result.value = rate
+		result.firing = rate < r.Threshold
+		result.message = fmt.Sprintf("Success rate %.2f%% over the last %d runs is below %.2f%%", rate, len(samples), r.Threshold)
+		return result, true, nil
+
+	case AlertRuleSlowRun:
+		if jobLog.Status != JobStatusSuccess {
+			return result, false, nil
+		}
+		samples, err := recentRuns(db, jobLog, r.Window, true)
+		if err != nil || len(samples) < minSlowRunSamples {
+			return result, false, err
+		}
+		limit := summarise(samples).percentile(95) * r.Threshold
+		result.value = round2(jobLog.Duration)
+		result.firing = jobLog.Duration > limit
+		result.message = fmt.Sprintf("Run took %.2fs, more than %.2f×p95 (%.2fs)", jobLog.Duration, r.Threshold, limit)
+		return result, true, nil
+	}
+
+	return result, false, nil
+}
+
+// recentRuns returns up to limit finished runs of the job, newest first. The given run is
+// included unless onlyEarlier is set, in which case only earlier successful runs are returned.
+func recentRuns(db *gorm.DB, jobLog JobLog, limit int, onlyEarlier bool) ([]runSample, error) {
+	var samples []runSample
+	query := db.Model(&JobLog{}).
+		Select("job_id, status, start_time, duration").
+		Where("job_id = ?", jobLog.JobID)
+	if onlyEarlier {
+		query = query.Where("id <> ? AND start_time <= ? AND status = ?", jobLog.ID, jobLog.StartTime, JobStatusSuccess)
+	} else {
+		query = query.Where("start_time <= ? AND status IN ?", jobLog.StartTime, FinishedStatuses)
+	}
+	err := query.Order("start_time DESC").Limit(limit).Scan(&samples).Error
+	return samples, err
+}
+
+// EvaluateAlertRules checks the enabled rules of a job after a run. New alerts are stored as firing and
+// open alerts whose condition cleared are resolved; both are returned so they can be notified.
+func EvaluateAlertRules(db *gorm.DB, jobLog JobLog) ([]Alert, error) {
+	var rules []AlertRule
+	if err := db.Where("job_id = ? AND enabled = ?", jobLog.JobID, true).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	changed := []Alert{}
+	for _, rule := range rules {
+		result, ok, err := rule.check(db, jobLog)
+		if err != nil {
+			return changed, fmt.Errorf("failed to evaluate alert rule %s: %v", rule.ID, err)
+		}
+		if !ok {
+			continue
+		}
+
+		var open Alert
+		openErr := db.Where("rule_id = ? AND status = ?", rule.ID, AlertStatusFiring).First(&open).Error
+		hasOpen := openErr == nil
+
+		switch {
+		case result.firing && !hasOpen:
+			alert := Alert{
+				RuleID:     rule.ID,
+				JobID:      rule.JobID,
+				RuleType:   rule.Type,
+				Status:     AlertStatusFiring,
+				Message:    result.message,
+				Value:      result.value,
+				FiredLogID: jobLog.ID,
+				FiredAt:    jobLog.EndTime,
+			}
+			if err := db.Create(&alert).Error; err != nil {
+				return changed, fmt.Errorf("failed to store alert: %v", err)
+			}
+			changed = append(changed, alert)
+
+		case !result.firing && hasOpen:
+			resolvedAt := jobLog.EndTime
+			open.Status = AlertStatusResolved
+			open.ResolvedLogID = jobLog.ID
+			open.ResolvedAt = &resolvedAt
+			if err := db.Save(&open).Error; err != nil {
+				return changed, fmt.Errorf("failed to resolve alert: %v", err)
+			}
+			changed = append(changed, open)
+		}
+	}
+
+	return changed, nil
+}
+
+// HasAlertRules reports whether a job has enabled alert rules
+func HasAlertRules(db *gorm.DB, jobID string) bool {
+	var count int64
+	db.Model(&AlertRule{}).Where("job_id = ? AND enabled = ?", jobID, true).Count(&count)
+	return count > 0
+}
+
+// ResolveRuleAlerts resolves the open alerts of a rule without notifying, e.g. when it is deleted
+func ResolveRuleAlerts(db *gorm.DB, ruleID string) error {
+	return db.Model(&Alert{}).
+		Where("rule_id = ? AND status = ?", ruleID, AlertStatusFiring).
+		Updates(map[string]interface{}{
+			"status":      AlertStatusResolved,
+			"resolved_at": time.Now(),
+		}).Error
+}
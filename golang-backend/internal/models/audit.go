@@ -0,0 +1,153 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditAction names what an audited request did
+type AuditAction string
+
+const (
+	AuditLogin            AuditAction = "auth.login"
+	AuditLoginFailed      AuditAction = "auth.login_failed"
+	AuditRegister         AuditAction = "auth.register"
+	AuditJobCreate        AuditAction = "job.create"
+	AuditJobUpdate        AuditAction = "job.update"
+	AuditJobDelete        AuditAction = "job.delete"
+	AuditJobRestore       AuditAction = "job.restore"
+	AuditJobPause         AuditAction = "job.pause"
+	AuditJobResume        AuditAction = "job.resume"
+	AuditJobRun           AuditAction = "job.run"
+	AuditJobRollback      AuditAction = "job.rollback"
+	AuditJobSubscribe     AuditAction = "job.subscriptions"
+	AuditJobWebhookSecret AuditAction = "job.webhook_secret"
+	AuditProjectCreate    AuditAction = "project.create"
+	AuditProjectUpdate    AuditAction = "project.update"
+	AuditProjectDelete    AuditAction = "project.delete"
+	AuditProjectRestore   AuditAction = "project.restore"
+	AuditTagCreate        AuditAction = "tag.create"
+	AuditTagUpdate        AuditAction = "tag.update"
+	AuditTagDelete        AuditAction = "tag.delete"
+	AuditChannelCreate    AuditAction = "channel.create"
+	AuditChannelUpdate    AuditAction = "channel.update"
+	AuditChannelDelete    AuditAction = "channel.delete"
+	AuditAlertRuleCreate  AuditAction = "alert_rule.create"
+	AuditAlertRuleUpdate  AuditAction = "alert_rule.update"
+	AuditAlertRuleDelete  AuditAction = "alert_rule.delete"
+)
+
+// ErrAuditImmutable is returned when changing or deleting an audit entry
+var ErrAuditImmutable = errors.New("audit entries are append-only")
+
+// AuditEntry records an action of a user. Entries are never changed or deleted.
+type AuditEntry struct {
+	ID           string          `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	ActorID      string          `json:"actorId,omitempty" gorm:"type:varchar(36);index"`
+	Actor        string          `json:"actor" gorm:"type:varchar(100);index"` // User email, or "api" when unauthenticated
+	Action       AuditAction     `json:"action" gorm:"type:varchar(50);index;not null"`
+	ResourceType string          `json:"resourceType" gorm:"type:varchar(30);index:idx_audit_entries_resource"`
+	ResourceID   string          `json:"resourceId,omitempty" gorm:"type:varchar(36);index:idx_audit_entries_resource"`
+	Before       json.RawMessage `json:"before,omitempty" gorm:"-"` // Resource before the action, stored as JSON
+	BeforeJSON   string          `json:"-" gorm:"column:before;type:text"`
+	After        json.RawMessage `json:"after,omitempty" gorm:"-"` // Resource after the action, stored as JSON
+	AfterJSON    string          `json:"-" gorm:"column:after;type:text"`
+	IP           string          `json:"ip" gorm:"column:ip;type:varchar(45)"`
+	UserAgent    string          `json:"userAgent" gorm:"type:varchar(255)"`
+	CreatedAt    time.Time       `json:"createdAt" gorm:"autoCreateTime;index"`
+}
+
+func (a *AuditEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == "" {
+		a.ID = generateUUID()
+	}
+	a.BeforeJSON = string(a.Before)
+	a.AfterJSON = string(a.After)
+	if len(a.UserAgent) > 255 {
+		a.UserAgent = a.UserAgent[:255]
+	}
+	return
+}
+
+// BeforeUpdate keeps the audit log append-only
+func (a *AuditEntry) BeforeUpdate(tx *gorm.DB) (err error) {
+	return ErrAuditImmutable
+}
+
+// BeforeDelete keeps the audit log append-only
+func (a *AuditEntry) BeforeDelete(tx *gorm.DB) (err error) {
+	return ErrAuditImmutable
+}
+
+// AfterFind restores the snapshots
+func (a *AuditEntry) AfterFind(tx *gorm.DB) (err error) {
+	if a.BeforeJSON != "" {
+		a.Before = json.RawMessage(a.BeforeJSON)
+	}
+	if a.AfterJSON != "" {
+		a.After = json.RawMessage(a.AfterJSON)
+	}
+	return
+}
+
+// AuditSnapshot encodes a resource for the Before and After fields of an entry; nil stays empty
+//
+// Secrets must not be part of the JSON of resource: webhook secrets of jobs are tagged json:"-",
+// and channels are snapshotted without theirs.
+func AuditSnapshot(resource interface{}) json.RawMessage {
+	if resource == nil {
+		return nil
+	}
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// AuditFilter selects audit entries
+type AuditFilter struct {
+	Actor        string // Email or user ID
+	Action       string
+	ResourceType string
+	ResourceID   string
+	From         time.Time
+	To           time.Time
+}
+
+// Apply restricts a query on audit entries to the filter, newest first
+func (f AuditFilter) Apply(db *gorm.DB) *gorm.DB {
+	query := db.Model(&AuditEntry{}).Order("created_at DESC")
+	if f.Actor != "" {
+		query = query.Where("actor = ? OR actor_id = ?", f.Actor, f.Actor)
+	}
+	if f.Action != "" {
+		query = query.Where("action = ?", f.Action)
+	}
+	if f.ResourceType != "" {
+		query = query.Where("resource_type = ?", f.ResourceType)
+	}
+	if f.ResourceID != "" {
+		query = query.Where("resource_id = ?", f.ResourceID)
+	}
+	if !f.From.IsZero() {
+		query = query.Where("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		query = query.Where("created_at < ?", f.To)
+	}
+	return query
+}
+
+// Matches reports whether an entry is selected by the filter, for stores that cannot Apply it
+func (f AuditFilter) Matches(entry *AuditEntry) bool {
+	return (f.Actor == "" || entry.Actor == f.Actor || entry.ActorID == f.Actor) &&
+		(f.Action == "" || string(entry.Action) == f.Action) &&
+		(f.ResourceType == "" || entry.ResourceType == f.ResourceType) &&
+		(f.ResourceID == "" || entry.ResourceID == f.ResourceID) &&
+		(f.From.IsZero() || !entry.CreatedAt.Before(f.From)) &&
+		(f.To.IsZero() || entry.CreatedAt.Before(f.To))
+}
@@ -4,23 +4,36 @@ package models
 import (
 	"fmt"
 	"log"
+	"net"
+	"net/url"
+	"strings"
 
 	"crontab/internal/config"
 	
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/driver/sqlserver"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-// SetupDatabase connects to the database and returns a DB instance
+// Supported values of the DB_DRIVER setting
+const (
+	DriverSQLServer = "sqlserver"
+	DriverPostgres  = "postgres"
+	DriverMySQL     = "mysql"
+	DriverSQLite    = "sqlite"
+)
+
+// SetupDatabase connects to the database selected by DB_DRIVER and returns a DB instance
 func SetupDatabase(cfg *config.Config) (*gorm.DB, error) {
-	// Build connection string
-	dsn := fmt.Sprintf("sqlserver://%s:%s@%s:%s?database=%s",
-		cfg.GetString("DB_USERNAME", "sa"),
-		cfg.GetString("DB_PASSWORD", "YourStrong@Passw0rd"),
-		cfg.GetString("DB_HOST", "localhost"),
-		cfg.GetString("DB_PORT", "1433"),
-		cfg.GetString("DB_NAME", "crontab"))
+	driver := strings.ToLower(cfg.GetString("DB_DRIVER", DriverSQLServer))
+	
+	dialector, err := openDialector(driver, cfg)
+	if err != nil {
+		return nil, err
+	}
 	
 	// Set logging level
 	logLevel := logger.Silent
@@ -29,82 +42,75 @@ func SetupDatabase(cfg *config.Config) (*gorm.DB, error) {
 	}
 	
 	// Open database connection
-	db, err := gorm.Open(sqlserver.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logLevel),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
 	
-	log.Println("Connected to database successfully")
-	return db, nil
-}
-
-// MigrateDatabase runs migrations for all models
-func MigrateDatabase(db *gorm.DB) error {
-	log.Println("Running database migrations")
-	
-	err := db.AutoMigrate(
-		&Project{},
-		&Job{},
-		&JobLog{},
-		&User{},
-		&Role{},
-	)
-	
-	if err != nil {
-		return fmt.Errorf("failed to migrate database: %v", err)
+	if driver == DriverSQLite {
+		// SQLite allows a single writer; serialise access instead of failing with "database is locked"
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure database: %v", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
 	}
 	
-	log.Println("Database migrations completed successfully")
-	
-	// Create default roles if they don't exist
-	return seedDefaultRoles(db)
+	log.Printf("Connected to %s database successfully", driver)
+	return db, nil
 }
 
-// seedDefaultRoles creates default roles if they don't exist
-func seedDefaultRoles(db *gorm.DB) error {
-	roles := []struct {
-		Name        string
-		Permissions []string
-	}{
-		{
-			Name:        "admin",
-			Permissions: []string{"view", "create", "update", "delete", "manage_users"},
-		},
-		{
-			Name:        "user",
-			Permissions: []string{"view", "create", "update"},
-		},
-		{
-			Name:        "viewer",
-			Permissions: []string{"view"},
-		},
-	}
+// openDialector returns the gorm dialector of a driver. DB_DSN is used as is when set,
+// otherwise the DSN is built from DB_HOST, DB_PORT, DB_USERNAME, DB_PASSWORD and DB_NAME
+// (DB_PATH for SQLite).
+func openDialector(driver string, cfg *config.Config) (gorm.Dialector, error) {
+	dsn := cfg.GetString("DB_DSN", "")
+	host := cfg.GetString("DB_HOST", "localhost")
+	username := cfg.GetString("DB_USERNAME", "")
+	password := cfg.GetString("DB_PASSWORD", "")
+	name := cfg.GetString("DB_NAME", "crontab")
 	
-	for _, r := range roles {
-		var count int64
-		db.Model(&Role{}).Where("name = ?", r.Name).Count(&count)
+	switch driver {
+	case DriverSQLServer:
+		if dsn == "" {
+			dsn = (&url.URL{
+				Scheme:   "sqlserver",
+				User:     url.UserPassword(username, password),
+				Host:     net.JoinHostPort(host, cfg.GetString("DB_PORT", "1433")),
+				RawQuery: url.Values{"database": {name}}.Encode(),
+			}).String()
+		}
+		return sqlserver.Open(dsn), nil
+		
+	case DriverPostgres:
+		if dsn == "" {
+			dsn = (&url.URL{
+				Scheme:   "postgres",
+				User:     url.UserPassword(username, password),
+				Host:     net.JoinHostPort(host, cfg.GetString("DB_PORT", "5432")),
+				Path:     "/" + name,
+				RawQuery: url.Values{"sslmode": {cfg.GetString("DB_SSLMODE", "disable")}}.Encode(),
+			}).String()
+		}
+		return postgres.Open(dsn), nil
+		
+	case DriverMySQL:
+		if dsn == "" {
+			// parseTime is required to scan DATETIME columns into time.Time
+			dsn = fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC",
+				username, password, net.JoinHostPort(host, cfg.GetString("DB_PORT", "3306")), name)
+		}
+		return mysql.Open(dsn), nil
 		
-		if count == 0 {
-			permissionsJSON, err := MarshalPermissions(r.Permissions)
-			if err != nil {
-				return fmt.Errorf("failed to marshal permissions: %v", err)
-			}
-			
-			role := Role{
-				Name: r.Name,
-				Permissions: r.Permissions,
-				PermissionsJSON: permissionsJSON,
-			}
-			
-			if err := db.Create(&role).Error; err != nil {
-				return fmt.Errorf("failed to create role %s: %v", r.Name, err)
-			}
-			
-			log.Printf("Created default role: %s", r.Name)
+	case DriverSQLite:
+		if dsn == "" {
+			dsn = cfg.GetString("DB_PATH", "crontab.db") + "?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on"
 		}
+		return sqlite.Open(dsn), nil
 	}
 	
-	return nil
+	return nil, fmt.Errorf("unsupported DB_DRIVER %q, expected one of %s, %s, %s or %s",
+		driver, DriverSQLServer, DriverPostgres, DriverMySQL, DriverSQLite)
 }
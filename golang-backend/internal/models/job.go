@@ -2,9 +2,19 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"path/filepath"
+	"strings"
 	"time"
+	"unicode"
 
+	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
+
+	"crontab/internal/config"
 )
 
 type JobStatus string
@@ -15,18 +25,67 @@ const (
 	JobStatusSuccess JobStatus = "success"
 	JobStatusFailed  JobStatus = "failed"
 	JobStatusPaused  JobStatus = "paused"
+	JobStatusMissed  JobStatus = "missed" // A heartbeat job did not ping in time
+)
+
+// FinishedStatuses are the statuses of runs that have completed
+var FinishedStatuses = []JobStatus{JobStatusSuccess, JobStatusFailed, JobStatusMissed}
+
+type JobType string
+
+const (
+	JobTypeShell JobType = "shell"
+	JobTypeHTTP  JobType = "http"
+	JobTypeHeartbeat JobType = "heartbeat" // Runs elsewhere and reports through its ping URL
+)
+
+// scheduleParser accepts the schedules the scheduler runs: cron expressions with a seconds field, or descriptors such as @hourly
+// AllowedRunAsUsers and AllowedRunAsGroups are the accounts jobs may run as, configured with
+// RUN_AS_ALLOWED_USERS and RUN_AS_ALLOWED_GROUPS. Jobs cannot switch accounts while they are empty.
+var (
+	AllowedRunAsUsers  []string
+	AllowedRunAsGroups []string
 )
 
+// ConfigureRunAs reads the accounts jobs may run as from comma-separated lists in the configuration
+func ConfigureRunAs(cfg *config.Config) {
+	AllowedRunAsUsers = splitList(cfg.GetString("RUN_AS_ALLOWED_USERS", ""))
+	AllowedRunAsGroups = splitList(cfg.GetString("RUN_AS_ALLOWED_GROUPS", ""))
+}
+
+// splitList splits a comma-separated list, dropping blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+var scheduleParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// DefaultHeartbeatGrace is how long after its scheduled time a heartbeat job may ping when no grace period is set
+const DefaultHeartbeatGrace = 5 * time.Minute
+
 type Job struct {
 	ID            string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
 	Name          string    `json:"name" gorm:"type:varchar(100);not null"`
+	Type          JobType   `json:"type" gorm:"type:varchar(10);default:'shell'"`
 	Command       string    `json:"command" gorm:"type:varchar(500);not null"`
+	Endpoint      string    `json:"endpoint" gorm:"type:varchar(500)"`
+	HTTPMethod    string    `json:"httpMethod" gorm:"column:http_method;type:varchar(10)"`
+	RequestBody   string    `json:"requestBody" gorm:"type:text"`
+	Headers       map[string]string `json:"headers" gorm:"-"` // Stored as JSON in the database
+	HeadersJSON   string    `json:"-" gorm:"column:headers;type:text"`
 	Schedule      string    `json:"schedule" gorm:"type:varchar(100);not null"`
 	Description   string    `json:"description" gorm:"type:varchar(500)"`
 	Status        JobStatus `json:"status" gorm:"type:varchar(10);default:'idle'"`
 	LastRun       time.Time `json:"lastRun" gorm:"default:null"`
 	NextRun       time.Time `json:"nextRun" gorm:"default:null"`
-	Tags          string    `json:"tags" gorm:"type:varchar(255)"`
+	Tags          []string  `json:"tags" gorm:"-"` // Names of TagRefs, filled when they are preloaded
+	TagRefs       []Tag     `json:"tagDetails,omitempty" gorm:"many2many:job_tags"`
 	FailCount     int       `json:"failCount" gorm:"default:0"`
 	SuccessCount  int       `json:"successCount" gorm:"default:0"`
 	ProjectID     string    `json:"projectId" gorm:"type:varchar(36);not null"`
@@ -34,8 +93,113 @@ type Job struct {
 	UpdatedAt     time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
 	Timezone      string    `json:"timezone" gorm:"type:varchar(50);default:'UTC'"`
 	UseLocalTime  bool      `json:"useLocalTime" gorm:"default:false"`
+	WorkingDir    string    `json:"workingDir" gorm:"type:varchar(255)"`
+	RunAsUser     string    `json:"runAsUser" gorm:"type:varchar(64)"`
+	RunAsGroup    string    `json:"runAsGroup" gorm:"type:varchar(64)"`
+	CPULimit      int       `json:"cpuLimit" gorm:"default:0"`     // RLIMIT_CPU in seconds, 0 = unlimited
+	MemoryLimit   int       `json:"memoryLimit" gorm:"default:0"`  // RLIMIT_AS in megabytes, 0 = unlimited
+	MaxOpenFiles  int       `json:"maxOpenFiles" gorm:"default:0"` // RLIMIT_NOFILE, 0 = unlimited
+	MaxProcesses  int       `json:"maxProcesses" gorm:"default:0"` // RLIMIT_NPROC, 0 = unlimited
+	EmailNotifications     *EmailNotificationSettings `json:"emailNotifications" gorm:"-"` // Stored as JSON in the database
+	EmailNotificationsJSON string    `json:"-" gorm:"column:email_notifications;type:text"`
+	Webhooks      []WebhookSettings `json:"webhooks" gorm:"-"` // Stored as JSON in the database
+	WebhooksJSON  string    `json:"-" gorm:"column:webhooks;type:text"`
 	Logs          []JobLog  `json:"logs,omitempty" gorm:"foreignKey:JobID"`
-	AverageRuntime float64   `json:"averageRuntime" gorm:"-"` // Calculated field
+	AverageRuntime float64   `json:"averageRuntime" gorm:"default:0"` // Average duration of successful runs in seconds
+	AutoPauseAfterFailures int  `json:"autoPauseAfterFailures" gorm:"default:0"` // Pause after this many failures in a row, 0 = never
+	ConsecutiveFailures    int  `json:"consecutiveFailures" gorm:"default:0"`
+	PausedReason  string    `json:"pausedReason,omitempty" gorm:"type:varchar(255)"`
+	PausedBy      string    `json:"pausedBy,omitempty" gorm:"type:varchar(100)"` // User email, or "scheduler" for automatic pauses
+	PausedAt      *time.Time `json:"pausedAt,omitempty"`
+	PingToken     string    `json:"pingToken,omitempty" gorm:"type:varchar(64);index"` // Heartbeat jobs: secret part of the ping URL
+	GracePeriod   int       `json:"gracePeriod" gorm:"default:0"` // Heartbeat jobs: seconds a ping may arrive around its scheduled time, 0 = DefaultHeartbeatGrace
+	ExpectedDuration int    `json:"expectedDuration" gorm:"default:0"` // Seconds after which a run still going is reported as slow, 0 = no SLA
+	DeletedAt     gorm.DeletedAt `json:"deletedAt,omitempty" gorm:"index"` // Set while the job is in the trash
+	DeletedWithProject string   `json:"-" gorm:"type:varchar(36);index"` // Project whose deletion moved the job to the trash
+	RevisionID    string    `json:"revisionId,omitempty" gorm:"type:varchar(36)"` // Current JobRevision
+	Version       int64     `json:"version" gorm:"not null;default:1"` // Incremented on every edit, exposed as the ETag
+	pauseChanged  bool      // Set by Pause and Resume until the job is saved
+}
+
+// jobEditableColumns are the columns of the configuration of a job, see JobSpec
+var jobEditableColumns = []string{
+	"name", "type", "command", "endpoint", "http_method", "request_body", "headers", "schedule", "description",
+	"timezone", "use_local_time", "working_dir", "run_as_user", "run_as_group",
+	"cpu_limit", "memory_limit", "max_open_files", "max_processes", "email_notifications", "webhooks",
+	"auto_pause_after_failures", "ping_token", "grace_period", "expected_duration",
+}
+
+// EditableColumns returns the columns an edit of the job stores. Its status and counters are
+// maintained by runs and only stored with an edit that pauses or resumes the job.
+func (j *Job) EditableColumns() []string {
+	columns := append([]string{}, jobEditableColumns...)
+	if j.pauseChanged {
+		columns = append(columns, "status", "paused_reason", "paused_by", "paused_at")
+		if j.Status != JobStatusPaused {
+			columns = append(columns, "consecutive_failures")
+		}
+	}
+	return columns
+}
+
+// KeepServerState copies into j the fields of stored that an edit does not store, see EditableColumns
+func (j *Job) KeepServerState(stored *Job) {
+	j.ProjectID = stored.ProjectID
+	j.LastRun, j.NextRun = stored.LastRun, stored.NextRun
+	j.SuccessCount, j.FailCount = stored.SuccessCount, stored.FailCount
+	j.AverageRuntime = stored.AverageRuntime
+	j.CreatedAt = stored.CreatedAt
+	j.RevisionID = stored.RevisionID
+	if !j.pauseChanged {
+		j.Status = stored.Status
+		j.PausedReason, j.PausedBy, j.PausedAt = stored.PausedReason, stored.PausedBy, stored.PausedAt
+	}
+	if !j.pauseChanged || j.Status == JobStatusPaused {
+		j.ConsecutiveFailures = stored.ConsecutiveFailures
+	}
+}
+
+// HeartbeatGrace returns how long around its scheduled time a heartbeat job may ping
+func (j *Job) HeartbeatGrace() time.Duration {
+	if j.GracePeriod > 0 {
+		return time.Duration(j.GracePeriod) * time.Second
+	}
+	return DefaultHeartbeatGrace
+}
+
+// HeartbeatDeadline returns when a run of a heartbeat job that started at start has to be completed:
+// the grace period after the next scheduled time. Completion pings arriving later belong to another run.
+func (j *Job) HeartbeatDeadline(start time.Time) time.Time {
+	grace := j.HeartbeatGrace()
+	schedule, err := scheduleParser.Parse(j.Schedule)
+	if err != nil {
+		return start.Add(grace)
+	}
+	// A start ping may arrive up to the grace period before its own scheduled time
+	return schedule.Next(start.Add(grace)).Add(grace)
+}
+
+// PausedByScheduler is recorded as PausedBy when a job is paused automatically
+const PausedByScheduler = "scheduler"
+
+// Pause marks the job as paused and records why and by whom
+func (j *Job) Pause(reason, by string) {
+	now := time.Now()
+	j.Status = JobStatusPaused
+	j.PausedReason = reason
+	j.PausedBy = by
+	j.PausedAt = &now
+	j.pauseChanged = true
+}
+
+// Resume makes a paused job idle again, clearing the pause details and the failure counter
+func (j *Job) Resume() {
+	j.Status = JobStatusIdle
+	j.PausedReason = ""
+	j.PausedBy = ""
+	j.PausedAt = nil
+	j.ConsecutiveFailures = 0
+	j.pauseChanged = true
 }
 
 func (j *Job) BeforeCreate(tx *gorm.DB) (err error) {
@@ -45,5 +209,151 @@ func (j *Job) BeforeCreate(tx *gorm.DB) (err error) {
 	if j.Status == "" {
 		j.Status = JobStatusIdle
 	}
+	if j.Type == "" {
+		j.Type = JobTypeShell
+	}
+	return
+}
+
+// BeforeSave encodes the JSON columns of a job
+func (j *Job) BeforeSave(tx *gorm.DB) (err error) {
+	if j.HeadersJSON, err = encodeJSONColumn(j.Headers, j.Headers == nil); err != nil {
+		return err
+	}
+	if j.EmailNotificationsJSON, err = encodeJSONColumn(j.EmailNotifications, j.EmailNotifications == nil); err != nil {
+		return err
+	}
+	// Generate a signing secret for webhooks that were created without one
+	for i := range j.Webhooks {
+		if j.Webhooks[i].Secret == "" {
+			j.Webhooks[i].Secret = generateSecret()
+		}
+	}
+	stored := make([]storedWebhook, len(j.Webhooks))
+	for i, hook := range j.Webhooks {
+		stored[i] = storedWebhook{WebhookSettings: hook, Secret: hook.Secret}
+	}
+	if j.WebhooksJSON, err = encodeJSONColumn(stored, len(stored) == 0); err != nil {
+		return err
+	}
+	// Heartbeat jobs get their ping token when they are saved for the first time
+	if j.Type == JobTypeHeartbeat && j.PingToken == "" {
+		j.PingToken = generateSecret()
+	}
 	return
 }
+
+// AfterSave forgets that the job was paused or resumed once that is stored
+func (j *Job) AfterSave(tx *gorm.DB) (err error) {
+	j.pauseChanged = false
+	return
+}
+
+// AfterFind decodes the JSON columns of a job
+func (j *Job) AfterFind(tx *gorm.DB) (err error) {
+	if j.TagRefs != nil {
+		j.Tags = make([]string, 0, len(j.TagRefs))
+		for _, tag := range j.TagRefs {
+			j.Tags = append(j.Tags, tag.Name)
+		}
+	}
+	if j.HeadersJSON != "" {
+		if err := json.Unmarshal([]byte(j.HeadersJSON), &j.Headers); err != nil {
+			return err
+		}
+	}
+	if j.EmailNotificationsJSON != "" {
+		j.EmailNotifications = &EmailNotificationSettings{}
+		if err := json.Unmarshal([]byte(j.EmailNotificationsJSON), j.EmailNotifications); err != nil {
+			return err
+		}
+	}
+	if j.WebhooksJSON != "" {
+		var stored []storedWebhook
+		if err := json.Unmarshal([]byte(j.WebhooksJSON), &stored); err != nil {
+			return err
+		}
+		j.Webhooks = make([]WebhookSettings, len(stored))
+		for i, hook := range stored {
+			j.Webhooks[i] = hook.WebhookSettings
+			j.Webhooks[i].Secret = hook.Secret
+		}
+	}
+	return
+}
+
+// Validate checks the execution settings of a job
+func (j *Job) Validate() error {
+	if strings.TrimSpace(j.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(j.Name) > 100 {
+		return fmt.Errorf("name must be at most 100 characters")
+	}
+	if strings.IndexFunc(j.Name, unicode.IsControl) >= 0 {
+		return fmt.Errorf("name must not contain control characters")
+	}
+	if j.Schedule == "" {
+		return fmt.Errorf("schedule is required")
+	}
+	if _, err := scheduleParser.Parse(j.Schedule); err != nil {
+		return fmt.Errorf("invalid schedule %q: %v", j.Schedule, err)
+	}
+	switch j.Type {
+	case "", JobTypeShell:
+		if j.Command == "" {
+			return fmt.Errorf("command is required for shell jobs")
+		}
+	case JobTypeHeartbeat:
+		if j.GracePeriod < 0 {
+			return fmt.Errorf("gracePeriod must not be negative")
+		}
+	case JobTypeHTTP:
+		u, err := url.Parse(j.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("endpoint must be an absolute http(s) URL")
+		}
+	default:
+		return fmt.Errorf("unknown job type %q", j.Type)
+	}
+	if j.CPULimit < 0 || j.MemoryLimit < 0 || j.MaxOpenFiles < 0 || j.MaxProcesses < 0 {
+		return fmt.Errorf("resource limits must not be negative")
+	}
+	if j.ExpectedDuration < 0 {
+		return fmt.Errorf("expectedDuration must not be negative")
+	}
+	if j.AutoPauseAfterFailures < 0 {
+		return fmt.Errorf("autoPauseAfterFailures must not be negative")
+	}
+	if j.WorkingDir != "" && !filepath.IsAbs(j.WorkingDir) {
+		return fmt.Errorf("working directory must be an absolute path")
+	}
+	if err := j.CheckRunAs(); err != nil {
+		return err
+	}
+	for i := range j.Webhooks {
+		u, err := url.Parse(j.Webhooks[i].URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook URL must be an absolute http(s) URL")
+		}
+	}
+	if j.EmailNotifications != nil {
+		for _, recipient := range j.EmailNotifications.Recipients {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				return fmt.Errorf("invalid email recipient %q", recipient)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckRunAs reports an error when the job runs as a user or group outside the configured allowlists
+func (j *Job) CheckRunAs() error {
+	if j.RunAsUser != "" && !containsString(AllowedRunAsUsers, j.RunAsUser) {
+		return fmt.Errorf("runAsUser %q is not allowed, see RUN_AS_ALLOWED_USERS", j.RunAsUser)
+	}
+	if j.RunAsGroup != "" && !containsString(AllowedRunAsGroups, j.RunAsGroup) {
+		return fmt.Errorf("runAsGroup %q is not allowed, see RUN_AS_ALLOWED_GROUPS", j.RunAsGroup)
+	}
+	return nil
+}
@@ -7,6 +7,14 @@ import (
 	"gorm.io/gorm"
 )
 
+type RunTrigger string
+
+const (
+	TriggerSchedule RunTrigger = "schedule" // Started by the cron scheduler
+	TriggerManual   RunTrigger = "manual"   // Started through the API
+	TriggerExternal RunTrigger = "external" // Reported by an external system
+)
+
 type JobLog struct {
 	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
 	JobID     string    `json:"jobId" gorm:"type:varchar(36);not null"`
@@ -16,6 +24,11 @@ type JobLog struct {
 	Duration  float64   `json:"duration"` // in seconds
 	Output    string    `json:"output" gorm:"type:text"`
 	Error     string    `json:"error" gorm:"type:text"`
+	Violation string    `json:"violation,omitempty" gorm:"type:varchar(255)"` // Resource limit that killed the run
+	Trigger   RunTrigger `json:"trigger" gorm:"type:varchar(20);default:'schedule'"`
+	TraceID   string    `json:"traceId,omitempty" gorm:"type:varchar(32)"` // OpenTelemetry trace of the run
+	SLABreached bool    `json:"slaBreached" gorm:"default:false"` // Run exceeded the job's expected duration
+	RevisionID string   `json:"revisionId,omitempty" gorm:"type:varchar(36);index"` // Revision of the job configuration that ran
 	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
 }
 
@@ -23,5 +36,9 @@ func (l *JobLog) BeforeCreate(tx *gorm.DB) (err error) {
 	if l.ID == "" {
 		l.ID = generateUUID()
 	}
+	// Link the run to the configuration it ran with
+	if l.RevisionID == "" && l.JobID != "" {
+		l.RevisionID, err = currentRevisionID(tx.Session(&gorm.Session{NewDB: true}), l.JobID)
+	}
 	return
 }
@@ -0,0 +1,275 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RevisionAuthorSystem is recorded as the author of revisions the server creates on its own
+const RevisionAuthorSystem = "system"
+
+// ErrRevisionImmutable is returned when saving changes to an existing revision
+var ErrRevisionImmutable = errors.New("job revisions are immutable")
+
+// JobSpec is the user-defined configuration of a job, as recorded in its revisions.
+// Runtime state such as the status, counters and run times is not part of it.
+type JobSpec struct {
+	Name                   string                     `json:"name"`
+	Type                   JobType                    `json:"type"`
+	Command                string                     `json:"command"`
+	Endpoint               string                     `json:"endpoint"`
+	HTTPMethod             string                     `json:"httpMethod"`
+	RequestBody            string                     `json:"requestBody"`
+	Headers                map[string]string          `json:"headers"`
+	Schedule               string                     `json:"schedule"`
+	Description            string                     `json:"description"`
+	Tags                   []string                   `json:"tags"`
+	Timezone               string                     `json:"timezone"`
+	UseLocalTime           bool                       `json:"useLocalTime"`
+	WorkingDir             string                     `json:"workingDir"`
+	RunAsUser              string                     `json:"runAsUser"`
+	RunAsGroup             string                     `json:"runAsGroup"`
+	CPULimit               int                        `json:"cpuLimit"`
+	MemoryLimit            int                        `json:"memoryLimit"`
+	MaxOpenFiles           int                        `json:"maxOpenFiles"`
+	MaxProcesses           int                        `json:"maxProcesses"`
+	EmailNotifications     *EmailNotificationSettings `json:"emailNotifications"`
+	Webhooks               []WebhookSettings          `json:"webhooks"`
+	AutoPauseAfterFailures int                        `json:"autoPauseAfterFailures"`
+	GracePeriod            int                        `json:"gracePeriod"`
+	ExpectedDuration       int                        `json:"expectedDuration"`
+}
+
+// Spec returns the configuration of a job. Tags must have been loaded. Webhook secrets are left
+// out: revisions are readable by every user who can view the job.
+func (j *Job) Spec() JobSpec {
+	var webhooks []WebhookSettings
+	if j.Webhooks != nil {
+		webhooks = make([]WebhookSettings, len(j.Webhooks))
+		for i, hook := range j.Webhooks {
+			hook.Secret = ""
+			webhooks[i] = hook
+		}
+	}
+	return JobSpec{
+		Name:                   j.Name,
+		Type:                   j.Type,
+		Command:                j.Command,
+		Endpoint:               j.Endpoint,
+		HTTPMethod:             j.HTTPMethod,
+		RequestBody:            j.RequestBody,
+		Headers:                j.Headers,
+		Schedule:               j.Schedule,
+		Description:            j.Description,
+		Tags:                   j.Tags,
+		Timezone:               j.Timezone,
+		UseLocalTime:           j.UseLocalTime,
+		WorkingDir:             j.WorkingDir,
+		RunAsUser:              j.RunAsUser,
+		RunAsGroup:             j.RunAsGroup,
+		CPULimit:               j.CPULimit,
+		MemoryLimit:            j.MemoryLimit,
+		MaxOpenFiles:           j.MaxOpenFiles,
+		MaxProcesses:           j.MaxProcesses,
+		EmailNotifications:     j.EmailNotifications,
+		Webhooks:               webhooks,
+		AutoPauseAfterFailures: j.AutoPauseAfterFailures,
+		GracePeriod:            j.GracePeriod,
+		ExpectedDuration:       j.ExpectedDuration,
+	}
+}
+
+// ApplySpec overwrites the configuration of a job. Tags are only copied to the Tags
+// field; SetJobTags stores them. Webhooks keep the secrets they had for the same URL.
+func (j *Job) ApplySpec(spec JobSpec) {
+	j.Name = spec.Name
+	j.Type = spec.Type
+	j.Command = spec.Command
+	j.Endpoint = spec.Endpoint
+	j.HTTPMethod = spec.HTTPMethod
+	j.RequestBody = spec.RequestBody
+	j.Headers = spec.Headers
+	j.Schedule = spec.Schedule
+	j.Description = spec.Description
+	j.Tags = spec.Tags
+	j.Timezone = spec.Timezone
+	j.UseLocalTime = spec.UseLocalTime
+	j.WorkingDir = spec.WorkingDir
+	j.RunAsUser = spec.RunAsUser
+	j.RunAsGroup = spec.RunAsGroup
+	j.CPULimit = spec.CPULimit
+	j.MemoryLimit = spec.MemoryLimit
+	j.MaxOpenFiles = spec.MaxOpenFiles
+	j.MaxProcesses = spec.MaxProcesses
+	j.EmailNotifications = spec.EmailNotifications
+	webhooks := append([]WebhookSettings(nil), spec.Webhooks...)
+	KeepWebhookSecrets(webhooks, j.Webhooks)
+	j.Webhooks = webhooks
+	j.AutoPauseAfterFailures = spec.AutoPauseAfterFailures
+	j.GracePeriod = spec.GracePeriod
+	j.ExpectedDuration = spec.ExpectedDuration
+}
+
+// FieldChange is a field that differs between two job specs
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// DiffJobSpecs lists the fields that differ between two specs, in declaration order.
+// Empty and missing lists or maps are considered equal.
+func DiffJobSpecs(from, to JobSpec) []FieldChange {
+	changes := []FieldChange{}
+	fromValue, toValue := reflect.ValueOf(from), reflect.ValueOf(to)
+	specType := fromValue.Type()
+
+	for i := 0; i < specType.NumField(); i++ {
+		a, b := fromValue.Field(i), toValue.Field(i)
+		if isEmptyCollection(a) && isEmptyCollection(b) {
+			continue
+		}
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			continue
+		}
+		changes = append(changes, FieldChange{
+			Field: strings.Split(specType.Field(i).Tag.Get("json"), ",")[0],
+			From:  a.Interface(),
+			To:    b.Interface(),
+		})
+	}
+
+	return changes
+}
+
+func isEmptyCollection(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// JobRevision is an immutable snapshot of a job's configuration, recorded whenever it changes
+type JobRevision struct {
+	ID                string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	JobID             string    `json:"jobId" gorm:"type:varchar(36);not null;uniqueIndex:idx_job_revisions_job_number"`
+	Number            int       `json:"number" gorm:"not null;uniqueIndex:idx_job_revisions_job_number"` // Sequential per job, starting at 1
+	Author            string    `json:"author" gorm:"type:varchar(100)"`                                 // User email, "api" or "system"
+	Reason            string    `json:"reason,omitempty" gorm:"type:varchar(255)"`
+	ChangedFields     []string  `json:"changedFields" gorm:"-"` // Stored as a comma separated list
+	ChangedFieldsList string    `json:"-" gorm:"column:changed_fields;type:varchar(1000)"`
+	Spec              JobSpec   `json:"spec" gorm:"-"` // Stored as JSON in the database
+	SpecJSON          string    `json:"-" gorm:"column:spec;type:text"`
+	CreatedAt         time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+func (r *JobRevision) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == "" {
+		r.ID = generateUUID()
+	}
+	r.ChangedFieldsList = strings.Join(r.ChangedFields, ",")
+	r.SpecJSON, err = encodeJSONColumn(r.Spec, false)
+	return
+}
+
+// BeforeUpdate keeps revisions immutable
+func (r *JobRevision) BeforeUpdate(tx *gorm.DB) (err error) {
+	return ErrRevisionImmutable
+}
+
+// AfterFind decodes the spec and changed fields
+func (r *JobRevision) AfterFind(tx *gorm.DB) (err error) {
+	r.ChangedFields = []string{}
+	if r.ChangedFieldsList != "" {
+		r.ChangedFields = strings.Split(r.ChangedFieldsList, ",")
+	}
+	if r.SpecJSON == "" {
+		return
+	}
+	return json.Unmarshal([]byte(r.SpecJSON), &r.Spec)
+}
+
+// RecordJobRevision stores the current configuration of a job as a new revision and makes it
+// the job's current revision. When nothing changed since the latest revision, that one is kept.
+func RecordJobRevision(db *gorm.DB, job *Job, author, reason string) (*JobRevision, error) {
+	spec := job.Spec()
+	revision := JobRevision{
+		JobID:  job.ID,
+		Number: 1,
+		Author: author,
+		Reason: reason,
+		Spec:   spec,
+	}
+
+	var latest JobRevision
+	err := db.Where("job_id = ?", job.ID).Order("number DESC").First(&latest).Error
+	switch {
+	case err == nil:
+		changes := DiffJobSpecs(latest.Spec, spec)
+		if len(changes) == 0 {
+			if job.RevisionID != latest.ID {
+				if err := setCurrentRevision(db, job, latest.ID); err != nil {
+					return nil, err
+				}
+			}
+			return &latest, nil
+		}
+		revision.Number = latest.Number + 1
+		revision.ChangedFields = changedFieldNames(changes)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		revision.ChangedFields = changedFieldNames(DiffJobSpecs(JobSpec{}, spec))
+	default:
+		return nil, err
+	}
+
+	if err := db.Create(&revision).Error; err != nil {
+		return nil, fmt.Errorf("failed to record revision: %v", err)
+	}
+	if err := setCurrentRevision(db, job, revision.ID); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func setCurrentRevision(db *gorm.DB, job *Job, revisionID string) error {
+	if err := db.Model(&Job{}).Where("id = ?", job.ID).UpdateColumn("revision_id", revisionID).Error; err != nil {
+		return fmt.Errorf("failed to update current revision: %v", err)
+	}
+	job.RevisionID = revisionID
+	return nil
+}
+
+func changedFieldNames(changes []FieldChange) []string {
+	names := make([]string, len(changes))
+	for i, change := range changes {
+		names[i] = change.Field
+	}
+	return names
+}
+
+// currentRevisionID returns the current revision of a job. Jobs created before revisions
+// were introduced get their first revision on the way.
+func currentRevisionID(db *gorm.DB, jobID string) (string, error) {
+	var job Job
+	if err := db.Unscoped().Preload("TagRefs").First(&job, "id = ?", jobID).Error; err != nil {
+		return "", err
+	}
+	if job.RevisionID != "" {
+		return job.RevisionID, nil
+	}
+
+	revision, err := RecordJobRevision(db, &job, RevisionAuthorSystem, "Recorded for a job created before revision history")
+	if err != nil {
+		return "", err
+	}
+	return revision.ID, nil
+}
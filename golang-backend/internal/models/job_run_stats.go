@@ -0,0 +1,255 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// ewmaAlpha is the weight of the newest run in the exponentially weighted moving average
+	ewmaAlpha = 0.2
+	// lastDurationsSize is how many recent durations are kept per job
+	lastDurationsSize = 10
+	// recordRunRetries bounds the optimistic update retries on concurrent writers
+	recordRunRetries = 5
+)
+
+// JobRunStats holds running aggregates of a job's executions.
+// Duration aggregates only consider successful runs, matching Job.AverageRuntime.
+type JobRunStats struct {
+	JobID             string    `json:"jobId" gorm:"primaryKey;type:varchar(36)"`
+	RunCount          int64     `json:"runCount" gorm:"default:0"`
+	SuccessCount      int64     `json:"successCount" gorm:"default:0"`
+	FailCount         int64     `json:"failCount" gorm:"default:0"`
+	DurationSum       float64   `json:"durationSum" gorm:"default:0"`
+	DurationMin       float64   `json:"durationMin" gorm:"default:0"`
+	DurationMax       float64   `json:"durationMax" gorm:"default:0"`
+	DurationEWMA      float64   `json:"durationEwma" gorm:"column:duration_ewma;default:0"`
+	LastDurations     []float64 `json:"lastDurations" gorm:"-"` // Stored as JSON in the database
+	LastDurationsJSON string    `json:"-" gorm:"column:last_durations;type:varchar(500)"`
+	LastRunAt         time.Time `json:"lastRunAt" gorm:"default:null"`
+	Version           int64     `json:"-" gorm:"default:0"`
+	UpdatedAt         time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// AverageDuration returns the mean duration of successful runs
+func (s *JobRunStats) AverageDuration() float64 {
+	if s.SuccessCount == 0 {
+		return 0
+	}
+	return s.DurationSum / float64(s.SuccessCount)
+}
+
+// apply folds a finished run into the aggregates
+func (s *JobRunStats) apply(status JobStatus, duration float64, at time.Time) {
+	s.RunCount++
+	if at.After(s.LastRunAt) {
+		s.LastRunAt = at
+	}
+
+	if status != JobStatusSuccess {
+		s.FailCount++
+		return
+	}
+
+	if s.SuccessCount == 0 {
+		s.DurationMin = duration
+		s.DurationMax = duration
+		s.DurationEWMA = duration
+	} else {
+		if duration < s.DurationMin {
+			s.DurationMin = duration
+		}
+		if duration > s.DurationMax {
+			s.DurationMax = duration
+		}
+		s.DurationEWMA = ewmaAlpha*duration + (1-ewmaAlpha)*s.DurationEWMA
+	}
+	s.SuccessCount++
+	s.DurationSum += duration
+
+	s.LastDurations = append(s.LastDurations, duration)
+	if len(s.LastDurations) > lastDurationsSize {
+		s.LastDurations = s.LastDurations[len(s.LastDurations)-lastDurationsSize:]
+	}
+}
+
+func (s *JobRunStats) encode() error {
+	data, err := json.Marshal(s.LastDurations)
+	if err != nil {
+		return err
+	}
+	s.LastDurationsJSON = string(data)
+	return nil
+}
+
+// AfterFind decodes the JSON encoded recent durations
+func (s *JobRunStats) AfterFind(tx *gorm.DB) (err error) {
+	s.LastDurations = []float64{}
+	if s.LastDurationsJSON == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(s.LastDurationsJSON), &s.LastDurations)
+}
+
+// GetJobRunStats loads the aggregates of a job, returning empty stats when none exist yet
+func GetJobRunStats(db *gorm.DB, jobID string) (*JobRunStats, error) {
+	stats := &JobRunStats{JobID: jobID, LastDurations: []float64{}}
+	err := db.First(stats, "job_id = ?", jobID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return stats, nil
+	}
+	return stats, err
+}
+
+// RecordRun folds a finished run into the job's aggregates.
+// Concurrent writers are detected through the version column and retried.
+func RecordRun(db *gorm.DB, jobID string, status JobStatus, duration float64, at time.Time) (*JobRunStats, error) {
+	for attempt := 0; attempt < recordRunRetries; attempt++ {
+		var stats JobRunStats
+		err := db.First(&stats, "job_id = ?", jobID).Error
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			stats = JobRunStats{JobID: jobID}
+			stats.apply(status, duration, at)
+			if err := stats.encode(); err != nil {
+				return nil, err
+			}
+			// A concurrent insert makes this fail, in which case the update path is retried
+			if err := db.Create(&stats).Error; err == nil {
+				return &stats, nil
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		version := stats.Version
+		stats.apply(status, duration, at)
+		stats.Version++
+		if err := stats.encode(); err != nil {
+			return nil, err
+		}
+
+		updated, err := stats.saveVersioned(db, version)
+		if err != nil {
+			return nil, err
+		}
+		if updated {
+			return &stats, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to record run for job %s: too many concurrent updates", jobID)
+}
+
+// saveVersioned stores the aggregates over the row with the given version.
+// It reports false when a concurrent writer changed the row first.
+func (s *JobRunStats) saveVersioned(db *gorm.DB, version int64) (bool, error) {
+	result := db.Model(&JobRunStats{}).
+		Where("job_id = ? AND version = ?", s.JobID, version).
+		Updates(map[string]interface{}{
+			"run_count":      s.RunCount,
+			"success_count":  s.SuccessCount,
+			"fail_count":     s.FailCount,
+			"duration_sum":   s.DurationSum,
+			"duration_min":   s.DurationMin,
+			"duration_max":   s.DurationMax,
+			"duration_ewma":  s.DurationEWMA,
+			"last_durations": s.LastDurationsJSON,
+			"last_run_at":    s.LastRunAt,
+			"version":        s.Version,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// errStatsChanged aborts a rebuild whose stats row was changed by a concurrent writer
+var errStatsChanged = errors.New("job run stats changed concurrently")
+
+// RebuildJobRunStats recomputes a job's aggregates from its logs and stores them,
+// replacing whatever was recorded before. It also refreshes Job.AverageRuntime.
+// The row is updated in place through its version, like RecordRun, so a run recorded
+// while the logs are read makes the rebuild start over instead of being lost.
+func RebuildJobRunStats(db *gorm.DB, jobID string) (*JobRunStats, error) {
+	for attempt := 0; attempt < recordRunRetries; attempt++ {
+		var stats *JobRunStats
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var current JobRunStats
+			err := tx.First(&current, "job_id = ?", jobID).Error
+			exists := err == nil
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			stats, err = computeJobRunStats(tx, jobID)
+			if err != nil {
+				return err
+			}
+
+			if exists {
+				stats.Version = current.Version + 1
+				updated, err := stats.saveVersioned(tx, current.Version)
+				if err != nil {
+					return err
+				}
+				if !updated {
+					return errStatsChanged
+				}
+			} else if err := tx.Create(stats).Error; err != nil {
+				// A concurrent RecordRun created the row first
+				return errStatsChanged
+			}
+
+			return tx.Model(&Job{}).Where("id = ?", jobID).Update("average_runtime", stats.AverageDuration()).Error
+		})
+		if errors.Is(err, errStatsChanged) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return stats, nil
+	}
+
+	return nil, fmt.Errorf("failed to rebuild run stats for job %s: too many concurrent updates", jobID)
+}
+
+// computeJobRunStats folds the finished logs of a job, oldest first, into fresh aggregates
+func computeJobRunStats(db *gorm.DB, jobID string) (*JobRunStats, error) {
+	stats := &JobRunStats{JobID: jobID, LastDurations: []float64{}}
+
+	rows, err := db.Model(&JobLog{}).
+		Select("status, start_time, duration").
+		Where("job_id = ? AND status IN ?", jobID, FinishedStatuses).
+		Order("start_time ASC").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			status    JobStatus
+			startTime time.Time
+			duration  float64
+		)
+		if err := rows.Scan(&status, &startTime, &duration); err != nil {
+			return nil, err
+		}
+		stats.apply(status, duration, startTime)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := stats.encode(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
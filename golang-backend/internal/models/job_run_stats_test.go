@@ -0,0 +1,130 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// statsDB returns a SQLite database with the tables run statistics are built from
+func statsDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := SetupDatabase(dbConfig(t, map[string]string{"DB_DRIVER": DriverSQLite, "DB_PATH": filepath.Join(t.TempDir(), "stats.db")}))
+	if err != nil {
+		t.Fatalf("SetupDatabase failed: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get connection pool: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&Project{}, &Job{}, &JobRevision{}, &JobLog{}, &JobRunStats{}, &SearchTerm{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// finishedRun stores a finished log for a job and folds it into the stats, like the scheduler does
+func finishedRun(t *testing.T, db *gorm.DB, jobID string, status JobStatus, duration float64, at time.Time) {
+	t.Helper()
+	run := JobLog{JobID: jobID, Status: status, StartTime: at, EndTime: at.Add(time.Duration(duration * float64(time.Second))), Duration: duration}
+	if err := db.Create(&run).Error; err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+	if _, err := RecordRun(db, jobID, status, duration, at); err != nil {
+		t.Fatalf("RecordRun failed: %v", err)
+	}
+}
+
+func TestRebuildJobRunStatsUpdatesInPlace(t *testing.T) {
+	db := statsDB(t)
+	project := Project{Name: "Backups"}
+	if err := db.Create(&project).Error; err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	job := Job{ProjectID: project.ID, Name: "dump", Command: "true", Schedule: "0 0 * * * *", Status: JobStatusIdle}
+	if err := db.Create(&job).Error; err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	finishedRun(t, db, job.ID, JobStatusSuccess, 2, start)
+	finishedRun(t, db, job.ID, JobStatusFailed, 1, start.Add(time.Hour))
+	// A run whose stats were lost, as the rebuild is meant to repair
+	lost := JobLog{JobID: job.ID, Status: JobStatusSuccess, StartTime: start.Add(2 * time.Hour), Duration: 4}
+	if err := db.Create(&lost).Error; err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+
+	// A writer that read the stats before the rebuild must not overwrite it
+	stale, err := GetJobRunStats(db, job.ID)
+	if err != nil {
+		t.Fatalf("GetJobRunStats failed: %v", err)
+	}
+
+	stats, err := RebuildJobRunStats(db, job.ID)
+	if err != nil {
+		t.Fatalf("RebuildJobRunStats failed: %v", err)
+	}
+	if stats.RunCount != 3 || stats.SuccessCount != 2 || stats.FailCount != 1 || stats.AverageDuration() != 3 {
+		t.Errorf("rebuilt stats = %+v, want 3 runs, 2 successes averaging 3s", stats)
+	}
+	if stats.Version != stale.Version+1 {
+		t.Errorf("version = %d, want %d: the rebuild must not reset the version", stats.Version, stale.Version+1)
+	}
+
+	stale.apply(JobStatusSuccess, 10, start.Add(3*time.Hour))
+	stale.Version++
+	if err := stale.encode(); err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if updated, err := stale.saveVersioned(db, stale.Version-1); err != nil || updated {
+		t.Errorf("stale update: updated = %v, err = %v, want rejected", updated, err)
+	}
+
+	// Runs recorded after the rebuild continue from it
+	finishedRun(t, db, job.ID, JobStatusSuccess, 6, start.Add(4*time.Hour))
+	stored, err := GetJobRunStats(db, job.ID)
+	if err != nil {
+		t.Fatalf("GetJobRunStats failed: %v", err)
+	}
+	if stored.RunCount != 4 || stored.SuccessCount != 3 || stored.Version != stats.Version+1 {
+		t.Errorf("stats after the rebuild = %+v", stored)
+	}
+
+	var average float64
+	db.Model(&Job{}).Where("id = ?", job.ID).Pluck("average_runtime", &average)
+	if average != 3 {
+		t.Errorf("average runtime = %g, want 3", average)
+	}
+}
+
+func TestRebuildJobRunStatsCreatesMissingStats(t *testing.T) {
+	db := statsDB(t)
+	project := Project{Name: "Reports"}
+	if err := db.Create(&project).Error; err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	job := Job{ProjectID: project.ID, Name: "report", Command: "true", Schedule: "0 0 * * * *", Status: JobStatusIdle}
+	if err := db.Create(&job).Error; err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	run := JobLog{JobID: job.ID, Status: JobStatusSuccess, StartTime: time.Now(), Duration: 5}
+	if err := db.Create(&run).Error; err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+
+	stats, err := RebuildJobRunStats(db, job.ID)
+	if err != nil {
+		t.Fatalf("RebuildJobRunStats failed: %v", err)
+	}
+	if stats.RunCount != 1 || stats.DurationSum != 5 {
+		t.Errorf("rebuilt stats = %+v", stats)
+	}
+	stored, err := GetJobRunStats(db, job.ID)
+	if err != nil || stored.RunCount != 1 {
+		t.Errorf("stored stats = %+v, err = %v", stored, err)
+	}
+}
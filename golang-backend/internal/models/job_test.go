@@ -0,0 +1,21 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJobValidateRejectsControlCharactersInName(t *testing.T) {
+	for _, name := range []string{"Backup\r\nBcc: attacker@example.com", "Backup\n", "Back\tup", "Back\x00up"} {
+		job := Job{Name: name, Schedule: "0 0 * * * *", Type: JobTypeShell, Command: "true"}
+		err := job.Validate()
+		if err == nil || !strings.Contains(err.Error(), "control characters") {
+			t.Errorf("Validate(%q) = %v, want a control character error", name, err)
+		}
+	}
+
+	job := Job{Name: "Sauvegarde nocturne é", Schedule: "0 0 * * * *", Type: JobTypeShell, Command: "true"}
+	if err := job.Validate(); err != nil {
+		t.Errorf("Validate rejected a valid name: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailNotificationSettings configures which runs of a job send an email
+type EmailNotificationSettings struct {
+	Recipients []string `json:"recipients"`
+	OnSuccess  bool     `json:"onSuccess"`
+	OnFailure  bool     `json:"onFailure"`
+}
+
+// WebhookSettings configures an outbound webhook of a job
+type WebhookSettings struct {
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Secret    string            `json:"-"` // HMAC-SHA256 signing key, only returned when it is generated
+	OnSuccess bool              `json:"onSuccess"`
+	OnFailure bool              `json:"onFailure"`
+}
+
+// storedWebhook is a webhook as stored in the webhooks column of a job, with its secret
+type storedWebhook struct {
+	WebhookSettings
+	Secret string `json:"secret"`
+}
+
+// WebhookSecret is the signing secret of a webhook of a job
+type WebhookSecret struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+// KeepWebhookSecrets gives the webhooks without a secret the secret of a previous webhook with
+// the same URL, so that editing a job, which never sees the secrets, does not replace them
+func KeepWebhookSecrets(webhooks, previous []WebhookSettings) {
+	secrets := make(map[string][]string)
+	for _, hook := range previous {
+		if hook.Secret != "" {
+			secrets[hook.URL] = append(secrets[hook.URL], hook.Secret)
+		}
+	}
+	for i := range webhooks {
+		if webhooks[i].Secret != "" || len(secrets[webhooks[i].URL]) == 0 {
+			continue
+		}
+		webhooks[i].Secret = secrets[webhooks[i].URL][0]
+		secrets[webhooks[i].URL] = secrets[webhooks[i].URL][1:]
+	}
+}
+
+// NewWebhookSecrets returns the secrets of webhooks that none of the previous webhooks has, i.e.
+// the secrets generated when the job was saved
+func NewWebhookSecrets(webhooks, previous []WebhookSettings) []WebhookSecret {
+	known := make(map[string]bool)
+	for _, hook := range previous {
+		known[hook.Secret] = true
+	}
+	var secrets []WebhookSecret
+	for _, hook := range webhooks {
+		if hook.Secret != "" && !known[hook.Secret] {
+			secrets = append(secrets, WebhookSecret{URL: hook.URL, Secret: hook.Secret})
+		}
+	}
+	return secrets
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusSent   DeliveryStatus = "sent"
+	DeliveryStatusFailed DeliveryStatus = "failed"
+)
+
+// NotificationDelivery records one attempt to deliver a notification
+type NotificationDelivery struct {
+	ID             string         `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	EventID        string         `json:"eventId" gorm:"type:varchar(36);index"` // Shared by retries and redeliveries of an event
+	JobID          string         `json:"jobId" gorm:"type:varchar(36);index;not null"`
+	ChannelID      string         `json:"channelId,omitempty" gorm:"type:varchar(36);index"` // Set for deliveries to a NotificationChannel
+	JobLogID       string         `json:"jobLogId" gorm:"type:varchar(36);index"`
+	Channel        string         `json:"channel" gorm:"type:varchar(20);not null"` // e.g. "email", "webhook", "slack"
+	Event          string         `json:"event" gorm:"type:varchar(20);not null"`
+	Target         string         `json:"target" gorm:"type:varchar(1000)"` // Recipients or URL
+	Status         DeliveryStatus `json:"status" gorm:"type:varchar(10);not null"`
+	Attempt        int            `json:"attempt" gorm:"default:1"`
+	Payload        string         `json:"payload,omitempty" gorm:"type:text"`
+	ResponseStatus int            `json:"responseStatus,omitempty"`
+	ResponseBody   string         `json:"responseBody,omitempty" gorm:"type:text"`
+	Error          string         `json:"error" gorm:"type:text"`
+	RedeliveryOf   string         `json:"redeliveryOf,omitempty" gorm:"type:varchar(36)"`
+	CreatedAt      time.Time      `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+func (d *NotificationDelivery) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == "" {
+		d.ID = generateUUID()
+	}
+	return
+}
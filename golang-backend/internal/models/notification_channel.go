@@ -0,0 +1,185 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ChannelType string
+
+const (
+	ChannelTypeEmail   ChannelType = "email"
+	ChannelTypeWebhook ChannelType = "webhook"
+	ChannelTypeSlack   ChannelType = "slack"
+	ChannelTypeTeams   ChannelType = "teams"
+	ChannelTypeDiscord ChannelType = "discord"
+)
+
+// Notification events a job can subscribe a channel to
+const (
+	NotifyOnSuccess  = "success"
+	NotifyOnFailure  = "failure"
+	NotifyOnRecovery = "recovery" // First success after one or more failures
+	NotifyOnSlow     = "slow"     // Run exceeded its expected duration
+	NotifyOnAlert    = "alert"    // An alert rule of the job started firing
+	NotifyOnPaused   = "paused"   // The job was paused automatically after repeated failures
+)
+
+// ValidNotifyEvents lists the event filters accepted by subscriptions
+var ValidNotifyEvents = []string{NotifyOnSuccess, NotifyOnFailure, NotifyOnRecovery, NotifyOnSlow, NotifyOnAlert, NotifyOnPaused}
+
+// ChannelSettings holds the type specific configuration of a channel
+type ChannelSettings struct {
+	URL        string            `json:"url,omitempty"`        // webhook, slack, teams, discord
+	Method     string            `json:"method,omitempty"`     // webhook
+	Headers    map[string]string `json:"headers,omitempty"`    // webhook
+	Secret     string            `json:"secret,omitempty"`     // webhook HMAC-SHA256 signing key
+	Recipients []string          `json:"recipients,omitempty"` // email
+}
+
+// RedactedValue stands in for credentials, such as channel header values, in API responses and the audit log
+const RedactedValue = "********"
+
+// RedactURL keeps only the scheme and host of a URL that is a credential itself, like the
+// incoming webhooks of Slack, Teams and Discord
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return RedactedValue
+	}
+	return u.Scheme + "://" + u.Host + "/" + RedactedValue
+}
+
+// NotificationChannel is a reusable notification target owned by a project
+type NotificationChannel struct {
+	ID           string          `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	ProjectID    string          `json:"projectId" gorm:"type:varchar(36);index;not null"`
+	Name         string          `json:"name" gorm:"type:varchar(100);not null"`
+	Type         ChannelType     `json:"type" gorm:"type:varchar(10);not null"`
+	Enabled      bool            `json:"enabled"`
+	Settings     ChannelSettings `json:"settings" gorm:"-"` // Stored as JSON in the database
+	SettingsJSON string          `json:"-" gorm:"column:settings;type:text"`
+	CreatedAt    time.Time       `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time       `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (ch *NotificationChannel) BeforeCreate(tx *gorm.DB) (err error) {
+	if ch.ID == "" {
+		ch.ID = generateUUID()
+	}
+	return
+}
+
+// BeforeSave encodes the channel settings
+func (ch *NotificationChannel) BeforeSave(tx *gorm.DB) (err error) {
+	if ch.Type == ChannelTypeWebhook && ch.Settings.Secret == "" {
+		ch.Settings.Secret = generateSecret()
+	}
+	ch.SettingsJSON, err = encodeJSONColumn(ch.Settings, false)
+	return
+}
+
+// AfterFind decodes the channel settings
+func (ch *NotificationChannel) AfterFind(tx *gorm.DB) (err error) {
+	if ch.SettingsJSON == "" {
+		return
+	}
+	return json.Unmarshal([]byte(ch.SettingsJSON), &ch.Settings)
+}
+
+// Validate checks that the settings match the channel type
+func (ch *NotificationChannel) Validate() error {
+	if strings.TrimSpace(ch.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch ch.Type {
+	case ChannelTypeEmail:
+		if len(ch.Settings.Recipients) == 0 {
+			return fmt.Errorf("email channels need at least one recipient")
+		}
+		for _, recipient := range ch.Settings.Recipients {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				return fmt.Errorf("invalid email recipient %q", recipient)
+			}
+		}
+	case ChannelTypeWebhook, ChannelTypeSlack, ChannelTypeTeams, ChannelTypeDiscord:
+		u, err := url.Parse(ch.Settings.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an absolute http(s) URL")
+		}
+	default:
+		return fmt.Errorf("unknown channel type %q", ch.Type)
+	}
+
+	return nil
+}
+
+// JobChannelSubscription subscribes a job to a channel for a set of events
+type JobChannelSubscription struct {
+	ID         string               `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	JobID      string               `json:"jobId" gorm:"type:varchar(36);index;not null"`
+	ChannelID  string               `json:"channelId" gorm:"type:varchar(36);index;not null"`
+	Events     []string             `json:"events" gorm:"-"` // Stored as a comma separated list
+	EventsList string               `json:"-" gorm:"column:events;type:varchar(100)"`
+	Channel    *NotificationChannel `json:"channel,omitempty" gorm:"foreignKey:ChannelID"`
+	CreatedAt  time.Time            `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+func (s *JobChannelSubscription) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == "" {
+		s.ID = generateUUID()
+	}
+	return
+}
+
+// BeforeSave encodes the event filters
+func (s *JobChannelSubscription) BeforeSave(tx *gorm.DB) (err error) {
+	s.EventsList = strings.Join(s.Events, ",")
+	return
+}
+
+// AfterFind decodes the event filters
+func (s *JobChannelSubscription) AfterFind(tx *gorm.DB) (err error) {
+	s.Events = []string{}
+	if s.EventsList != "" {
+		s.Events = strings.Split(s.EventsList, ",")
+	}
+	return
+}
+
+// Wants reports whether the subscription includes an event
+func (s *JobChannelSubscription) Wants(event string) bool {
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the event filters of the subscription
+func (s *JobChannelSubscription) Validate() error {
+	if len(s.Events) == 0 {
+		return fmt.Errorf("at least one event is required")
+	}
+	for _, e := range s.Events {
+		valid := false
+		for _, v := range ValidNotifyEvents {
+			if e == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unknown event %q, expected one of %s", e, strings.Join(ValidNotifyEvents, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func jobWithWebhook() *Job {
+	return &Job{
+		ID:       "job-1",
+		Name:     "Nightly backup",
+		Webhooks: []WebhookSettings{{URL: "https://hooks.example.com/a", Method: "POST", OnFailure: true}},
+	}
+}
+
+func TestWebhookSecretIsStoredButNotSerialized(t *testing.T) {
+	job := jobWithWebhook()
+	if err := job.BeforeSave(nil); err != nil {
+		t.Fatalf("BeforeSave failed: %v", err)
+	}
+	secret := job.Webhooks[0].Secret
+	if secret == "" {
+		t.Fatal("no secret generated")
+	}
+	if !strings.Contains(job.WebhooksJSON, secret) {
+		t.Errorf("webhooks column %q does not hold the secret", job.WebhooksJSON)
+	}
+
+	loaded := Job{WebhooksJSON: job.WebhooksJSON}
+	if err := loaded.AfterFind(nil); err != nil {
+		t.Fatalf("AfterFind failed: %v", err)
+	}
+	if loaded.Webhooks[0].Secret != secret {
+		t.Errorf("loaded secret = %q, want %q", loaded.Webhooks[0].Secret, secret)
+	}
+
+	for name, value := range map[string]interface{}{
+		"job":      loaded,
+		"spec":     loaded.Spec(),
+		"snapshot": AuditSnapshot(&loaded),
+	} {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("failed to encode %s: %v", name, err)
+		}
+		if strings.Contains(string(data), secret) {
+			t.Errorf("%s exposes the webhook secret: %s", name, data)
+		}
+	}
+}
+
+func TestApplySpecKeepsWebhookSecrets(t *testing.T) {
+	job := jobWithWebhook()
+	job.Webhooks[0].Secret = "kept"
+	spec := job.Spec()
+	spec.Webhooks = append(spec.Webhooks, WebhookSettings{URL: "https://hooks.example.com/b"})
+
+	job.ApplySpec(spec)
+	if job.Webhooks[0].Secret != "kept" {
+		t.Errorf("secret of the existing webhook = %q, want it kept", job.Webhooks[0].Secret)
+	}
+	if job.Webhooks[1].Secret != "" {
+		t.Errorf("new webhook got secret %q", job.Webhooks[1].Secret)
+	}
+}
+
+func TestNewWebhookSecrets(t *testing.T) {
+	previous := []WebhookSettings{{URL: "https://a", Secret: "old"}}
+	webhooks := []WebhookSettings{{URL: "https://a"}, {URL: "https://b", Secret: "new"}}
+	KeepWebhookSecrets(webhooks, previous)
+	if webhooks[0].Secret != "old" {
+		t.Errorf("secret = %q, want old", webhooks[0].Secret)
+	}
+
+	secrets := NewWebhookSecrets(webhooks, previous)
+	if len(secrets) != 1 || secrets[0] != (WebhookSecret{URL: "https://b", Secret: "new"}) {
+		t.Errorf("NewWebhookSecrets = %+v", secrets)
+	}
+}
@@ -2,6 +2,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,12 +15,33 @@ type Project struct {
 	Description string    `json:"description" gorm:"type:varchar(500)"`
 	CreatedAt   time.Time `json:"createdAt" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	DeletedAt   gorm.DeletedAt `json:"deletedAt,omitempty" gorm:"index"` // Set while the project is in the trash
+	Version     int64     `json:"version" gorm:"not null;default:1"` // Incremented on every edit, exposed as the ETag
 	Jobs        []Job     `json:"jobs,omitempty" gorm:"foreignKey:ProjectID"`
 }
 
+// EditableColumns returns the columns an edit of the project stores
+func (p *Project) EditableColumns() []string {
+	return []string{"name", "description"}
+}
+
 func (p *Project) BeforeCreate(tx *gorm.DB) (err error) {
 	if p.ID == "" {
 		p.ID = generateUUID()
 	}
 	return
 }
+
+// Validate checks the user-defined fields of a project
+func (p *Project) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(p.Name) > 100 {
+		return fmt.Errorf("name must be at most 100 characters")
+	}
+	if len(p.Description) > 500 {
+		return fmt.Errorf("description must be at most 500 characters")
+	}
+	return nil
+}
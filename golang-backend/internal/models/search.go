@@ -0,0 +1,294 @@
+package models
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// SearchDocType is the kind of record an entry of the search index points to
+type SearchDocType string
+
+const (
+	SearchDocJob SearchDocType = "job" // A job, by its configuration
+	SearchDocRun SearchDocType = "run" // A run, by its output and error
+)
+
+const (
+	// MaxSearchWordLength is the length of the longest word kept in the search index, in characters
+	MaxSearchWordLength = 64
+	// MaxSearchOccurrences caps how often a word counts in one field when ranking
+	MaxSearchOccurrences = 5
+	// maxSearchRunText is how much of the output and of the error of a run is searchable, in bytes
+	maxSearchRunText = 64 << 10
+	// searchSnippetLength is the length of the text shown around a match, in bytes
+	searchSnippetLength = 200
+)
+
+// SearchFieldWeights ranks matches by the field they occur in
+var SearchFieldWeights = map[string]float64{
+	"name":        10,
+	"tags":        6,
+	"endpoint":    4,
+	"command":     4,
+	"description": 2,
+	"error":       2,
+	"output":      1,
+}
+
+// SearchTerm is an entry of the search index: how often a word occurs in one field of a job or run
+type SearchTerm struct {
+	Term        string        `gorm:"primaryKey;type:varchar(64)"`
+	DocType     SearchDocType `gorm:"primaryKey;type:varchar(10)"`
+	DocID       string        `gorm:"primaryKey;type:varchar(36)"`
+	Field       string        `gorm:"primaryKey;type:varchar(20)"`
+	JobID       string        `gorm:"type:varchar(36);not null;index"` // The job itself, or the job of the run
+	Occurrences int           `gorm:"not null"`
+}
+
+// SearchText is the text of one searchable field of a job or run
+type SearchText struct {
+	Field string
+	Text  string
+}
+
+// SearchHighlight is a field of a search result with the words searched for marked
+type SearchHighlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"` // HTML-escaped text around the first match, with every match wrapped in <mark>
+}
+
+// SearchTexts returns the searchable fields of a job. job.Tags must hold its tags.
+func (j *Job) SearchTexts() []SearchText {
+	return []SearchText{
+		{Field: "name", Text: j.Name},
+		{Field: "tags", Text: strings.Join(j.Tags, " ")},
+		{Field: "endpoint", Text: j.Endpoint},
+		{Field: "command", Text: j.Command},
+		{Field: "description", Text: j.Description},
+	}
+}
+
+// SearchTexts returns the searchable fields of a run: the start of its error and output
+func (l *JobLog) SearchTexts() []SearchText {
+	return []SearchText{
+		{Field: "error", Text: searchablePrefix(l.Error)},
+		{Field: "output", Text: searchablePrefix(l.Output)},
+	}
+}
+
+// searchablePrefix cuts text to the part of run output that is searchable, on a character boundary
+func searchablePrefix(text string) string {
+	if len(text) <= maxSearchRunText {
+		return text
+	}
+	cut := maxSearchRunText
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
+
+// wordSpan is the position of a word in a text
+type wordSpan struct {
+	start, end int
+}
+
+// searchSpans returns the positions of the words of text: runs of letters and digits of at most
+// MaxSearchWordLength characters. Anything else separates words, so "ORA-01555" is "ora" and "01555".
+func searchSpans(text string) []wordSpan {
+	var spans []wordSpan
+	start, length := -1, 0
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start, length = i, 0
+			}
+			length++
+			continue
+		}
+		if start >= 0 && length <= MaxSearchWordLength {
+			spans = append(spans, wordSpan{start, i})
+		}
+		start = -1
+	}
+	if start >= 0 && length <= MaxSearchWordLength {
+		spans = append(spans, wordSpan{start, len(text)})
+	}
+	return spans
+}
+
+// SearchWords splits text into the lowercase words the search index is made of, in order and with repetitions
+func SearchWords(text string) []string {
+	spans := searchSpans(text)
+	words := make([]string, len(spans))
+	for i, span := range spans {
+		words[i] = strings.ToLower(text[span.start:span.end])
+	}
+	return words
+}
+
+// HasSearchPhrase reports whether words occur one after the other in text
+func HasSearchPhrase(text string, words []string) bool {
+	if len(words) == 0 {
+		return false
+	}
+	textWords := SearchWords(text)
+	for i := 0; i+len(words) <= len(textWords); i++ {
+		match := true
+		for j, word := range words {
+			if textWords[i+j] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// HighlightSearch returns the part of text around the first of the words, HTML-escaped, with every
+// occurrence of the words wrapped in <mark>. It returns false when text contains none of them.
+func HighlightSearch(text string, words map[string]bool) (string, bool) {
+	var matches []wordSpan
+	for _, span := range searchSpans(text) {
+		if words[strings.ToLower(text[span.start:span.end])] {
+			matches = append(matches, span)
+		}
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	// Long texts are cut to a snippet starting a little before the first match
+	from, to := 0, len(text)
+	if len(text) > searchSnippetLength {
+		from = matches[0].start - searchSnippetLength/4
+		if from < 0 {
+			from = 0
+		}
+		to = from + searchSnippetLength
+		if to > len(text) {
+			to = len(text)
+		}
+		for from > 0 && !utf8.RuneStart(text[from]) {
+			from++
+		}
+		for to < len(text) && !utf8.RuneStart(text[to]) {
+			to--
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, match := range matches {
+		if match.start < from || match.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:match.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[match.start:match.end]))
+		b.WriteString("</mark>")
+		pos = match.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// IndexJob replaces the entries of a job in the search index. job.Tags must hold its tags.
+func IndexJob(db *gorm.DB, job *Job) error {
+	return indexSearchDoc(db, SearchDocJob, job.ID, job.ID, job.SearchTexts())
+}
+
+// IndexRun replaces the entries of a run in the search index
+func IndexRun(db *gorm.DB, run *JobLog) error {
+	return indexSearchDoc(db, SearchDocRun, run.ID, run.JobID, run.SearchTexts())
+}
+
+// ReindexJobs indexes the given jobs again, including jobs in the trash, e.g. after their tags changed
+func ReindexJobs(db *gorm.DB, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var jobs []Job
+	if err := db.Unscoped().Preload("TagRefs").Find(&jobs, "id IN ?", ids).Error; err != nil {
+		return err
+	}
+	for i := range jobs {
+		if err := IndexJob(db, &jobs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TaggedJobIDs returns the IDs of the jobs with a tag, including jobs in the trash
+func TaggedJobIDs(db *gorm.DB, tagID string) ([]string, error) {
+	var ids []string
+	err := db.Table("job_tags").Where("tag_id = ?", tagID).Pluck("job_id", &ids).Error
+	return ids, err
+}
+
+// RebuildSearchIndex indexes a job and all its runs again. It returns the number of runs indexed.
+func RebuildSearchIndex(db *gorm.DB, jobID string) (int, error) {
+	if err := ReindexJobs(db, []string{jobID}); err != nil {
+		return 0, err
+	}
+
+	indexed := 0
+	var runs []JobLog
+	err := db.Where("job_id = ?", jobID).FindInBatches(&runs, 100, func(tx *gorm.DB, batch int) error {
+		for i := range runs {
+			if err := IndexRun(db, &runs[i]); err != nil {
+				return err
+			}
+		}
+		indexed += len(runs)
+		return nil
+	}).Error
+	return indexed, err
+}
+
+// indexSearchDoc replaces the entries of a job or run in the search index with the words of texts
+func indexSearchDoc(db *gorm.DB, docType SearchDocType, docID, jobID string, texts []SearchText) error {
+	if err := db.Where("doc_type = ? AND doc_id = ?", docType, docID).Delete(&SearchTerm{}).Error; err != nil {
+		return err
+	}
+
+	var entries []SearchTerm
+	for _, text := range texts {
+		occurrences := make(map[string]int)
+		var words []string
+		for _, word := range SearchWords(text.Text) {
+			if occurrences[word] == 0 {
+				words = append(words, word)
+			}
+			occurrences[word]++
+		}
+		for _, word := range words {
+			entries = append(entries, SearchTerm{
+				Term:        word,
+				DocType:     docType,
+				DocID:       docID,
+				Field:       text.Field,
+				JobID:       jobID,
+				Occurrences: occurrences[word],
+			})
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return db.CreateInBatches(entries, 100).Error
+}
@@ -0,0 +1,383 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StatsBucket aggregates runs that started within one hour or day
+type StatsBucket struct {
+	Start           time.Time `json:"start"`
+	Total           int       `json:"total"`
+	Success         int       `json:"success"`
+	Failed          int       `json:"failed"`
+	SLABreaches     int       `json:"slaBreaches"`
+	AverageDuration float64   `json:"averageDuration"`
+}
+
+// FailureStreak is a run of consecutive failed executions
+type FailureStreak struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Count int       `json:"count"`
+}
+
+// JobStats summarises the runs of a job within a time range
+type JobStats struct {
+	JobID                string          `json:"jobId"`
+	From                 time.Time       `json:"from"`
+	To                   time.Time       `json:"to"`
+	TotalRuns            int             `json:"totalRuns"`
+	SuccessCount         int             `json:"successCount"`
+	FailCount            int             `json:"failCount"`
+	SuccessRate          float64         `json:"successRate"` // percentage of finished runs
+	SLABreaches          int             `json:"slaBreaches"` // runs that exceeded the job's expected duration
+	AverageDuration      float64         `json:"averageDuration"`
+	P50Duration          float64         `json:"p50Duration"`
+	P90Duration          float64         `json:"p90Duration"`
+	P99Duration          float64         `json:"p99Duration"`
+	LongestFailureStreak int             `json:"longestFailureStreak"`
+	CurrentFailureStreak int             `json:"currentFailureStreak"`
+	FailureStreaks       []FailureStreak `json:"failureStreaks"`
+	Hourly               []StatsBucket   `json:"hourly"`
+	Daily                []StatsBucket   `json:"daily"`
+	Aggregates           *JobRunStats    `json:"aggregates"` // All-time running aggregates
+}
+
+// ProjectJobSummary is the per-job line of a project rollup
+type ProjectJobSummary struct {
+	JobID           string    `json:"jobId"`
+	Name            string    `json:"name"`
+	Status          JobStatus `json:"status"`
+	TotalRuns       int       `json:"totalRuns"`
+	SuccessRate     float64   `json:"successRate"`
+	SLABreaches     int       `json:"slaBreaches"`
+	AverageDuration float64   `json:"averageDuration"`
+}
+
+// ProjectStats rolls up the jobs of a project within a time range
+type ProjectStats struct {
+	ProjectID       string              `json:"projectId"`
+	From            time.Time           `json:"from"`
+	To              time.Time           `json:"to"`
+	TotalJobs       int                 `json:"totalJobs"`
+	JobsByStatus    map[JobStatus]int   `json:"jobsByStatus"`
+	TotalRuns       int                 `json:"totalRuns"`
+	SuccessCount    int                 `json:"successCount"`
+	FailCount       int                 `json:"failCount"`
+	SuccessRate     float64             `json:"successRate"`
+	SLABreaches     int                 `json:"slaBreaches"`
+	AverageDuration float64             `json:"averageDuration"`
+	P50Duration     float64             `json:"p50Duration"`
+	P90Duration     float64             `json:"p90Duration"`
+	P99Duration     float64             `json:"p99Duration"`
+	Jobs            []ProjectJobSummary `json:"jobs"`
+	Daily           []StatsBucket       `json:"daily"`
+}
+
+// runSample is the subset of a JobLog needed for statistics
+type runSample struct {
+	JobID       string
+	Status      JobStatus
+	StartTime   time.Time
+	Duration    float64
+	SLABreached bool `gorm:"column:sla_breached"`
+}
+
+// loadRunSamples fetches finished runs in [from, to) ordered by start time
+func loadRunSamples(db *gorm.DB, jobIDs []string, from, to time.Time) ([]runSample, error) {
+	var samples []runSample
+	err := db.Model(&JobLog{}).
+		Select("job_id, status, start_time, duration, sla_breached").
+		Where("job_id IN ? AND start_time >= ? AND start_time < ?", jobIDs, from, to).
+		Where("status IN ?", FinishedStatuses).
+		Order("start_time ASC").
+		Scan(&samples).Error
+	return samples, err
+}
+
+// ComputeJobStats calculates run statistics for a job from its logs
+func ComputeJobStats(db *gorm.DB, jobID string, from, to time.Time) (*JobStats, error) {
+	samples, err := loadRunSamples(db, []string{jobID}, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := jobStats(jobID, samples, from, to)
+	if stats.Aggregates, err = GetJobRunStats(db, jobID); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// JobStatsFromLogs calculates run statistics for a job from logs already loaded, for stores
+// without SQL. Aggregates is left nil.
+func JobStatsFromLogs(jobID string, logs []JobLog, from, to time.Time) *JobStats {
+	return jobStats(jobID, samplesOf(logs, from, to), from, to)
+}
+
+// samplesOf keeps the finished logs in [from, to) ordered by start time, like loadRunSamples
+func samplesOf(logs []JobLog, from, to time.Time) []runSample {
+	samples := []runSample{}
+	for _, l := range logs {
+		if l.StartTime.Before(from) || !l.StartTime.Before(to) || !finished(l.Status) {
+			continue
+		}
+		samples = append(samples, runSample{
+			JobID:       l.JobID,
+			Status:      l.Status,
+			StartTime:   l.StartTime,
+			Duration:    l.Duration,
+			SLABreached: l.SLABreached,
+		})
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].StartTime.Before(samples[j].StartTime) })
+	return samples
+}
+
+func finished(status JobStatus) bool {
+	for _, f := range FinishedStatuses {
+		if status == f {
+			return true
+		}
+	}
+	return false
+}
+
+func jobStats(jobID string, samples []runSample, from, to time.Time) *JobStats {
+	stats := &JobStats{
+		JobID:          jobID,
+		From:           from,
+		To:             to,
+		FailureStreaks: []FailureStreak{},
+	}
+
+	summary := summarise(samples)
+	stats.TotalRuns = summary.total
+	stats.SuccessCount = summary.success
+	stats.FailCount = summary.failed
+	stats.SuccessRate = summary.successRate()
+	stats.SLABreaches = summary.slaBreaches
+	stats.AverageDuration = summary.average()
+	stats.P50Duration = summary.percentile(50)
+	stats.P90Duration = summary.percentile(90)
+	stats.P99Duration = summary.percentile(99)
+
+	// Walk the runs in order to find consecutive failures
+	var streak *FailureStreak
+	for _, s := range samples {
+		if s.Status != JobStatusSuccess {
+			if streak == nil {
+				streak = &FailureStreak{Start: s.StartTime}
+			}
+			streak.End = s.StartTime
+			streak.Count++
+			continue
+		}
+		if streak != nil {
+			stats.FailureStreaks = append(stats.FailureStreaks, *streak)
+			streak = nil
+		}
+	}
+	if streak != nil {
+		stats.FailureStreaks = append(stats.FailureStreaks, *streak)
+		stats.CurrentFailureStreak = streak.Count
+	}
+	for _, fs := range stats.FailureStreaks {
+		if fs.Count > stats.LongestFailureStreak {
+			stats.LongestFailureStreak = fs.Count
+		}
+	}
+
+	stats.Hourly = bucketize(samples, time.Hour)
+	stats.Daily = bucketize(samples, 24*time.Hour)
+
+	return stats
+}
+
+// ComputeProjectStats rolls up run statistics for all jobs of a project
+func ComputeProjectStats(db *gorm.DB, projectID string, from, to time.Time) (*ProjectStats, error) {
+	var jobs []Job
+	if err := db.Where("project_id = ?", projectID).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+
+	if len(jobs) == 0 {
+		return projectStats(projectID, jobs, nil, from, to), nil
+	}
+
+	jobIDs := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		jobIDs = append(jobIDs, job.ID)
+	}
+
+	samples, err := loadRunSamples(db, jobIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return projectStats(projectID, jobs, samples, from, to), nil
+}
+
+// ProjectStatsFromLogs rolls up run statistics for the jobs of a project from logs already loaded
+func ProjectStatsFromLogs(projectID string, jobs []Job, logs []JobLog, from, to time.Time) *ProjectStats {
+	return projectStats(projectID, jobs, samplesOf(logs, from, to), from, to)
+}
+
+func projectStats(projectID string, jobs []Job, samples []runSample, from, to time.Time) *ProjectStats {
+	stats := &ProjectStats{
+		ProjectID:    projectID,
+		From:         from,
+		To:           to,
+		TotalJobs:    len(jobs),
+		JobsByStatus: make(map[JobStatus]int),
+		Jobs:         []ProjectJobSummary{},
+		Daily:        []StatsBucket{},
+	}
+
+	for _, job := range jobs {
+		stats.JobsByStatus[job.Status]++
+	}
+	if len(jobs) == 0 {
+		return stats
+	}
+
+	summary := summarise(samples)
+	stats.TotalRuns = summary.total
+	stats.SuccessCount = summary.success
+	stats.FailCount = summary.failed
+	stats.SuccessRate = summary.successRate()
+	stats.SLABreaches = summary.slaBreaches
+	stats.AverageDuration = summary.average()
+	stats.P50Duration = summary.percentile(50)
+	stats.P90Duration = summary.percentile(90)
+	stats.P99Duration = summary.percentile(99)
+	stats.Daily = bucketize(samples, 24*time.Hour)
+
+	perJob := make(map[string][]runSample)
+	for _, s := range samples {
+		perJob[s.JobID] = append(perJob[s.JobID], s)
+	}
+
+	for _, job := range jobs {
+		js := summarise(perJob[job.ID])
+		stats.Jobs = append(stats.Jobs, ProjectJobSummary{
+			JobID:           job.ID,
+			Name:            job.Name,
+			Status:          job.Status,
+			TotalRuns:       js.total,
+			SuccessRate:     js.successRate(),
+			SLABreaches:     js.slaBreaches,
+			AverageDuration: js.average(),
+		})
+	}
+
+	return stats
+}
+
+// runSummary holds counts and sorted durations of a set of runs
+type runSummary struct {
+	total       int
+	success     int
+	failed      int
+	slaBreaches int
+	durations   []float64
+}
+
+func summarise(samples []runSample) runSummary {
+	summary := runSummary{durations: make([]float64, 0, len(samples))}
+	for _, s := range samples {
+		summary.total++
+		if s.Status == JobStatusSuccess {
+			summary.success++
+		} else {
+			summary.failed++
+		}
+		if s.SLABreached {
+			summary.slaBreaches++
+		}
+		if s.Status == JobStatusMissed {
+			continue // Missed heartbeats have no meaningful duration
+		}
+		summary.durations = append(summary.durations, s.Duration)
+	}
+	sort.Float64s(summary.durations)
+	return summary
+}
+
+func (r runSummary) successRate() float64 {
+	if r.total == 0 {
+		return 0
+	}
+	return round2(float64(r.success) / float64(r.total) * 100)
+}
+
+func (r runSummary) average() float64 {
+	if len(r.durations) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, d := range r.durations {
+		sum += d
+	}
+	return round2(sum / float64(len(r.durations)))
+}
+
+// percentile uses the nearest-rank method on the sorted durations
+func (r runSummary) percentile(p float64) float64 {
+	if len(r.durations) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(r.durations))))
+	if rank < 1 {
+		rank = 1
+	}
+	return round2(r.durations[rank-1])
+}
+
+// bucketize groups runs by their start time truncated to the bucket size (UTC)
+func bucketize(samples []runSample, size time.Duration) []StatsBucket {
+	buckets := []StatsBucket{}
+	index := make(map[time.Time]int)
+	sums := []float64{}
+	timed := []int{}
+
+	for _, s := range samples {
+		start := s.StartTime.UTC().Truncate(size)
+		i, exists := index[start]
+		if !exists {
+			i = len(buckets)
+			index[start] = i
+			buckets = append(buckets, StatsBucket{Start: start})
+			sums = append(sums, 0)
+			timed = append(timed, 0)
+		}
+		buckets[i].Total++
+		if s.Status == JobStatusSuccess {
+			buckets[i].Success++
+		} else {
+			buckets[i].Failed++
+		}
+		if s.SLABreached {
+			buckets[i].SLABreaches++
+		}
+		if s.Status != JobStatusMissed {
+			sums[i] += s.Duration
+			timed[i]++
+		}
+	}
+
+	for i := range buckets {
+		if timed[i] > 0 {
+			buckets[i].AverageDuration = round2(sums[i] / float64(timed[i]))
+		}
+	}
+
+	return buckets
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
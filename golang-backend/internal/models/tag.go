@@ -0,0 +1,154 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxTagNameLength is the longest tag name accepted
+const MaxTagNameLength = 50
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Tag labels jobs of a project. Names are unique within a project.
+type Tag struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	ProjectID string    `json:"projectId" gorm:"type:varchar(36);not null;uniqueIndex:idx_tags_project_name"`
+	Name      string    `json:"name" gorm:"type:varchar(50);not null;uniqueIndex:idx_tags_project_name"`
+	Color     string    `json:"color,omitempty" gorm:"type:varchar(7)"` // #rrggbb
+	JobCount  int64     `json:"jobCount" gorm:"->;-:migration"`         // Only filled by ListTags
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (t *Tag) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == "" {
+		t.ID = generateUUID()
+	}
+	return
+}
+
+// Validate checks the name and color of a tag
+func (t *Tag) Validate() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(t.Name) > MaxTagNameLength {
+		return fmt.Errorf("name must be at most %d characters", MaxTagNameLength)
+	}
+	if strings.Contains(t.Name, ",") {
+		return fmt.Errorf("name must not contain commas")
+	}
+	if t.Color != "" && !tagColorPattern.MatchString(t.Color) {
+		return fmt.Errorf("color must be a hex color such as #3b82f6")
+	}
+	return nil
+}
+
+// NormalizeTagNames trims tag names and drops empty and duplicate ones, comparing case-insensitively
+func NormalizeTagNames(names []string) ([]string, error) {
+	normalized := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		tag := Tag{Name: name}
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		if err := tag.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid tag %q: %v", name, err)
+		}
+		key := strings.ToLower(tag.Name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, tag.Name)
+	}
+	return normalized, nil
+}
+
+// SetJobTags replaces the tags of a job with the named tags of its project, creating missing ones
+func SetJobTags(db *gorm.DB, job *Job, names []string) error {
+	names, err := NormalizeTagNames(names)
+	if err != nil {
+		return err
+	}
+
+	tags := make([]Tag, 0, len(names))
+	for _, name := range names {
+		// Reuse an existing tag whose name only differs in case
+		var tag Tag
+		err := db.Where("project_id = ? AND LOWER(name) = LOWER(?)", job.ProjectID, name).First(&tag).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			tag = Tag{ProjectID: job.ProjectID, Name: name}
+			err = db.Create(&tag).Error
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save tag %q: %v", name, err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := db.Model(job).Omit("TagRefs.*").Association("TagRefs").Replace(tags); err != nil {
+		return fmt.Errorf("failed to update job tags: %v", err)
+	}
+
+	job.TagRefs = tags
+	job.Tags = make([]string, 0, len(tags))
+	for _, tag := range tags {
+		job.Tags = append(job.Tags, tag.Name)
+	}
+	return nil
+}
+
+// ListTags returns the tags of a project, or of every project when projectID is empty,
+// with the number of jobs using each tag
+func ListTags(db *gorm.DB, projectID string) ([]Tag, error) {
+	// Jobs and projects in the trash are left out
+	query := db.Model(&Tag{}).
+		Select("tags.*, (SELECT COUNT(*) FROM job_tags JOIN jobs ON jobs.id = job_tags.job_id"+
+			" WHERE job_tags.tag_id = tags.id AND jobs.deleted_at IS NULL) AS job_count").
+		Where("tags.project_id IN (?)", db.Model(&Project{}).Select("id")).
+		Order("tags.name")
+	if projectID != "" {
+		query = query.Where("tags.project_id = ?", projectID)
+	}
+
+	tags := []Tag{}
+	if err := query.Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// WithTags restricts a job query to jobs having any (or, when all is set, every) of the named tags.
+// Names are compared case-insensitively and must be normalized with NormalizeTagNames.
+func WithTags(names []string, all bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(names) == 0 {
+			return db
+		}
+
+		lowered := make([]string, len(names))
+		for i, name := range names {
+			lowered[i] = strings.ToLower(name)
+		}
+
+		tagged := db.Session(&gorm.Session{NewDB: true}).
+			Table("job_tags").
+			Select("job_tags.job_id").
+			Joins("JOIN tags ON tags.id = job_tags.tag_id").
+			Where("LOWER(tags.name) IN ?", lowered)
+		if all {
+			tagged = tagged.Group("job_tags.job_id").Having("COUNT(DISTINCT tags.id) = ?", len(names))
+		}
+
+		return db.Where("jobs.id IN (?)", tagged)
+	}
+}
@@ -0,0 +1,270 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrProjectInTrash is returned when restoring a job whose project is in the trash
+var ErrProjectInTrash = errors.New("the job's project is in the trash, restore the project first")
+
+// Trash lists the jobs and projects that were deleted but not purged yet
+type Trash struct {
+	Jobs     []Job     `json:"jobs"`
+	Projects []Project `json:"projects"`
+}
+
+// ListTrash returns the deleted jobs and projects of the given projects, most recently deleted
+// first. Nil projects stands for every project.
+func ListTrash(db *gorm.DB, projects []string) (*Trash, error) {
+	trash := &Trash{Jobs: []Job{}, Projects: []Project{}}
+	if err := db.Unscoped().Scopes(inProjects("project_id", projects)).Preload("TagRefs").Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&trash.Jobs).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Unscoped().Scopes(inProjects("id", projects)).Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&trash.Projects).Error; err != nil {
+		return nil, err
+	}
+	return trash, nil
+}
+
+// inProjects restricts a query to the records whose column holds one of projects, where nil
+// stands for every project
+func inProjects(column string, projects []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch {
+		case projects == nil:
+			return db
+		case len(projects) == 0:
+			return db.Where("1 = 0")
+		}
+		return db.Where(column+" IN ?", projects)
+	}
+}
+
+// ProjectDeleteMode decides what happens to the jobs of a deleted project
+type ProjectDeleteMode string
+
+const (
+	ProjectDeleteRefuse  ProjectDeleteMode = "refuse"  // Fail when the project has jobs
+	ProjectDeleteCascade ProjectDeleteMode = "cascade" // Move the jobs to the trash as well; their logs are deleted when they are purged
+	ProjectDeleteMove    ProjectDeleteMode = "move"    // Move the jobs to another project
+)
+
+// ErrProjectHasJobs is returned when deleting a project with jobs in refuse mode
+var ErrProjectHasJobs = errors.New("the project still has jobs")
+
+// DeleteProject moves a project to the trash in a single transaction, handling its jobs
+// according to mode. In cascade mode the jobs are moved to the trash marked as deleted with
+// the project, so that restoring the project brings them back with it. Their logs stay until
+// PurgeTrash deletes the jobs, as long as they can still be restored. In move mode
+// targetProjectID receives the jobs. It returns the IDs of the jobs of the project.
+func DeleteProject(db *gorm.DB, project *Project, mode ProjectDeleteMode, targetProjectID string) ([]string, error) {
+	now := time.Now()
+	var jobIDs []string
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Job{}).Where("project_id = ?", project.ID).Pluck("id", &jobIDs).Error; err != nil {
+			return err
+		}
+
+		switch mode {
+		case ProjectDeleteRefuse:
+			if len(jobIDs) > 0 {
+				return ErrProjectHasJobs
+			}
+		case ProjectDeleteCascade:
+			err := tx.Model(&Job{}).Where("project_id = ?", project.ID).
+				Updates(map[string]interface{}{"deleted_at": now, "deleted_with_project": project.ID}).Error
+			if err != nil {
+				return err
+			}
+		case ProjectDeleteMove:
+			if len(jobIDs) > 0 {
+				if err := moveJobs(tx, project.ID, targetProjectID, jobIDs); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("unknown delete mode %q", mode)
+		}
+
+		return tx.Model(project).Update("deleted_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return jobIDs, nil
+}
+
+// moveJobs moves jobs to another project. Their tags are replaced with the tags of the
+// same name in the target project, and their channel subscriptions, which point at
+// channels of the old project, are removed.
+func moveJobs(tx *gorm.DB, fromProjectID, toProjectID string, jobIDs []string) error {
+	var tags []Tag
+	if err := tx.Where("project_id = ? AND id IN (?)", fromProjectID,
+		tx.Table("job_tags").Select("tag_id").Where("job_id IN ?", jobIDs)).Find(&tags).Error; err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		var target Tag
+		err := tx.Where("project_id = ? AND LOWER(name) = LOWER(?)", toProjectID, tag.Name).First(&target).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			target = Tag{ProjectID: toProjectID, Name: tag.Name, Color: tag.Color}
+			err = tx.Create(&target).Error
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Table("job_tags").Where("tag_id = ? AND job_id IN ?", tag.ID, jobIDs).Update("tag_id", target.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Where("job_id IN ?", jobIDs).Delete(&JobChannelSubscription{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&Job{}).Where("id IN ?", jobIDs).Update("project_id", toProjectID).Error
+}
+
+// RestoreJob takes a job of one of the given projects out of the trash, where nil projects stands
+// for every project
+func RestoreJob(db *gorm.DB, id string, projects []string) (*Job, error) {
+	var job Job
+	if err := db.Unscoped().Scopes(inProjects("project_id", projects)).Where("deleted_at IS NOT NULL").First(&job, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := db.Model(&Project{}).Where("id = ?", job.ProjectID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrProjectInTrash
+	}
+
+	if err := db.Unscoped().Model(&job).Updates(map[string]interface{}{"deleted_at": nil, "deleted_with_project": ""}).Error; err != nil {
+		return nil, err
+	}
+	job.DeletedAt = gorm.DeletedAt{}
+	job.DeletedWithProject = ""
+	return &job, nil
+}
+
+// RestoreProject takes one of the given projects out of the trash together with the jobs that
+// were deleted with it, where nil projects stands for every project. It returns the restored jobs.
+func RestoreProject(db *gorm.DB, id string, projects []string) (*Project, []Job, error) {
+	var project Project
+	if err := db.Unscoped().Scopes(inProjects("id", projects)).Where("deleted_at IS NOT NULL").First(&project, "id = ?", id).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var jobs []Job
+	err := db.Transaction(func(tx *gorm.DB) error {
+		deletedWith := tx.Unscoped().Where("project_id = ? AND deleted_with_project = ? AND deleted_at IS NOT NULL", project.ID, project.ID)
+		if err := deletedWith.Session(&gorm.Session{}).Find(&jobs).Error; err != nil {
+			return err
+		}
+		err := deletedWith.Session(&gorm.Session{}).Model(&Job{}).
+			Updates(map[string]interface{}{"deleted_at": nil, "deleted_with_project": ""}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&project).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	project.DeletedAt = gorm.DeletedAt{}
+	for i := range jobs {
+		jobs[i].DeletedAt = gorm.DeletedAt{}
+		jobs[i].DeletedWithProject = ""
+	}
+	return &project, jobs, nil
+}
+
+// PurgeResult counts what PurgeTrash removed
+type PurgeResult struct {
+	Jobs     int `json:"jobs"`
+	Projects int `json:"projects"`
+}
+
+// PurgeTrash permanently deletes jobs and projects that were moved to the trash before
+// the given time, together with everything that belongs to them. Projects that still own
+// jobs in the trash wait until those are purged as well.
+func PurgeTrash(db *gorm.DB, before time.Time) (PurgeResult, error) {
+	var result PurgeResult
+
+	var jobIDs []string
+	if err := db.Unscoped().Model(&Job{}).Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Pluck("id", &jobIDs).Error; err != nil {
+		return result, err
+	}
+	for _, id := range jobIDs {
+		if err := db.Transaction(func(tx *gorm.DB) error { return purgeJob(tx, id) }); err != nil {
+			return result, fmt.Errorf("failed to purge job %s: %v", id, err)
+		}
+		result.Jobs++
+	}
+
+	var projectIDs []string
+	if err := db.Unscoped().Model(&Project{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Where("id NOT IN (?)", db.Unscoped().Model(&Job{}).Select("project_id")).
+		Pluck("id", &projectIDs).Error; err != nil {
+		return result, err
+	}
+	for _, id := range projectIDs {
+		if err := db.Transaction(func(tx *gorm.DB) error { return purgeProject(tx, id) }); err != nil {
+			return result, fmt.Errorf("failed to purge project %s: %v", id, err)
+		}
+		result.Projects++
+	}
+
+	return result, nil
+}
+
+// purgeJob deletes a job and the rows referencing it
+func purgeJob(tx *gorm.DB, id string) error {
+	dependents := []interface{}{
+		&JobLog{},
+		&JobRunStats{},
+		&NotificationDelivery{},
+		&JobChannelSubscription{},
+		&Alert{},
+		&AlertRule{},
+		&JobRevision{},
+		&SearchTerm{},
+	}
+	for _, model := range dependents {
+		if err := tx.Where("job_id = ?", id).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Table("job_tags").Where("job_id = ?", id).Delete(nil).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&Job{}, "id = ?", id).Error
+}
+
+// purgeProject deletes a project without jobs and the rows referencing it
+func purgeProject(tx *gorm.DB, id string) error {
+	if err := tx.Where("channel_id IN (?)", tx.Model(&NotificationChannel{}).Select("id").Where("project_id = ?", id)).
+		Delete(&JobChannelSubscription{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", id).Delete(&NotificationChannel{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Table("job_tags").Where("tag_id IN (?)", tx.Model(&Tag{}).Select("id").Where("project_id = ?", id)).Delete(nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_id = ?", id).Delete(&Tag{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&Project{}, "id = ?", id).Error
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestRestoreProjectBringsBackJobsDeletedWithIt(t *testing.T) {
+	db := statsDB(t)
+	if err := db.AutoMigrate(&Tag{}, &NotificationChannel{}, &JobChannelSubscription{}, &NotificationDelivery{}, &Alert{}, &AlertRule{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	project := Project{Name: "Backups"}
+	if err := db.Create(&project).Error; err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	cascaded := Job{ProjectID: project.ID, Name: "Nightly", Command: "true", Schedule: "0 0 2 * * *"}
+	alone := Job{ProjectID: project.ID, Name: "Weekly", Command: "true", Schedule: "0 0 3 * * 0"}
+	for _, job := range []*Job{&cascaded, &alone} {
+		if err := db.Create(job).Error; err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+	finishedRun(t, db, cascaded.ID, JobStatusSuccess, 1, time.Now())
+
+	// A job deleted on its own stays in the trash when the project is restored
+	if err := db.Delete(&alone).Error; err != nil {
+		t.Fatalf("failed to delete job: %v", err)
+	}
+	if _, err := DeleteProject(db, &project, ProjectDeleteCascade, ""); err != nil {
+		t.Fatalf("DeleteProject failed: %v", err)
+	}
+
+	// Logs of jobs in the trash are kept until they are purged
+	var logs int64
+	db.Model(&JobLog{}).Where("job_id = ?", cascaded.ID).Count(&logs)
+	if logs != 1 {
+		t.Errorf("%d logs after the cascading delete, want 1", logs)
+	}
+
+	// Drivers that round timestamps store the jobs with a deletion time other than the project's
+	db.Unscoped().Model(&Job{}).Where("id = ?", cascaded.ID).Update("deleted_at", time.Now().Add(-time.Second).Truncate(time.Second))
+
+	_, jobs, err := RestoreProject(db, project.ID, nil)
+	if err != nil {
+		t.Fatalf("RestoreProject failed: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != cascaded.ID {
+		t.Fatalf("restored jobs = %+v, want only %s", jobs, cascaded.ID)
+	}
+	var restored Job
+	if err := db.First(&restored, "id = ?", cascaded.ID).Error; err != nil || restored.DeletedWithProject != "" {
+		t.Errorf("restored job: %+v, err %v", restored, err)
+	}
+	if err := db.First(&Job{}, "id = ?", alone.ID).Error; err == nil {
+		t.Error("the job deleted on its own was restored with the project")
+	}
+
+	// Purging deletes the logs with the job
+	if _, err := DeleteProject(db, &project, ProjectDeleteCascade, ""); err != nil {
+		t.Fatalf("DeleteProject failed: %v", err)
+	}
+	result, err := PurgeTrash(db, time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("PurgeTrash failed: %v", err)
+	}
+	if result.Jobs != 2 || result.Projects != 1 {
+		t.Errorf("purged %+v, want 2 jobs and 1 project", result)
+	}
+	db.Model(&JobLog{}).Where("job_id = ?", cascaded.ID).Count(&logs)
+	if logs != 0 {
+		t.Errorf("%d logs left after purging", logs)
+	}
+}
+
+func TestTrashOnlyShowsGivenProjects(t *testing.T) {
+	db := statsDB(t)
+	if err := db.AutoMigrate(&Tag{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	billing, reports := Project{Name: "Billing"}, Project{Name: "Reports"}
+	for _, project := range []*Project{&billing, &reports} {
+		if err := db.Create(project).Error; err != nil {
+			t.Fatalf("failed to create project: %v", err)
+		}
+	}
+	invoices := Job{ProjectID: billing.ID, Name: "invoices", Command: "true", Schedule: "0 0 2 * * *"}
+	sales := Job{ProjectID: reports.ID, Name: "sales", Command: "true", Schedule: "0 0 2 * * *"}
+	for _, job := range []*Job{&invoices, &sales} {
+		if err := db.Create(job).Error; err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+		if err := db.Delete(job).Error; err != nil {
+			t.Fatalf("failed to delete job: %v", err)
+		}
+	}
+
+	trash, err := ListTrash(db, []string{billing.ID})
+	if err != nil {
+		t.Fatalf("ListTrash failed: %v", err)
+	}
+	if len(trash.Jobs) != 1 || trash.Jobs[0].ID != invoices.ID {
+		t.Errorf("trash of Billing = %+v, want only invoices", trash.Jobs)
+	}
+	if trash, _ := ListTrash(db, []string{}); len(trash.Jobs) != 0 {
+		t.Errorf("trash of no project = %+v", trash.Jobs)
+	}
+	if _, err := RestoreJob(db, sales.ID, []string{billing.ID}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("RestoreJob of a job of another project: err = %v, want not found", err)
+	}
+	if _, err := RestoreJob(db, invoices.ID, []string{billing.ID}); err != nil {
+		t.Errorf("RestoreJob failed: %v", err)
+	}
+}
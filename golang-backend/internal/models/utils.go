@@ -5,9 +5,18 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrVersionConflict is returned when a record was changed by someone else since it was read
+var ErrVersionConflict = errors.New("the record was changed by someone else")
+
 // generateUUID generates a pseudo-UUID
 func generateUUID() string {
 	bytes := make([]byte, 16)
@@ -19,6 +28,21 @@ func generateUUID() string {
 	return hex.EncodeToString(bytes)
 }
 
+// NewID generates an identifier in the same format as the primary keys
+func NewID() string {
+	return generateUUID()
+}
+
+// generateSecret generates a random hex secret for signing payloads
+func generateSecret() string {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return generateUUID() + generateUUID()
+	}
+	
+	return hex.EncodeToString(bytes)
+}
+
 // MarshalPermissions converts a string slice to JSON string
 func MarshalPermissions(permissions []string) (string, error) {
 	if len(permissions) == 0 {
@@ -47,3 +71,60 @@ func UnmarshalPermissions(data string) ([]string, error) {
 	
 	return permissions, nil
 }
+
+// ProjectPermissionPrefix marks a permission restricting a role to one project, as in "project:<id>"
+const ProjectPermissionPrefix = "project:"
+
+// ProjectScope returns the projects a role with the given permissions is restricted to,
+// or nil when the role has no project permissions and may access every project
+func ProjectScope(permissions []string) []string {
+	var projects []string
+	for _, permission := range permissions {
+		if strings.HasPrefix(permission, ProjectPermissionPrefix) {
+			projects = append(projects, strings.TrimPrefix(permission, ProjectPermissionPrefix))
+		}
+	}
+	
+	return projects
+}
+
+// encodeJSONColumn serializes a value stored in a JSON text column, using "" for empty values
+func encodeJSONColumn(value interface{}, empty bool) (string, error) {
+	if empty {
+		return "", nil
+	}
+	
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	
+	return string(data), nil
+}
+
+// SaveVersioned stores the given columns of a record, but only if its version in the database is still
+// the one it was read with. Other columns, such as those maintained by runs, keep their stored values.
+// On success the version is incremented; otherwise ErrVersionConflict is returned.
+func SaveVersioned(db *gorm.DB, value interface{}, version *int64, columns []string) error {
+	read := *version
+	*version = read + 1
+	columns = append(append([]string{}, columns...), "version", "updated_at")
+	result := db.Model(value).Where("version = ?", read).Select(columns).Omit(clause.Associations).Updates(value)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		*version = read
+	}
+	return result.Error
+}
+
+// containsString reports whether value is one of values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
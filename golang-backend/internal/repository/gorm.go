@@ -0,0 +1,676 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"crontab/internal/models"
+)
+
+// NewGormStore returns repositories backed by a database
+func NewGormStore(db *gorm.DB) Store {
+	return Store{
+		Jobs:       &gormJobs{db: db},
+		Projects:   &gormProjects{db: db},
+		Runs:       &gormRuns{db: db},
+		Deliveries: &gormDeliveries{db: db},
+		Audit:      &gormAudit{db: db},
+		Trash:      &gormTrash{db: db},
+		Search:     &gormSearch{db: db},
+	}
+}
+
+// notFound maps gorm's missing record error to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+type gormJobs struct {
+	db *gorm.DB
+}
+
+func (r *gormJobs) Get(ctx context.Context, id string) (*models.Job, error) {
+	var job models.Job
+	if err := r.db.WithContext(ctx).Preload("TagRefs").First(&job, "id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &job, nil
+}
+
+func (r *gormJobs) List(ctx context.Context, filter JobFilter, page Page) ([]models.Job, PageInfo, error) {
+	order, err := jobSorts.resolve(page, "createdAt")
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	query := r.db.WithContext(ctx).Model(&models.Job{})
+	if filter.ProjectID != "" {
+		query = query.Where("jobs.project_id = ?", filter.ProjectID)
+	}
+	query = whereIn(query, "jobs.project_id", filter.Projects)
+	if len(filter.Statuses) > 0 {
+		query = query.Where("jobs.status IN ?", filter.Statuses)
+	}
+	if filter.Active {
+		query = query.Where("jobs.status <> ?", models.JobStatusPaused)
+	}
+	if filter.NamePrefix != "" {
+		query = query.Where("LOWER(jobs.name) LIKE ? ESCAPE '!'", likePrefix(filter.NamePrefix))
+	}
+	query = whereTimeRange(query, "jobs.last_run", filter.LastRunFrom, filter.LastRunTo)
+	query = whereTimeRange(query, "jobs.next_run", filter.NextRunFrom, filter.NextRunTo)
+	query = query.Scopes(models.WithTags(filter.Tags, filter.MatchAllTags))
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	var jobs []models.Job
+	if err := order.apply(query.Preload("TagRefs"), "jobs.id", page.Limit).Find(&jobs).Error; err != nil {
+		return nil, PageInfo{}, err
+	}
+	jobs, info := order.finish(jobs, jobID, page.Limit, total)
+	return jobs, info, nil
+}
+
+// likePrefix returns a LIKE pattern matching values that start with prefix, ignoring case.
+// Wildcards in prefix are escaped with "!".
+func likePrefix(prefix string) string {
+	escaped := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(strings.ToLower(prefix))
+	return escaped + "%"
+}
+
+// whereTimeRange restricts a query to rows whose column lies in [from, to); zero bounds are open
+func whereTimeRange(query *gorm.DB, column string, from, to time.Time) *gorm.DB {
+	if !from.IsZero() {
+		query = query.Where(column+" >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where(column+" < ?", to)
+	}
+	return query
+}
+
+func (r *gormJobs) Create(ctx context.Context, job *models.Job, author string) error {
+	job.TagRefs = nil
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		if err := models.SetJobTags(tx, job, job.Tags); err != nil {
+			return err
+		}
+		if err := models.IndexJob(tx, job); err != nil {
+			return err
+		}
+		_, err := models.RecordJobRevision(tx, job, author, "Job created")
+		return err
+	})
+}
+
+func (r *gormJobs) Save(ctx context.Context, job *models.Job, author, reason string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := models.SaveVersioned(tx, job, &job.Version, job.EditableColumns()); err != nil {
+			return err
+		}
+		if err := models.SetJobTags(tx, job, job.Tags); err != nil {
+			return err
+		}
+		if err := models.IndexJob(tx, job); err != nil {
+			return err
+		}
+		_, err := models.RecordJobRevision(tx, job, author, reason)
+		return err
+	})
+}
+
+func (r *gormJobs) Delete(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&models.Job{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return result.Error
+}
+
+func (r *gormJobs) Revisions(ctx context.Context, jobID string) ([]models.JobRevision, error) {
+	revisions := []models.JobRevision{}
+	err := r.db.WithContext(ctx).Where("job_id = ?", jobID).Order("number DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+func (r *gormJobs) Revision(ctx context.Context, jobID string, number int) (*models.JobRevision, error) {
+	var revision models.JobRevision
+	if err := r.db.WithContext(ctx).Where("job_id = ? AND number = ?", jobID, number).First(&revision).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &revision, nil
+}
+
+type gormProjects struct {
+	db *gorm.DB
+}
+
+func (r *gormProjects) Get(ctx context.Context, id string) (*models.Project, error) {
+	var project models.Project
+	if err := r.db.WithContext(ctx).First(&project, "id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &project, nil
+}
+
+func (r *gormProjects) List(ctx context.Context, filter ProjectFilter, page Page) ([]models.Project, PageInfo, error) {
+	order, err := projectSorts.resolve(page, "createdAt")
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	query := r.db.WithContext(ctx).Model(&models.Project{})
+	if filter.NamePrefix != "" {
+		query = query.Where("LOWER(projects.name) LIKE ? ESCAPE '!'", likePrefix(filter.NamePrefix))
+	}
+	query = whereIn(query, "projects.id", filter.IDs)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	var projects []models.Project
+	if err := order.apply(query, "projects.id", page.Limit).Find(&projects).Error; err != nil {
+		return nil, PageInfo{}, err
+	}
+	projects, info := order.finish(projects, projectID, page.Limit, total)
+	return projects, info, nil
+}
+
+func (r *gormProjects) Create(ctx context.Context, project *models.Project) error {
+	return r.db.WithContext(ctx).Omit("Jobs").Create(project).Error
+}
+
+func (r *gormProjects) Save(ctx context.Context, project *models.Project) error {
+	return models.SaveVersioned(r.db.WithContext(ctx), project, &project.Version, project.EditableColumns())
+}
+
+func (r *gormProjects) Delete(ctx context.Context, project *models.Project, mode models.ProjectDeleteMode, targetProjectID string) ([]string, error) {
+	return models.DeleteProject(r.db.WithContext(ctx), project, mode, targetProjectID)
+}
+
+type gormRuns struct {
+	db *gorm.DB
+}
+
+func (r *gormRuns) List(ctx context.Context, filter RunFilter, page Page) ([]models.JobLog, PageInfo, error) {
+	order, err := runSorts.resolve(page, "-startTime")
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	query := whereRuns(r.db.WithContext(ctx).Model(&models.JobLog{}), filter)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	var runs []models.JobLog
+	if err := order.apply(query, "job_logs.id", page.Limit).Find(&runs).Error; err != nil {
+		return nil, PageInfo{}, err
+	}
+	runs, info := order.finish(runs, runID, page.Limit, total)
+	return runs, info, nil
+}
+
+func (r *gormRuns) ListWithJobs(ctx context.Context, filter RunFilter, page Page) ([]RunWithJob, PageInfo, error) {
+	order, err := runWithJobSorts.resolve(page, "-startTime")
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	query := r.withJobs(ctx, filter)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	var runs []RunWithJob
+	if err := order.apply(query.Select(runWithJobColumns), "job_logs.id", page.Limit).Find(&runs).Error; err != nil {
+		return nil, PageInfo{}, err
+	}
+	runs, info := order.finish(runs, runWithJobID, page.Limit, total)
+	return runs, info, nil
+}
+
+func (r *gormRuns) EachWithJob(ctx context.Context, filter RunFilter, sort string, fn func(*RunWithJob) error) error {
+	order, err := runWithJobSorts.resolve(Page{Sort: sort}, "-startTime")
+	if err != nil {
+		return err
+	}
+
+	query := order.apply(r.withJobs(ctx, filter).Select(runWithJobColumns), "job_logs.id", 0)
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var run RunWithJob
+		if err := r.db.ScanRows(rows, &run); err != nil {
+			return err
+		}
+		if err := fn(&run); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// runWithJobColumns are the columns of a RunWithJob in a query built by withJobs
+const runWithJobColumns = "job_logs.*, jobs.name AS job_name, jobs.project_id AS project_id, projects.name AS project_name"
+
+// withJobs selects the runs of jobs outside the trash, joined with their jobs and projects
+func (r *gormRuns) withJobs(ctx context.Context, filter RunFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).
+		Table("job_logs").
+		Joins("JOIN jobs ON jobs.id = job_logs.job_id AND jobs.deleted_at IS NULL").
+		Joins("LEFT JOIN projects ON projects.id = jobs.project_id")
+	return whereRuns(query, filter)
+}
+
+// whereRuns restricts a query on job_logs to the runs matching filter
+func whereRuns(query *gorm.DB, filter RunFilter) *gorm.DB {
+	if filter.JobID != "" {
+		query = query.Where("job_logs.job_id = ?", filter.JobID)
+	}
+	if filter.ProjectID != "" {
+		query = query.Where("job_logs.job_id IN (?)", query.Session(&gorm.Session{NewDB: true}).
+			Model(&models.Job{}).Select("id").Where("project_id = ?", filter.ProjectID))
+	}
+	if filter.Projects != nil {
+		query = query.Where("job_logs.job_id IN (?)", whereIn(query.Session(&gorm.Session{NewDB: true}).
+			Model(&models.Job{}).Select("id"), "project_id", filter.Projects))
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("job_logs.status IN ?", filter.Statuses)
+	}
+	if len(filter.Triggers) > 0 {
+		// trigger is a reserved word in several databases
+		query = query.Where(clause.IN{Column: clause.Column{Table: "job_logs", Name: "trigger"}, Values: runTriggers(filter.Triggers)})
+	}
+	if filter.MinDuration > 0 {
+		query = query.Where("job_logs.duration >= ?", filter.MinDuration)
+	}
+	if filter.MaxDuration > 0 {
+		query = query.Where("job_logs.duration <= ?", filter.MaxDuration)
+	}
+	return whereTimeRange(query, "job_logs.start_time", filter.From, filter.To)
+}
+
+// whereIn restricts a query to the records whose column holds one of values, where nil stands
+// for every value
+func whereIn(query *gorm.DB, column string, values []string) *gorm.DB {
+	switch {
+	case values == nil:
+		return query
+	case len(values) == 0:
+		return query.Where("1 = 0")
+	}
+	return query.Where(column+" IN ?", values)
+}
+
+// runTriggers converts triggers for an IN condition
+func runTriggers(triggers []models.RunTrigger) []interface{} {
+	values := make([]interface{}, len(triggers))
+	for i, trigger := range triggers {
+		values[i] = trigger
+	}
+	return values
+}
+
+func (r *gormRuns) Start(ctx context.Context, run *models.JobLog) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Mark the job as running, keeping the status of jobs paused meanwhile. Runs leave the
+		// version alone: edits don't store the status and counters, see models.Job.EditableColumns.
+		err := tx.Model(&models.Job{ID: run.JobID}).Updates(map[string]interface{}{
+			"last_run": run.StartTime,
+			"status":   unlessPaused(models.JobStatusRunning),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(run).Error
+	})
+}
+
+func (r *gormRuns) Finish(ctx context.Context, run *models.JobLog) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"success_count":        gorm.Expr("success_count + 1"),
+			"consecutive_failures": 0,
+			"status":               unlessPaused(models.JobStatusIdle),
+		}
+		if run.Status != models.JobStatusSuccess {
+			delete(updates, "success_count")
+			updates["fail_count"] = gorm.Expr("fail_count + 1")
+			updates["consecutive_failures"] = gorm.Expr("consecutive_failures + 1")
+			updates["status"] = unlessPaused(run.Status)
+		}
+		if err := tx.Model(&models.Job{ID: run.JobID}).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Save(run).Error; err != nil {
+			return err
+		}
+		return models.IndexRun(tx, run)
+	})
+}
+
+// unlessPaused sets the status of a job in an update, except for jobs paused meanwhile
+func unlessPaused(status models.JobStatus) clause.Expr {
+	return gorm.Expr("CASE WHEN status = ? THEN status ELSE ? END", models.JobStatusPaused, status)
+}
+
+func (r *gormRuns) Create(ctx context.Context, run *models.JobLog) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Create(run).Error; err != nil {
+		return err
+	}
+	return models.IndexRun(db, run)
+}
+
+func (r *gormRuns) JobStats(ctx context.Context, jobID string, from, to time.Time) (*models.JobStats, error) {
+	return models.ComputeJobStats(r.db.WithContext(ctx), jobID, from, to)
+}
+
+func (r *gormRuns) ProjectStats(ctx context.Context, projectID string, from, to time.Time) (*models.ProjectStats, error) {
+	return models.ComputeProjectStats(r.db.WithContext(ctx), projectID, from, to)
+}
+
+type gormDeliveries struct {
+	db *gorm.DB
+}
+
+func (r *gormDeliveries) Get(ctx context.Context, id string) (*models.NotificationDelivery, error) {
+	var delivery models.NotificationDelivery
+	if err := r.db.WithContext(ctx).First(&delivery, "id = ?", id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &delivery, nil
+}
+
+func (r *gormDeliveries) List(ctx context.Context, filter DeliveryFilter, page Page) ([]models.NotificationDelivery, PageInfo, error) {
+	order, err := deliverySorts.resolve(page, "-createdAt")
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	query := r.db.WithContext(ctx).Model(&models.NotificationDelivery{})
+	if filter.JobID != "" {
+		query = query.Where("job_id = ?", filter.JobID)
+	}
+	if filter.Channel != "" {
+		query = query.Where("channel = ?", filter.Channel)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	var deliveries []models.NotificationDelivery
+	if err := order.apply(query, "notification_deliveries.id", page.Limit).Find(&deliveries).Error; err != nil {
+		return nil, PageInfo{}, err
+	}
+	deliveries, info := order.finish(deliveries, deliveryID, page.Limit, total)
+	return deliveries, info, nil
+}
+
+func (r *gormDeliveries) Create(ctx context.Context, delivery *models.NotificationDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+type gormAudit struct {
+	db *gorm.DB
+}
+
+func (r *gormAudit) Append(ctx context.Context, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *gormAudit) List(ctx context.Context, filter models.AuditFilter, limit int) ([]models.AuditEntry, error) {
+	entries := []models.AuditEntry{}
+	err := filter.Apply(r.db.WithContext(ctx)).Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+func (r *gormAudit) Each(ctx context.Context, filter models.AuditFilter, fn func(*models.AuditEntry) error) error {
+	db := r.db.WithContext(ctx)
+	rows, err := filter.Apply(db).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.AuditEntry
+		if err := db.ScanRows(rows, &entry); err != nil {
+			return err
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+type gormTrash struct {
+	db *gorm.DB
+}
+
+func (r *gormTrash) List(ctx context.Context, projects []string) (*models.Trash, error) {
+	return models.ListTrash(r.db.WithContext(ctx), projects)
+}
+
+func (r *gormTrash) RestoreJob(ctx context.Context, id string, projects []string) (*models.Job, error) {
+	job, err := models.RestoreJob(r.db.WithContext(ctx), id, projects)
+	return job, notFound(err)
+}
+
+func (r *gormTrash) RestoreProject(ctx context.Context, id string, projects []string) (*models.Project, []models.Job, error) {
+	project, jobs, err := models.RestoreProject(r.db.WithContext(ctx), id, projects)
+	return project, jobs, notFound(err)
+}
+
+type gormSearch struct {
+	db *gorm.DB
+}
+
+func (r *gormSearch) Search(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	db := r.db.WithContext(ctx)
+
+	// Rare words weigh more than common ones
+	var frequencies []struct {
+		Term    string
+		DocType models.SearchDocType
+		Docs    int64
+	}
+	err := db.Model(&models.SearchTerm{}).
+		Select("term, doc_type, COUNT(DISTINCT doc_id) AS docs").
+		Where("term IN ? AND doc_type IN ?", query.Words, query.Types).
+		Group("term, doc_type").
+		Scan(&frequencies).Error
+	if err != nil || len(frequencies) == 0 {
+		return []SearchHit{}, err
+	}
+	totals := make(map[models.SearchDocType]int64)
+	for _, docType := range query.Types {
+		var model interface{} = &models.Job{}
+		if docType == models.SearchDocRun {
+			model = &models.JobLog{}
+		}
+		var total int64
+		if err := db.Model(model).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		totals[docType] = total
+	}
+
+	// The weights are computed here, so they are written into the query as numbers
+	weights := "CASE field"
+	for _, field := range searchFields() {
+		weights += fmt.Sprintf(" WHEN '%s' THEN %g", field, models.SearchFieldWeights[field])
+	}
+	weights += " ELSE 0 END"
+	rarity := "CASE"
+	var args []interface{}
+	for _, frequency := range frequencies {
+		rarity += " WHEN doc_type = ? AND term = ? THEN " + strconv.FormatFloat(searchIDF(totals[frequency.DocType], frequency.Docs), 'f', 6, 64)
+		args = append(args, frequency.DocType, frequency.Term)
+	}
+	rarity += " ELSE 0 END"
+	occurrences := fmt.Sprintf("CASE WHEN occurrences > %[1]d THEN %[1]d ELSE occurrences END", models.MaxSearchOccurrences)
+
+	visibleJobs := db.Model(&models.Job{}).Select("id")
+	if query.ProjectID != "" {
+		visibleJobs = visibleJobs.Where("project_id = ?", query.ProjectID)
+	}
+	if query.Projects != nil && len(query.Projects) == 0 {
+		return []SearchHit{}, nil
+	}
+	visibleJobs = whereIn(visibleJobs, "project_id", query.Projects)
+
+	var ranked []struct {
+		DocType models.SearchDocType
+		DocID   string
+		Score   float64
+	}
+	err = db.Model(&models.SearchTerm{}).
+		Select("doc_type, doc_id, SUM(("+occurrences+") * ("+weights+") * ("+rarity+")) AS score", args...).
+		Where("term IN ? AND doc_type IN ?", query.Words, query.Types).
+		Where("job_id IN (?)", visibleJobs).
+		Group("doc_type, doc_id").
+		Having("COUNT(DISTINCT term) = ?", len(query.Words)).
+		Order("score DESC, doc_id").
+		Limit(query.Limit).
+		Scan(&ranked).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var jobIDs, runIDs []string
+	for _, hit := range ranked {
+		if hit.DocType == models.SearchDocJob {
+			jobIDs = append(jobIDs, hit.DocID)
+		} else {
+			runIDs = append(runIDs, hit.DocID)
+		}
+	}
+	found, err := r.load(ctx, jobIDs, runIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]SearchHit, 0, len(ranked))
+	for _, rank := range ranked {
+		// Records changed since they were ranked may be gone
+		if hit, ok := found[searchKey{rank.DocType, rank.DocID}]; ok {
+			hit.Score = rank.Score
+			hits = append(hits, hit)
+		}
+	}
+	return hits, nil
+}
+
+// searchKey identifies a job or run found by a search
+type searchKey struct {
+	docType models.SearchDocType
+	id      string
+}
+
+// load reads the jobs and runs found by a search
+func (r *gormSearch) load(ctx context.Context, jobIDs, runIDs []string) (map[searchKey]SearchHit, error) {
+	db := r.db.WithContext(ctx)
+	found := make(map[searchKey]SearchHit, len(jobIDs)+len(runIDs))
+
+	if len(jobIDs) > 0 {
+		var jobs []models.Job
+		if err := db.Preload("TagRefs").Find(&jobs, "id IN ?", jobIDs).Error; err != nil {
+			return nil, err
+		}
+		projectNames := make(map[string]string)
+		var projects []models.Project
+		var projectIDs []string
+		for _, job := range jobs {
+			projectIDs = append(projectIDs, job.ProjectID)
+		}
+		if err := db.Select("id, name").Find(&projects, "id IN ?", projectIDs).Error; err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			projectNames[project.ID] = project.Name
+		}
+		for i := range jobs {
+			job := &jobs[i]
+			found[searchKey{models.SearchDocJob, job.ID}] = SearchHit{
+				Type:        models.SearchDocJob,
+				ID:          job.ID,
+				JobID:       job.ID,
+				JobName:     job.Name,
+				ProjectID:   job.ProjectID,
+				ProjectName: projectNames[job.ProjectID],
+				Status:      job.Status,
+				Texts:       job.SearchTexts(),
+			}
+		}
+	}
+
+	if len(runIDs) > 0 {
+		var runs []RunWithJob
+		if err := (&gormRuns{db: r.db}).withJobs(ctx, RunFilter{}).Select(runWithJobColumns).Where("job_logs.id IN ?", runIDs).Find(&runs).Error; err != nil {
+			return nil, err
+		}
+		for i := range runs {
+			run := &runs[i]
+			startTime := run.StartTime
+			found[searchKey{models.SearchDocRun, run.ID}] = SearchHit{
+				Type:        models.SearchDocRun,
+				ID:          run.ID,
+				JobID:       run.JobID,
+				JobName:     run.JobName,
+				ProjectID:   run.ProjectID,
+				ProjectName: run.ProjectName,
+				Status:      run.Status,
+				StartTime:   &startTime,
+				Texts:       run.SearchTexts(),
+			}
+		}
+	}
+	return found, nil
+}
+
+// searchFields returns the fields of models.SearchFieldWeights in a fixed order
+func searchFields() []string {
+	fields := make([]string, 0, len(models.SearchFieldWeights))
+	for field := range models.SearchFieldWeights {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,888 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"crontab/internal/models"
+)
+
+// NewMemoryStore returns repositories that keep everything in memory, for tests and tools that
+// must not depend on a database. Records are copied in and out, so callers never share them.
+func NewMemoryStore() Store {
+	m := &memory{
+		jobs:      make(map[string]*models.Job),
+		revisions: make(map[string][]models.JobRevision),
+		projects:  make(map[string]*models.Project),
+		runs:      make(map[string][]models.JobLog),
+	}
+	return Store{
+		Jobs:       &memoryJobs{m},
+		Projects:   &memoryProjects{m},
+		Runs:       &memoryRuns{m},
+		Deliveries: &memoryDeliveries{m},
+		Audit:      &memoryAudit{m},
+		Trash:      &memoryTrash{m},
+		Search:     &memorySearch{m},
+	}
+}
+
+// memory is the state shared by the in-memory repositories
+type memory struct {
+	mu         sync.Mutex
+	jobs       map[string]*models.Job
+	revisions  map[string][]models.JobRevision // By job ID, oldest first
+	projects   map[string]*models.Project
+	runs       map[string][]models.JobLog // By job ID, oldest first
+	deliveries []models.NotificationDelivery
+	audit      []models.AuditEntry
+}
+
+// cloneJob copies a job including its tags, headers and notification settings
+func cloneJob(job *models.Job) *models.Job {
+	clone := *job
+	clone.Tags = append([]string{}, job.Tags...)
+	clone.TagRefs = nil
+	clone.Logs = nil
+	clone.Headers = nil
+	clone.EmailNotifications = nil
+	clone.Webhooks = nil
+	if job.PausedAt != nil {
+		pausedAt := *job.PausedAt
+		clone.PausedAt = &pausedAt
+	}
+	// The JSON columns hold the encoded settings, see Job.BeforeSave
+	clone.AfterFind(nil)
+	return &clone
+}
+
+type memoryJobs struct {
+	m *memory
+}
+
+func (r *memoryJobs) Get(ctx context.Context, id string) (*models.Job, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	job, ok := r.m.jobs[id]
+	if !ok || job.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	return cloneJob(job), nil
+}
+
+func (r *memoryJobs) List(ctx context.Context, filter JobFilter, page Page) ([]models.Job, PageInfo, error) {
+	order, err := jobSorts.resolve(page, "createdAt")
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	jobs := []models.Job{}
+	for _, job := range r.m.jobs {
+		if job.DeletedAt.Valid ||
+			(filter.ProjectID != "" && job.ProjectID != filter.ProjectID) ||
+			!inProjects(filter.Projects, job.ProjectID) ||
+			(len(filter.Statuses) > 0 && !hasStatus(filter.Statuses, job.Status)) ||
+			(filter.Active && job.Status == models.JobStatusPaused) ||
+			!strings.HasPrefix(strings.ToLower(job.Name), strings.ToLower(filter.NamePrefix)) ||
+			!inTimeRange(job.LastRun, filter.LastRunFrom, filter.LastRunTo) ||
+			!inTimeRange(job.NextRun, filter.NextRunFrom, filter.NextRunTo) ||
+			!hasTags(job.Tags, filter.Tags, filter.MatchAllTags) {
+			continue
+		}
+		jobs = append(jobs, *cloneJob(job))
+	}
+	jobs, info := order.pageOf(jobs, jobID, page.Limit)
+	return jobs, info, nil
+}
+
+// hasStatus reports whether status is one of statuses
+func hasStatus(statuses []models.JobStatus, status models.JobStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// inTimeRange reports whether t lies in [from, to); zero bounds are open. Unset times only match open ranges.
+func inTimeRange(t, from, to time.Time) bool {
+	if from.IsZero() && to.IsZero() {
+		return true
+	}
+	return !t.IsZero() && (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
+
+// hasTags reports whether a job with the given tags matches a tag filter
+func hasTags(jobTags, wanted []string, all bool) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	matched := 0
+	for _, w := range wanted {
+		for _, t := range jobTags {
+			if strings.EqualFold(t, w) {
+				matched++
+				break
+			}
+		}
+	}
+	if all {
+		return matched == len(wanted)
+	}
+	return matched > 0
+}
+
+func (r *memoryJobs) Create(ctx context.Context, job *models.Job, author string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	job.BeforeCreate(nil)
+	if _, exists := r.m.jobs[job.ID]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	if err := job.BeforeSave(nil); err != nil {
+		return err
+	}
+	now := time.Now()
+	job.CreatedAt, job.UpdatedAt = now, now
+	if job.Version == 0 {
+		job.Version = 1
+	}
+	job.Tags = append([]string{}, job.Tags...)
+	r.m.recordRevision(job, author, "Job created")
+	r.m.jobs[job.ID] = cloneJob(job)
+	return nil
+}
+
+func (r *memoryJobs) Save(ctx context.Context, job *models.Job, author, reason string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	stored, ok := r.m.jobs[job.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	if stored.Version != job.Version {
+		return models.ErrVersionConflict
+	}
+	if err := job.BeforeSave(nil); err != nil {
+		return err
+	}
+	job.KeepServerState(stored)
+	job.AfterSave(nil)
+	job.Version++
+	job.UpdatedAt = time.Now()
+	r.m.recordRevision(job, author, reason)
+	r.m.jobs[job.ID] = cloneJob(job)
+	return nil
+}
+
+// recordRevision mirrors models.RecordJobRevision: a revision is only added when the configuration changed
+func (m *memory) recordRevision(job *models.Job, author, reason string) {
+	spec := job.Spec()
+	revisions := m.revisions[job.ID]
+	previous := models.JobSpec{}
+	if len(revisions) > 0 {
+		latest := revisions[len(revisions)-1]
+		previous = latest.Spec
+		if len(models.DiffJobSpecs(previous, spec)) == 0 {
+			job.RevisionID = latest.ID
+			return
+		}
+	}
+
+	revision := models.JobRevision{
+		ID:        models.NewID(),
+		JobID:     job.ID,
+		Number:    len(revisions) + 1,
+		Author:    author,
+		Reason:    reason,
+		Spec:      spec,
+		CreatedAt: time.Now(),
+	}
+	for _, change := range models.DiffJobSpecs(previous, spec) {
+		revision.ChangedFields = append(revision.ChangedFields, change.Field)
+	}
+	m.revisions[job.ID] = append(revisions, revision)
+	job.RevisionID = revision.ID
+}
+
+func (r *memoryJobs) Delete(ctx context.Context, id string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	job, ok := r.m.jobs[id]
+	if !ok || job.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	job.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (r *memoryJobs) Revisions(ctx context.Context, jobID string) ([]models.JobRevision, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	stored := r.m.revisions[jobID]
+	revisions := make([]models.JobRevision, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		revisions = append(revisions, stored[i])
+	}
+	return revisions, nil
+}
+
+func (r *memoryJobs) Revision(ctx context.Context, jobID string, number int) (*models.JobRevision, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, revision := range r.m.revisions[jobID] {
+		if revision.Number == number {
+			return &revision, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+type memoryProjects struct {
+	m *memory
+}
+
+func (r *memoryProjects) Get(ctx context.Context, id string) (*models.Project, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	project, ok := r.m.projects[id]
+	if !ok || project.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	clone := *project
+	return &clone, nil
+}
+
+func (r *memoryProjects) List(ctx context.Context, filter ProjectFilter, page Page) ([]models.Project, PageInfo, error) {
+	order, err := projectSorts.resolve(page, "createdAt")
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	projects := []models.Project{}
+	for _, project := range r.m.projects {
+		if !project.DeletedAt.Valid && strings.HasPrefix(strings.ToLower(project.Name), strings.ToLower(filter.NamePrefix)) &&
+			inProjects(filter.IDs, project.ID) {
+			projects = append(projects, *project)
+		}
+	}
+	projects, info := order.pageOf(projects, projectID, page.Limit)
+	return projects, info, nil
+}
+
+func (r *memoryProjects) Create(ctx context.Context, project *models.Project) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	project.BeforeCreate(nil)
+	if _, exists := r.m.projects[project.ID]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	now := time.Now()
+	project.CreatedAt, project.UpdatedAt = now, now
+	if project.Version == 0 {
+		project.Version = 1
+	}
+	clone := *project
+	clone.Jobs = nil
+	r.m.projects[project.ID] = &clone
+	return nil
+}
+
+func (r *memoryProjects) Save(ctx context.Context, project *models.Project) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	stored, ok := r.m.projects[project.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	if stored.Version != project.Version {
+		return models.ErrVersionConflict
+	}
+	project.CreatedAt = stored.CreatedAt
+	project.Version++
+	project.UpdatedAt = time.Now()
+	clone := *project
+	clone.Jobs = nil
+	r.m.projects[project.ID] = &clone
+	return nil
+}
+
+// Delete mirrors models.DeleteProject, except that moved jobs keep their tag names as they are
+func (r *memoryProjects) Delete(ctx context.Context, project *models.Project, mode models.ProjectDeleteMode, targetProjectID string) ([]string, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	stored, ok := r.m.projects[project.ID]
+	if !ok || stored.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+
+	var jobs []*models.Job
+	for _, job := range r.m.jobs {
+		if job.ProjectID == project.ID && !job.DeletedAt.Valid {
+			jobs = append(jobs, job)
+		}
+	}
+
+	deletedAt := gorm.DeletedAt{Time: time.Now(), Valid: true}
+	ids := make([]string, 0, len(jobs))
+	switch mode {
+	case models.ProjectDeleteRefuse:
+		if len(jobs) > 0 {
+			return nil, models.ErrProjectHasJobs
+		}
+	case models.ProjectDeleteCascade:
+		for _, job := range jobs {
+			job.DeletedAt = deletedAt
+			job.DeletedWithProject = project.ID
+			ids = append(ids, job.ID)
+		}
+	case models.ProjectDeleteMove:
+		for _, job := range jobs {
+			job.ProjectID = targetProjectID
+			ids = append(ids, job.ID)
+		}
+	}
+
+	stored.DeletedAt = deletedAt
+	project.DeletedAt = deletedAt
+	return ids, nil
+}
+
+type memoryRuns struct {
+	m *memory
+}
+
+func (r *memoryRuns) List(ctx context.Context, filter RunFilter, page Page) ([]models.JobLog, PageInfo, error) {
+	order, err := runSorts.resolve(page, "-startTime")
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	runs := []models.JobLog{}
+	for _, run := range r.m.matchingRuns(filter) {
+		runs = append(runs, run.JobLog)
+	}
+	runs, info := order.pageOf(runs, runID, page.Limit)
+	return runs, info, nil
+}
+
+func (r *memoryRuns) ListWithJobs(ctx context.Context, filter RunFilter, page Page) ([]RunWithJob, PageInfo, error) {
+	order, err := runWithJobSorts.resolve(page, "-startTime")
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	runs, info := order.pageOf(r.m.runsWithJobs(filter), runWithJobID, page.Limit)
+	return runs, info, nil
+}
+
+func (r *memoryRuns) EachWithJob(ctx context.Context, filter RunFilter, sort string, fn func(*RunWithJob) error) error {
+	order, err := runWithJobSorts.resolve(Page{Sort: sort}, "-startTime")
+	if err != nil {
+		return err
+	}
+
+	r.m.mu.Lock()
+	runs, _ := order.pageOf(r.m.runsWithJobs(filter), runWithJobID, 0)
+	r.m.mu.Unlock()
+
+	for i := range runs {
+		if err := fn(&runs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runsWithJobs returns the runs of jobs outside the trash matching filter
+func (m *memory) runsWithJobs(filter RunFilter) []RunWithJob {
+	runs := []RunWithJob{}
+	for _, run := range m.matchingRuns(filter) {
+		job, ok := m.jobs[run.JobID]
+		if !ok || job.DeletedAt.Valid {
+			continue
+		}
+		run.JobName = job.Name
+		run.ProjectID = job.ProjectID
+		if project, ok := m.projects[job.ProjectID]; ok {
+			run.ProjectName = project.Name
+		}
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// matchingRuns returns the runs matching filter, without their job and project names
+func (m *memory) matchingRuns(filter RunFilter) []RunWithJob {
+	runs := []RunWithJob{}
+	for jobID, jobRuns := range m.runs {
+		if filter.JobID != "" && jobID != filter.JobID {
+			continue
+		}
+		if filter.ProjectID != "" {
+			if job, ok := m.jobs[jobID]; !ok || job.DeletedAt.Valid || job.ProjectID != filter.ProjectID {
+				continue
+			}
+		}
+		if filter.Projects != nil {
+			if job, ok := m.jobs[jobID]; !ok || !inProjects(filter.Projects, job.ProjectID) {
+				continue
+			}
+		}
+		for _, run := range jobRuns {
+			if (len(filter.Statuses) == 0 || hasStatus(filter.Statuses, run.Status)) &&
+				(len(filter.Triggers) == 0 || hasTrigger(filter.Triggers, run.Trigger)) &&
+				(filter.From.IsZero() || !run.StartTime.Before(filter.From)) &&
+				(filter.To.IsZero() || run.StartTime.Before(filter.To)) &&
+				(filter.MinDuration <= 0 || run.Duration >= filter.MinDuration) &&
+				(filter.MaxDuration <= 0 || run.Duration <= filter.MaxDuration) {
+				runs = append(runs, RunWithJob{JobLog: run})
+			}
+		}
+	}
+	return runs
+}
+
+// hasTrigger reports whether trigger is one of triggers
+func hasTrigger(triggers []models.RunTrigger, trigger models.RunTrigger) bool {
+	for _, t := range triggers {
+		if t == trigger {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryRuns) Start(ctx context.Context, run *models.JobLog) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if job, ok := r.m.jobs[run.JobID]; ok {
+		job.LastRun = run.StartTime
+		if job.Status != models.JobStatusPaused {
+			job.Status = models.JobStatusRunning
+		}
+		run.RevisionID = job.RevisionID
+	}
+	r.m.addRun(run)
+	return nil
+}
+
+func (r *memoryRuns) Finish(ctx context.Context, run *models.JobLog) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if job, ok := r.m.jobs[run.JobID]; ok {
+		if run.Status == models.JobStatusSuccess {
+			job.SuccessCount++
+			job.ConsecutiveFailures = 0
+		} else {
+			job.FailCount++
+			job.ConsecutiveFailures++
+		}
+		if job.Status != models.JobStatusPaused {
+			job.Status = run.Status
+			if run.Status == models.JobStatusSuccess {
+				job.Status = models.JobStatusIdle
+			}
+		}
+	}
+
+	runs := r.m.runs[run.JobID]
+	for i := range runs {
+		if runs[i].ID == run.ID {
+			runs[i] = *run
+			return nil
+		}
+	}
+	r.m.addRun(run)
+	return nil
+}
+
+func (r *memoryRuns) Create(ctx context.Context, run *models.JobLog) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if run.RevisionID == "" {
+		if job, ok := r.m.jobs[run.JobID]; ok {
+			run.RevisionID = job.RevisionID
+		}
+	}
+	r.m.addRun(run)
+	return nil
+}
+
+// addRun stores a new run, filling in what the database would
+func (m *memory) addRun(run *models.JobLog) {
+	if run.ID == "" {
+		run.ID = models.NewID()
+	}
+	if run.Trigger == "" {
+		run.Trigger = models.TriggerSchedule
+	}
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = time.Now()
+	}
+	m.runs[run.JobID] = append(m.runs[run.JobID], *run)
+}
+
+// JobStats leaves Aggregates nil: the in-memory store keeps no all-time aggregates
+func (r *memoryRuns) JobStats(ctx context.Context, jobID string, from, to time.Time) (*models.JobStats, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return models.JobStatsFromLogs(jobID, r.m.runs[jobID], from, to), nil
+}
+
+func (r *memoryRuns) ProjectStats(ctx context.Context, projectID string, from, to time.Time) (*models.ProjectStats, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	jobs := []models.Job{}
+	var logs []models.JobLog
+	for _, job := range r.m.jobs {
+		if job.ProjectID == projectID && !job.DeletedAt.Valid {
+			jobs = append(jobs, *cloneJob(job))
+			logs = append(logs, r.m.runs[job.ID]...)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return models.ProjectStatsFromLogs(projectID, jobs, logs, from, to), nil
+}
+
+type memoryDeliveries struct {
+	m *memory
+}
+
+func (r *memoryDeliveries) Get(ctx context.Context, id string) (*models.NotificationDelivery, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, delivery := range r.m.deliveries {
+		if delivery.ID == id {
+			return &delivery, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryDeliveries) List(ctx context.Context, filter DeliveryFilter, page Page) ([]models.NotificationDelivery, PageInfo, error) {
+	order, err := deliverySorts.resolve(page, "-createdAt")
+	if err != nil {
+		return nil, PageInfo{}, err
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	deliveries := []models.NotificationDelivery{}
+	for _, delivery := range r.m.deliveries {
+		if (filter.JobID == "" || delivery.JobID == filter.JobID) &&
+			(filter.Channel == "" || delivery.Channel == filter.Channel) &&
+			(filter.Status == "" || delivery.Status == filter.Status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	deliveries, info := order.pageOf(deliveries, deliveryID, page.Limit)
+	return deliveries, info, nil
+}
+
+func (r *memoryDeliveries) Create(ctx context.Context, delivery *models.NotificationDelivery) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if err := delivery.BeforeCreate(nil); err != nil {
+		return err
+	}
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+	r.m.deliveries = append(r.m.deliveries, *delivery)
+	return nil
+}
+
+type memoryAudit struct {
+	m *memory
+}
+
+func (r *memoryAudit) Append(ctx context.Context, entry *models.AuditEntry) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if err := entry.BeforeCreate(nil); err != nil {
+		return err
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	r.m.audit = append(r.m.audit, *entry)
+	return nil
+}
+
+func (r *memoryAudit) List(ctx context.Context, filter models.AuditFilter, limit int) ([]models.AuditEntry, error) {
+	entries := []models.AuditEntry{}
+	err := r.Each(ctx, filter, func(entry *models.AuditEntry) error {
+		if len(entries) == limit {
+			return errStop
+		}
+		entries = append(entries, *entry)
+		return nil
+	})
+	if err == errStop {
+		err = nil
+	}
+	return entries, err
+}
+
+// errStop ends an iteration early without failing it
+var errStop = errors.New("stop")
+
+func (r *memoryAudit) Each(ctx context.Context, filter models.AuditFilter, fn func(*models.AuditEntry) error) error {
+	r.m.mu.Lock()
+	var entries []models.AuditEntry
+	for i := len(r.m.audit) - 1; i >= 0; i-- {
+		if filter.Matches(&r.m.audit[i]) {
+			entries = append(entries, r.m.audit[i])
+		}
+	}
+	r.m.mu.Unlock()
+
+	// Entries were appended oldest first, so entries of the same time stay newest first
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+	for i := range entries {
+		entry := entries[i]
+		entry.AfterFind(nil)
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type memoryTrash struct {
+	m *memory
+}
+
+func (r *memoryTrash) List(ctx context.Context, projects []string) (*models.Trash, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	trash := &models.Trash{Jobs: []models.Job{}, Projects: []models.Project{}}
+	for _, job := range r.m.jobs {
+		if job.DeletedAt.Valid && inProjects(projects, job.ProjectID) {
+			trash.Jobs = append(trash.Jobs, *cloneJob(job))
+		}
+	}
+	for _, project := range r.m.projects {
+		if project.DeletedAt.Valid && inProjects(projects, project.ID) {
+			trash.Projects = append(trash.Projects, *project)
+		}
+	}
+	sort.Slice(trash.Jobs, func(i, j int) bool { return trash.Jobs[i].DeletedAt.Time.After(trash.Jobs[j].DeletedAt.Time) })
+	sort.Slice(trash.Projects, func(i, j int) bool {
+		return trash.Projects[i].DeletedAt.Time.After(trash.Projects[j].DeletedAt.Time)
+	})
+	return trash, nil
+}
+
+func (r *memoryTrash) RestoreJob(ctx context.Context, id string, projects []string) (*models.Job, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	job, ok := r.m.jobs[id]
+	if !ok || !job.DeletedAt.Valid || !inProjects(projects, job.ProjectID) {
+		return nil, ErrNotFound
+	}
+	if project, ok := r.m.projects[job.ProjectID]; !ok || project.DeletedAt.Valid {
+		return nil, models.ErrProjectInTrash
+	}
+	job.DeletedAt = gorm.DeletedAt{}
+	job.DeletedWithProject = ""
+	return cloneJob(job), nil
+}
+
+func (r *memoryTrash) RestoreProject(ctx context.Context, id string, projects []string) (*models.Project, []models.Job, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	project, ok := r.m.projects[id]
+	if !ok || !project.DeletedAt.Valid || !inProjects(projects, id) {
+		return nil, nil, ErrNotFound
+	}
+	jobs := []models.Job{}
+	for _, job := range r.m.jobs {
+		if job.ProjectID == id && job.DeletedWithProject == id && job.DeletedAt.Valid {
+			job.DeletedAt = gorm.DeletedAt{}
+			job.DeletedWithProject = ""
+			jobs = append(jobs, *cloneJob(job))
+		}
+	}
+	project.DeletedAt = gorm.DeletedAt{}
+	clone := *project
+	return &clone, jobs, nil
+}
+
+type memorySearch struct {
+	m *memory
+}
+
+func (r *memorySearch) Search(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	// Like the search index, word frequencies cover every record and totals those outside the trash
+	type candidate struct {
+		hit         SearchHit
+		occurrences map[string]map[string]int // By word, then field
+	}
+	var candidates []candidate
+	totals := make(map[models.SearchDocType]int64)
+	docs := make(map[models.SearchDocType]map[string]int64)
+	consider := func(hit SearchHit, visible bool) {
+		if visible {
+			totals[hit.Type]++
+		}
+		if !hasSearchType(query.Types, hit.Type) {
+			return
+		}
+		c := candidate{hit: hit, occurrences: make(map[string]map[string]int)}
+		for _, text := range hit.Texts {
+			for _, word := range models.SearchWords(text.Text) {
+				if c.occurrences[word] == nil {
+					c.occurrences[word] = make(map[string]int)
+				}
+				c.occurrences[word][text.Field]++
+			}
+		}
+		if docs[hit.Type] == nil {
+			docs[hit.Type] = make(map[string]int64)
+		}
+		for _, word := range query.Words {
+			if c.occurrences[word] != nil {
+				docs[hit.Type][word]++
+			}
+		}
+		if visible && (query.ProjectID == "" || hit.ProjectID == query.ProjectID) && inProjects(query.Projects, hit.ProjectID) {
+			candidates = append(candidates, c)
+		}
+	}
+
+	for _, job := range r.m.jobs {
+		projectName := ""
+		if project, ok := r.m.projects[job.ProjectID]; ok {
+			projectName = project.Name
+		}
+		visible := !job.DeletedAt.Valid
+		consider(SearchHit{
+			Type:        models.SearchDocJob,
+			ID:          job.ID,
+			JobID:       job.ID,
+			JobName:     job.Name,
+			ProjectID:   job.ProjectID,
+			ProjectName: projectName,
+			Status:      job.Status,
+			Texts:       job.SearchTexts(),
+		}, visible)
+		for _, run := range r.m.runs[job.ID] {
+			startTime := run.StartTime
+			consider(SearchHit{
+				Type:        models.SearchDocRun,
+				ID:          run.ID,
+				JobID:       job.ID,
+				JobName:     job.Name,
+				ProjectID:   job.ProjectID,
+				ProjectName: projectName,
+				Status:      run.Status,
+				StartTime:   &startTime,
+				Texts:       run.SearchTexts(),
+			}, visible)
+		}
+	}
+
+	hits := []SearchHit{}
+	for _, c := range candidates {
+		score := 0.0
+		for _, word := range query.Words {
+			fields := c.occurrences[word]
+			if fields == nil {
+				score = -1
+				break
+			}
+			rarity := searchIDF(totals[c.hit.Type], docs[c.hit.Type][word])
+			for field, occurrences := range fields {
+				if occurrences > models.MaxSearchOccurrences {
+					occurrences = models.MaxSearchOccurrences
+				}
+				score += float64(occurrences) * models.SearchFieldWeights[field] * rarity
+			}
+		}
+		if score < 0 {
+			continue
+		}
+		c.hit.Score = score
+		hits = append(hits, c.hit)
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if query.Limit > 0 && len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, nil
+}
+
+// hasSearchType reports whether docType is one of types
+func hasSearchType(types []models.SearchDocType, docType models.SearchDocType) bool {
+	for _, t := range types {
+		if t == docType {
+			return true
+		}
+	}
+	return false
+}
+
+// inProjects reports whether projectID is one of projects, where nil stands for every project
+func inProjects(projects []string, projectID string) bool {
+	if projects == nil {
+		return true
+	}
+	for _, id := range projects {
+		if id == projectID {
+			return true
+		}
+	}
+	return false
+}
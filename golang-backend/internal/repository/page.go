@@ -0,0 +1,344 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"crontab/internal/models"
+)
+
+// ErrInvalidPage is returned for an unknown sort field or a malformed cursor
+var ErrInvalidPage = errors.New("invalid page")
+
+// Page selects one page of a sorted list. The zero Page returns the whole list in its default order.
+type Page struct {
+	Limit  int    // Records per page, 0 for all of them
+	Cursor string // NextCursor of the previous page, empty for the first page
+	Sort   string // Field to sort by, prefixed with "-" for descending order; empty for the default
+}
+
+// PageInfo describes a returned page
+type PageInfo struct {
+	Total      int64  `json:"total"` // Records matching the filter on all pages
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"nextCursor,omitempty"` // Empty on the last page
+}
+
+// sortKind is the type of the values of a sort field
+type sortKind int
+
+const (
+	sortString sortKind = iota
+	sortTime
+	sortNumber
+)
+
+// sortField is a field a list can be sorted by
+type sortField[T any] struct {
+	column string
+	kind   sortKind
+	// nullable columns may be NULL, or hold the empty string or a time before nullBefore,
+	// which older releases wrote for times that were not set. All of these sort before every
+	// other value, so first in ascending and last in descending order.
+	nullable bool
+	value    func(*T) interface{}
+}
+
+// nullBefore is the earliest time a nullable time column holds when it is set. Comparing with
+// it rather than with the zero time works on every driver, including SQL Server's datetime.
+var nullBefore = time.Unix(0, 0).UTC()
+
+// valueOf returns the value of the field for record, nil when it is not set
+func (f sortField[T]) valueOf(record *T) interface{} {
+	value := f.value(record)
+	if !f.nullable {
+		return value
+	}
+	switch v := value.(type) {
+	case time.Time:
+		if v.Before(nullBefore) {
+			return nil
+		}
+	case string:
+		if v == "" {
+			return nil
+		}
+	}
+	return value
+}
+
+// sortFields are the fields a list can be sorted by, by their JSON name
+type sortFields[T any] map[string]sortField[T]
+
+// resolvedSort is a parsed Page.Sort together with the position of the cursor
+type resolvedSort[T any] struct {
+	name  string
+	field sortField[T]
+	desc  bool
+	// The last record of the previous page, when there is a cursor. afterV is nil when its
+	// value is not set.
+	after   bool
+	afterV  interface{}
+	afterID string
+}
+
+// cursor is the position after the last record of a page, encoded as base64 JSON
+type cursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    string          `json:"id"`
+}
+
+// resolve parses the sort and cursor of a page, falling back to the default sort
+func (fields sortFields[T]) resolve(page Page, defaultSort string) (*resolvedSort[T], error) {
+	if page.Limit < 0 {
+		return nil, fmt.Errorf("%w: limit must not be negative", ErrInvalidPage)
+	}
+
+	name := page.Sort
+	if name == "" {
+		name = defaultSort
+	}
+	s := &resolvedSort[T]{name: name, desc: strings.HasPrefix(name, "-")}
+	field, ok := fields[strings.TrimPrefix(name, "-")]
+	if !ok {
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("%w: sort must be one of %s, optionally prefixed with \"-\"", ErrInvalidPage, strings.Join(names, ", "))
+	}
+	s.field = field
+
+	if page.Cursor == "" {
+		return s, nil
+	}
+	invalidCursor := fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+	data, err := base64.RawURLEncoding.DecodeString(page.Cursor)
+	if err != nil {
+		return nil, invalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, invalidCursor
+	}
+	if c.Sort != name {
+		return nil, fmt.Errorf("%w: the cursor belongs to sort %q", ErrInvalidPage, c.Sort)
+	}
+	switch {
+	case string(c.Value) == "null" && field.nullable:
+		s.afterV = nil
+	case field.kind == sortString:
+		var v string
+		err = json.Unmarshal(c.Value, &v)
+		s.afterV = v
+	case field.kind == sortTime:
+		var v time.Time
+		err = json.Unmarshal(c.Value, &v)
+		s.afterV = v
+		if field.nullable && v.Before(nullBefore) {
+			s.afterV = nil
+		}
+	case field.kind == sortNumber:
+		var v float64
+		err = json.Unmarshal(c.Value, &v)
+		s.afterV = v
+	}
+	if err != nil {
+		return nil, invalidCursor
+	}
+	s.after, s.afterID = true, c.ID
+	return s, nil
+}
+
+// cursorAfter returns the cursor pointing behind record
+func (s *resolvedSort[T]) cursorAfter(record *T, id string) string {
+	value, _ := json.Marshal(s.field.valueOf(record))
+	data, _ := json.Marshal(cursor{Sort: s.name, Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// isSet returns the SQL condition that a nullable column holds a value, and its variables
+func (s *resolvedSort[T]) isSet() (string, []interface{}) {
+	if s.field.kind == sortTime {
+		return fmt.Sprintf("(%s IS NOT NULL AND %s >= ?)", s.field.column, s.field.column), []interface{}{nullBefore}
+	}
+	return fmt.Sprintf("(%s IS NOT NULL AND %s <> '')", s.field.column, s.field.column), nil
+}
+
+// apply orders a query, skips the records up to the cursor and fetches one record beyond limit,
+// which tells whether there is a next page. idColumn is the unique tie breaker.
+func (s *resolvedSort[T]) apply(query *gorm.DB, idColumn string, limit int) *gorm.DB {
+	column := s.field.column
+	direction, op := "ASC", ">"
+	if s.desc {
+		direction, op = "DESC", "<"
+	}
+
+	if !s.field.nullable {
+		if s.after {
+			where := fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", column, op, column, idColumn, op)
+			query = query.Where(where, s.afterV, s.afterV, s.afterID)
+		}
+		query = query.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                fmt.Sprintf("%s %s, %s %s", column, direction, idColumn, direction),
+			WithoutParentheses: true,
+		}})
+	} else {
+		// Records without a value come first in ascending order, then those with one, each
+		// group in order of the column and the tie breaker
+		set, vars := s.isSet()
+		if s.after {
+			after := fmt.Sprintf("(%s AND (%s %s ? OR (%s = ? AND %s %s ?)))", set, column, op, column, idColumn, op)
+			args := append(append([]interface{}{}, vars...), s.afterV, s.afterV, s.afterID)
+			unset := fmt.Sprintf("(NOT %s AND %s %s ?)", set, idColumn, op)
+			switch {
+			case s.afterV == nil && !s.desc:
+				query = query.Where("("+set+" OR "+unset+")", append(append(append([]interface{}{}, vars...), vars...), s.afterID)...)
+			case s.afterV == nil:
+				query = query.Where(unset, append(append([]interface{}{}, vars...), s.afterID)...)
+			case !s.desc:
+				query = query.Where(after, args...)
+			default:
+				query = query.Where("(NOT "+set+" OR "+after+")", append(append([]interface{}{}, vars...), args...)...)
+			}
+		}
+		query = query.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                fmt.Sprintf("CASE WHEN %s THEN 1 ELSE 0 END %s, %s %s, %s %s", set, direction, column, direction, idColumn, direction),
+			Vars:               vars,
+			WithoutParentheses: true,
+		}})
+	}
+	if limit > 0 {
+		query = query.Limit(limit + 1)
+	}
+	return query
+}
+
+// finish trims the extra record fetched by apply and describes the page
+func (s *resolvedSort[T]) finish(records []T, id func(*T) string, limit int, total int64) ([]T, PageInfo) {
+	info := PageInfo{Total: total, Limit: limit}
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+		info.NextCursor = s.cursorAfter(&records[limit-1], id(&records[limit-1]))
+	}
+	if limit == 0 {
+		info.Limit = len(records)
+	}
+	return records, info
+}
+
+// pageOf sorts records kept in memory and returns the requested page
+func (s *resolvedSort[T]) pageOf(records []T, id func(*T) string, limit int) ([]T, PageInfo) {
+	less := func(a, b *T) bool {
+		if c := compareValues(s.field.valueOf(a), s.field.valueOf(b)); c != 0 {
+			return (c < 0) != s.desc
+		}
+		return (id(a) < id(b)) != s.desc
+	}
+	sort.SliceStable(records, func(i, j int) bool { return less(&records[i], &records[j]) })
+	total := int64(len(records))
+
+	if s.after {
+		start := sort.Search(len(records), func(i int) bool {
+			c := compareValues(s.field.valueOf(&records[i]), s.afterV)
+			if c == 0 {
+				c = strings.Compare(id(&records[i]), s.afterID)
+			}
+			if s.desc {
+				return c < 0
+			}
+			return c > 0
+		})
+		records = records[start:]
+	}
+	if limit > 0 && len(records) > limit+1 {
+		records = records[:limit+1]
+	}
+	return s.finish(records, id, limit, total)
+}
+
+// compareValues compares two values of a sort field; nil sorts before everything else
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+	return 0
+}
+
+var jobSorts = sortFields[models.Job]{
+	"name":      {column: "jobs.name", kind: sortString, value: func(j *models.Job) interface{} { return j.Name }},
+	"status":    {column: "jobs.status", kind: sortString, value: func(j *models.Job) interface{} { return string(j.Status) }},
+	"createdAt": {column: "jobs.created_at", kind: sortTime, value: func(j *models.Job) interface{} { return j.CreatedAt }},
+	"updatedAt": {column: "jobs.updated_at", kind: sortTime, value: func(j *models.Job) interface{} { return j.UpdatedAt }},
+	"lastRun":   {column: "jobs.last_run", kind: sortTime, nullable: true, value: func(j *models.Job) interface{} { return j.LastRun }},
+	"nextRun":   {column: "jobs.next_run", kind: sortTime, nullable: true, value: func(j *models.Job) interface{} { return j.NextRun }},
+}
+
+var projectSorts = sortFields[models.Project]{
+	"name":      {column: "projects.name", kind: sortString, value: func(p *models.Project) interface{} { return p.Name }},
+	"createdAt": {column: "projects.created_at", kind: sortTime, value: func(p *models.Project) interface{} { return p.CreatedAt }},
+	"updatedAt": {column: "projects.updated_at", kind: sortTime, value: func(p *models.Project) interface{} { return p.UpdatedAt }},
+}
+
+var deliverySorts = sortFields[models.NotificationDelivery]{
+	"createdAt": {column: "notification_deliveries.created_at", kind: sortTime, value: func(d *models.NotificationDelivery) interface{} { return d.CreatedAt }},
+	"status":    {column: "notification_deliveries.status", kind: sortString, value: func(d *models.NotificationDelivery) interface{} { return string(d.Status) }},
+	"channel":   {column: "notification_deliveries.channel", kind: sortString, value: func(d *models.NotificationDelivery) interface{} { return d.Channel }},
+}
+
+var runSorts = sortFields[models.JobLog]{
+	"startTime": {column: "job_logs.start_time", kind: sortTime, value: func(l *models.JobLog) interface{} { return l.StartTime }},
+	"endTime":   {column: "job_logs.end_time", kind: sortTime, nullable: true, value: func(l *models.JobLog) interface{} { return l.EndTime }},
+	"duration":  {column: "job_logs.duration", kind: sortNumber, value: func(l *models.JobLog) interface{} { return l.Duration }},
+	"status":    {column: "job_logs.status", kind: sortString, value: func(l *models.JobLog) interface{} { return string(l.Status) }},
+}
+
+// runWithJobSorts are the sorts of runs plus the names of their jobs and projects
+var runWithJobSorts = func() sortFields[RunWithJob] {
+	fields := sortFields[RunWithJob]{
+		"jobName":     {column: "jobs.name", kind: sortString, value: func(r *RunWithJob) interface{} { return r.JobName }},
+		"projectName": {column: "projects.name", kind: sortString, nullable: true, value: func(r *RunWithJob) interface{} { return r.ProjectName }},
+	}
+	for name, field := range runSorts {
+		value := field.value
+		field := sortField[RunWithJob]{column: field.column, kind: field.kind, nullable: field.nullable}
+		field.value = func(r *RunWithJob) interface{} { return value(&r.JobLog) }
+		fields[name] = field
+	}
+	return fields
+}()
+
+func jobID(j *models.Job) string                       { return j.ID }
+func projectID(p *models.Project) string               { return p.ID }
+func runID(l *models.JobLog) string                    { return l.ID }
+func runWithJobID(r *RunWithJob) string                { return r.ID }
+func deliveryID(d *models.NotificationDelivery) string { return d.ID }
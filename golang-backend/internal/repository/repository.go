@@ -0,0 +1,204 @@
+// Package repository persists jobs, projects, runs and audit entries. Every repository has a GORM
+// implementation backed by the configured database and an in-memory one for tests and tools.
+package repository
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"crontab/internal/models"
+)
+
+// ErrNotFound is returned when a record does not exist or is in the trash
+var ErrNotFound = errors.New("record not found")
+
+// JobFilter selects jobs. Zero fields do not filter; ranges include their start and exclude their end.
+type JobFilter struct {
+	ProjectID    string
+	Projects     []string // Only jobs of these projects; nil for every project
+	Statuses     []models.JobStatus
+	Tags         []string // Tag names, compared case-insensitively
+	MatchAllTags bool     // Require every tag instead of any
+	Active       bool     // Only jobs that are not paused
+	NamePrefix   string   // Compared case-insensitively
+	LastRunFrom  time.Time
+	LastRunTo    time.Time
+	NextRunFrom  time.Time
+	NextRunTo    time.Time
+}
+
+// ProjectFilter selects projects. Zero fields do not filter.
+type ProjectFilter struct {
+	NamePrefix string   // Compared case-insensitively
+	IDs        []string // Only these projects; nil for every project
+}
+
+// RunFilter selects runs. Zero fields do not filter; the time range includes its start and excludes its end.
+type RunFilter struct {
+	JobID       string
+	ProjectID   string
+	Projects    []string // Only runs of jobs of these projects; nil for every project
+	Statuses    []models.JobStatus
+	Triggers    []models.RunTrigger
+	From        time.Time // Start time
+	To          time.Time
+	MinDuration float64 // Seconds
+	MaxDuration float64 // Seconds
+}
+
+// DeliveryFilter selects notification deliveries. Zero fields do not filter.
+type DeliveryFilter struct {
+	JobID   string
+	Channel string
+	Status  models.DeliveryStatus
+}
+
+// RunWithJob is a run together with the names of its job and project
+type RunWithJob struct {
+	models.JobLog
+	JobName     string `json:"jobName"`
+	ProjectID   string `json:"projectId"`
+	ProjectName string `json:"projectName"`
+}
+
+// SearchQuery selects the jobs and runs a search finds
+type SearchQuery struct {
+	Words     []string               // Words that must all occur, as split by models.SearchWords
+	Types     []models.SearchDocType // Kinds of records to search
+	ProjectID string                 // Only jobs of this project and their runs
+	Projects  []string               // Only jobs of these projects and their runs; nil for every project
+	Limit     int
+}
+
+// SearchHit is a job or run found by a search
+type SearchHit struct {
+	Type        models.SearchDocType     `json:"type"`
+	ID          string                   `json:"id"`
+	Score       float64                  `json:"score"`
+	JobID       string                   `json:"jobId"`
+	JobName     string                   `json:"jobName"`
+	ProjectID   string                   `json:"projectId"`
+	ProjectName string                   `json:"projectName"`
+	Status      models.JobStatus         `json:"status"`              // Of the job or of the run
+	StartTime   *time.Time               `json:"startTime,omitempty"` // Of a run
+	Highlights  []models.SearchHighlight `json:"highlights"`
+	Texts       []models.SearchText      `json:"-"` // Searchable fields of the job or run
+}
+
+// searchIDF weighs a word by how rare it is among the total records of its kind, docs of which contain it
+func searchIDF(total, docs int64) float64 {
+	if docs == 0 {
+		return 0
+	}
+	if total < docs {
+		total = docs
+	}
+	return math.Log(1 + float64(total)/float64(docs))
+}
+
+// JobRepository stores jobs with their tags and configuration history
+type JobRepository interface {
+	// Get returns a job with its tag names
+	Get(ctx context.Context, id string) (*models.Job, error)
+	// List returns a page of the jobs matching filter, by default oldest first
+	List(ctx context.Context, filter JobFilter, page Page) ([]models.Job, PageInfo, error)
+	// Create stores a new job, its tags (job.Tags) and its first revision
+	Create(ctx context.Context, job *models.Job, author string) error
+	// Save stores a job read with its current version, together with its tags, and records a
+	// revision when its configuration changed. It returns models.ErrVersionConflict when the job
+	// was saved by someone else since it was read.
+	Save(ctx context.Context, job *models.Job, author, reason string) error
+	// Delete moves a job to the trash
+	Delete(ctx context.Context, id string) error
+	// Revisions returns the configuration history of a job, newest first
+	Revisions(ctx context.Context, jobID string) ([]models.JobRevision, error)
+	// Revision returns the revision of a job with the given number
+	Revision(ctx context.Context, jobID string, number int) (*models.JobRevision, error)
+}
+
+// ProjectRepository stores projects
+type ProjectRepository interface {
+	Get(ctx context.Context, id string) (*models.Project, error)
+	// List returns a page of the projects matching filter, by default oldest first
+	List(ctx context.Context, filter ProjectFilter, page Page) ([]models.Project, PageInfo, error)
+	Create(ctx context.Context, project *models.Project) error
+	// Save stores a project read with its current version, see JobRepository.Save
+	Save(ctx context.Context, project *models.Project) error
+	// Delete moves a project to the trash and handles its jobs according to mode.
+	// It returns the IDs of the jobs that were moved to the trash or to the target project.
+	Delete(ctx context.Context, project *models.Project, mode models.ProjectDeleteMode, targetProjectID string) ([]string, error)
+}
+
+// RunRepository stores the runs of jobs, kept as job logs
+type RunRepository interface {
+	// List returns a page of the runs matching filter, by default newest first
+	List(ctx context.Context, filter RunFilter, page Page) ([]models.JobLog, PageInfo, error)
+	// ListWithJobs returns a page of the runs of jobs outside the trash that match filter,
+	// with the names of their jobs and projects. Runs can also be sorted by jobName and projectName.
+	ListWithJobs(ctx context.Context, filter RunFilter, page Page) ([]RunWithJob, PageInfo, error)
+	// EachWithJob calls fn for every run ListWithJobs selects, in the order of sort, without
+	// loading them all at once. It stops at the first error returned by fn.
+	EachWithJob(ctx context.Context, filter RunFilter, sort string, fn func(*RunWithJob) error) error
+	// Start stores a run that is starting and marks its job as running
+	Start(ctx context.Context, run *models.JobLog) error
+	// Finish stores the outcome of a run and updates the counters and status of its job
+	Finish(ctx context.Context, run *models.JobLog) error
+	// Create stores a run reported from outside the scheduler as is
+	Create(ctx context.Context, run *models.JobLog) error
+	// JobStats summarises the finished runs of a job that started in [from, to)
+	JobStats(ctx context.Context, jobID string, from, to time.Time) (*models.JobStats, error)
+	// ProjectStats rolls up the finished runs of the jobs of a project that started in [from, to)
+	ProjectStats(ctx context.Context, projectID string, from, to time.Time) (*models.ProjectStats, error)
+}
+
+// DeliveryRepository stores the attempts to deliver notifications
+type DeliveryRepository interface {
+	Get(ctx context.Context, id string) (*models.NotificationDelivery, error)
+	// List returns a page of the deliveries matching filter, by default newest first
+	List(ctx context.Context, filter DeliveryFilter, page Page) ([]models.NotificationDelivery, PageInfo, error)
+	// Create stores a delivery attempt
+	Create(ctx context.Context, delivery *models.NotificationDelivery) error
+}
+
+// AuditRepository appends to and reads the audit log
+type AuditRepository interface {
+	Append(ctx context.Context, entry *models.AuditEntry) error
+	// List returns up to limit entries matching filter, newest first
+	List(ctx context.Context, filter models.AuditFilter, limit int) ([]models.AuditEntry, error)
+	// Each calls fn for every entry matching filter, newest first, without loading them all at
+	// once. It stops at the first error returned by fn.
+	Each(ctx context.Context, filter models.AuditFilter, fn func(*models.AuditEntry) error) error
+}
+
+// TrashRepository lists and restores the jobs and projects in the trash. Projects restricts
+// every method to the records of those projects; nil stands for every project.
+type TrashRepository interface {
+	// List returns the deleted jobs and projects, most recently deleted first
+	List(ctx context.Context, projects []string) (*models.Trash, error)
+	// RestoreJob takes a job out of the trash. It returns models.ErrProjectInTrash while the
+	// project of the job is deleted.
+	RestoreJob(ctx context.Context, id string, projects []string) (*models.Job, error)
+	// RestoreProject takes a project out of the trash together with the jobs that were deleted
+	// with it, and returns those jobs
+	RestoreProject(ctx context.Context, id string, projects []string) (*models.Project, []models.Job, error)
+}
+
+// SearchRepository finds jobs outside the trash and their runs by the words they contain
+type SearchRepository interface {
+	// Search returns the query.Limit best records containing every word of query, ranked by the
+	// fields the words occur in (see models.SearchFieldWeights), how often and how rare they are
+	Search(ctx context.Context, query SearchQuery) ([]SearchHit, error)
+}
+
+// Store bundles the repositories of one backend
+type Store struct {
+	Jobs       JobRepository
+	Projects   ProjectRepository
+	Runs       RunRepository
+	Deliveries DeliveryRepository
+	Audit      AuditRepository
+	Trash      TrashRepository
+	Search     SearchRepository
+}
@@ -0,0 +1,709 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"crontab/internal/config"
+	"crontab/internal/migrations"
+	"crontab/internal/models"
+)
+
+// testServers maps the database servers the store tests also run against to the variable holding
+// their DSN. A server is skipped when its variable is not set; its database is emptied by each test.
+var testServers = map[string]string{
+	models.DriverSQLServer: "TEST_SQLSERVER_DSN",
+	models.DriverPostgres:  "TEST_POSTGRES_DSN",
+	models.DriverMySQL:     "TEST_MYSQL_DSN",
+}
+
+// stores returns a fresh in-memory store, a fresh store backed by a migrated SQLite database and
+// one for each configured server in testServers, which must all behave the same
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+	all := map[string]Store{
+		"memory": NewMemoryStore(),
+		"gorm":   NewGormStore(migratedDB(t)),
+	}
+	for driver, variable := range testServers {
+		if dsn := os.Getenv(variable); dsn != "" {
+			all["gorm-"+driver] = NewGormStore(serverDB(t, driver, dsn))
+		}
+	}
+	return all
+}
+
+// migratedDB returns a fresh SQLite database with every migration applied
+func migratedDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "crontab.db"))
+	return openMigrated(t, models.DriverSQLite, "")
+}
+
+// serverDB returns the database of a server with every migration rolled back and applied again,
+// so that it is empty
+func serverDB(t *testing.T, driver, dsn string) *gorm.DB {
+	t.Helper()
+	db := openMigrated(t, driver, dsn)
+	migrator := migrations.New(db, nil)
+	if _, err := migrator.To(0); err != nil {
+		t.Fatalf("failed to empty the %s database: %v", driver, err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("failed to migrate the %s database: %v", driver, err)
+	}
+	return db
+}
+
+// openMigrated connects to a database and applies every migration
+func openMigrated(t *testing.T, driver, dsn string) *gorm.DB {
+	t.Helper()
+	t.Setenv("DB_DRIVER", driver)
+	t.Setenv("DB_DSN", dsn)
+	db, err := models.SetupDatabase(config.New())
+	if err != nil {
+		t.Fatalf("SetupDatabase failed: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get connection pool: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := migrations.New(db, nil).Up(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+func createProject(t *testing.T, store Store, name string) *models.Project {
+	t.Helper()
+	project := &models.Project{Name: name, Version: 1}
+	if err := store.Projects.Create(context.Background(), project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	return project
+}
+
+func createJob(t *testing.T, store Store, projectID, name string, tags ...string) *models.Job {
+	t.Helper()
+	job := &models.Job{
+		ProjectID: projectID,
+		Name:      name,
+		Type:      models.JobTypeShell,
+		Command:   "true",
+		Schedule:  "0 0 * * * *",
+		Status:    models.JobStatusIdle,
+		Timezone:  "UTC",
+		Tags:      tags,
+		Version:   1,
+	}
+	if err := store.Jobs.Create(context.Background(), job, "test"); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	return job
+}
+
+func getJob(t *testing.T, store Store, id string) *models.Job {
+	t.Helper()
+	job, err := store.Jobs.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	return job
+}
+
+func TestJobsSaveChecksVersion(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			project := createProject(t, store, "Backups")
+			created := createJob(t, store, project.ID, "Nightly", "db", "ops")
+
+			job := getJob(t, store, created.ID)
+			if len(job.Tags) != 2 {
+				t.Errorf("tags = %v, want db and ops", job.Tags)
+			}
+			stale := *job
+
+			job.Description = "Dumps the database"
+			if err := store.Jobs.Save(ctx, job, "test", ""); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			if job.Version != 2 {
+				t.Errorf("version = %d, want 2", job.Version)
+			}
+
+			stale.Description = "Lost update"
+			if err := store.Jobs.Save(ctx, &stale, "test", ""); !errors.Is(err, models.ErrVersionConflict) {
+				t.Errorf("Save of a stale job = %v, want ErrVersionConflict", err)
+			}
+			if got := getJob(t, store, job.ID); got.Description != "Dumps the database" || got.Version != 2 {
+				t.Errorf("stored job = %q version %d", got.Description, got.Version)
+			}
+
+			if err := store.Jobs.Delete(ctx, job.ID); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if _, err := store.Jobs.Get(ctx, job.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get of a job in the trash = %v, want ErrNotFound", err)
+			}
+			if err := store.Jobs.Delete(ctx, job.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second Delete = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestJobsListFiltersAndPages(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			project := createProject(t, store, "Reports")
+			createJob(t, store, project.ID, "Report daily", "reports")
+			createJob(t, store, project.ID, "Report weekly", "reports")
+			createJob(t, store, project.ID, "Cleanup")
+
+			jobs, info, err := store.Jobs.List(ctx, JobFilter{NamePrefix: "report"}, Page{Sort: "name", Limit: 1})
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if len(jobs) != 1 || jobs[0].Name != "Report daily" || info.Total != 2 || info.NextCursor == "" {
+				t.Fatalf("first page = %v, %+v", jobNames(jobs), info)
+			}
+			jobs, info, err = store.Jobs.List(ctx, JobFilter{NamePrefix: "report"}, Page{Sort: "name", Limit: 1, Cursor: info.NextCursor})
+			if err != nil {
+				t.Fatalf("List of the second page failed: %v", err)
+			}
+			if len(jobs) != 1 || jobs[0].Name != "Report weekly" || info.NextCursor != "" {
+				t.Errorf("second page = %v, %+v", jobNames(jobs), info)
+			}
+
+			jobs, _, err = store.Jobs.List(ctx, JobFilter{Tags: []string{"reports"}, Statuses: []models.JobStatus{models.JobStatusIdle}}, Page{})
+			if err != nil {
+				t.Fatalf("List by tag failed: %v", err)
+			}
+			if len(jobs) != 2 {
+				t.Errorf("jobs tagged reports = %v", jobNames(jobs))
+			}
+
+			if _, _, err := store.Jobs.List(ctx, JobFilter{}, Page{Sort: "command"}); !errors.Is(err, ErrInvalidPage) {
+				t.Errorf("List sorted by an unknown field = %v, want ErrInvalidPage", err)
+			}
+		})
+	}
+}
+
+func jobNames(jobs []models.Job) []string {
+	names := make([]string, len(jobs))
+	for i, job := range jobs {
+		names[i] = job.Name
+	}
+	return names
+}
+
+func TestPagesSortRecordsWithoutValueFirst(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			alpha := createProject(t, store, "Alpha")
+			beta := createProject(t, store, "Beta")
+			gone := createProject(t, store, "Gone")
+			jobs := []*models.Job{
+				createJob(t, store, alpha.ID, "A"),
+				createJob(t, store, gone.ID, "B"),
+				createJob(t, store, beta.ID, "C"),
+				createJob(t, store, gone.ID, "D"),
+				createJob(t, store, alpha.ID, "E"),
+			}
+			// The runs of B and D have no project name
+			removeProject(t, store, gone.ID)
+			start := time.Now().UTC().Truncate(time.Second)
+			for i, job := range jobs[:3] {
+				for j := 0; j < 2; j++ {
+					run := &models.JobLog{JobID: job.ID, Status: models.JobStatusRunning, StartTime: start.Add(time.Duration(i*2+j) * time.Minute)}
+					if err := store.Runs.Start(ctx, run); err != nil {
+						t.Fatalf("Start failed: %v", err)
+					}
+				}
+			}
+			run := &models.JobLog{JobID: jobs[3].ID, Status: models.JobStatusRunning, StartTime: start}
+			if err := store.Runs.Start(ctx, run); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+
+			for _, sort := range []string{"projectName", "-projectName"} {
+				all, _, err := store.Runs.ListWithJobs(ctx, RunFilter{}, Page{Sort: sort})
+				if err != nil {
+					t.Fatalf("ListWithJobs by %s failed: %v", sort, err)
+				}
+				if len(all) != 7 {
+					t.Fatalf("ListWithJobs by %s returned %d runs, want 7", sort, len(all))
+				}
+				unnamed := all[0].ProjectName == "" && all[1].ProjectName == "" && all[2].ProjectName == ""
+				if sort == "-projectName" {
+					unnamed = all[4].ProjectName == "" && all[5].ProjectName == "" && all[6].ProjectName == ""
+				}
+				if !unnamed {
+					t.Errorf("runs by %s don't sort those without a project first", sort)
+				}
+
+				var paged []string
+				for cursor, pages := "", 0; pages == 0 || cursor != ""; pages++ {
+					runs, info, err := store.Runs.ListWithJobs(ctx, RunFilter{}, Page{Sort: sort, Limit: 2, Cursor: cursor})
+					if err != nil {
+						t.Fatalf("ListWithJobs by %s, page %d failed: %v", sort, pages, err)
+					}
+					for _, run := range runs {
+						paged = append(paged, run.ID)
+					}
+					cursor = info.NextCursor
+				}
+				for i := range all {
+					if i >= len(paged) || paged[i] != all[i].ID {
+						t.Errorf("pages by %s = %v, want the runs of one list %v", sort, paged, runWithJobIDs(all))
+						break
+					}
+				}
+			}
+
+			for _, sort := range []string{"lastRun", "-lastRun"} {
+				all, _, err := store.Jobs.List(ctx, JobFilter{}, Page{Sort: sort})
+				if err != nil {
+					t.Fatalf("List by %s failed: %v", sort, err)
+				}
+				var paged []string
+				for cursor, pages := "", 0; pages == 0 || cursor != ""; pages++ {
+					page, info, err := store.Jobs.List(ctx, JobFilter{}, Page{Sort: sort, Limit: 1, Cursor: cursor})
+					if err != nil {
+						t.Fatalf("List by %s, page %d failed: %v", sort, pages, err)
+					}
+					paged = append(paged, jobNames(page)...)
+					cursor = info.NextCursor
+				}
+				want := jobNames(all)
+				if len(want) != 5 || (sort == "lastRun" && want[0] != "E") || (sort == "-lastRun" && want[4] != "E") {
+					t.Errorf("jobs by %s = %v, want the job that never ran first in ascending order", sort, want)
+				}
+				if len(paged) != len(want) {
+					t.Errorf("pages by %s = %v, want %v", sort, paged, want)
+					continue
+				}
+				for i := range want {
+					if paged[i] != want[i] {
+						t.Errorf("pages by %s = %v, want %v", sort, paged, want)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+// removeProject deletes the row of a project but not its jobs, as a database without foreign keys
+// allows
+func removeProject(t *testing.T, store Store, id string) {
+	t.Helper()
+	projects, ok := store.Projects.(*gormProjects)
+	if !ok {
+		m := store.Projects.(*memoryProjects).m
+		m.mu.Lock()
+		delete(m.projects, id)
+		m.mu.Unlock()
+		return
+	}
+
+	// The checks are turned off for one connection, or for the table on SQL Server
+	checks := map[string][2]string{
+		models.DriverSQLite:    {"PRAGMA foreign_keys = OFF", "PRAGMA foreign_keys = ON"},
+		models.DriverMySQL:     {"SET FOREIGN_KEY_CHECKS = 0", "SET FOREIGN_KEY_CHECKS = 1"},
+		models.DriverPostgres:  {"SET session_replication_role = replica", "SET session_replication_role = DEFAULT"},
+		models.DriverSQLServer: {"ALTER TABLE jobs NOCHECK CONSTRAINT ALL", "ALTER TABLE jobs CHECK CONSTRAINT ALL"},
+	}[projects.db.Dialector.Name()]
+	err := projects.db.Connection(func(db *gorm.DB) error {
+		if err := db.Exec(checks[0]).Error; err != nil {
+			return err
+		}
+		defer db.Exec(checks[1])
+		return db.Exec("DELETE FROM projects WHERE id = ?", id).Error
+	})
+	if err != nil {
+		t.Fatalf("failed to remove project: %v", err)
+	}
+}
+
+func runWithJobIDs(runs []RunWithJob) []string {
+	ids := make([]string, len(runs))
+	for i, run := range runs {
+		ids[i] = run.ID
+	}
+	return ids
+}
+
+func TestRunsUpdateTheirJob(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			project := createProject(t, store, "Backups")
+			job := createJob(t, store, project.ID, "Nightly")
+			start := time.Now().UTC().Truncate(time.Second)
+
+			run := &models.JobLog{JobID: job.ID, Status: models.JobStatusRunning, StartTime: start}
+			if err := store.Runs.Start(ctx, run); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			running := getJob(t, store, job.ID)
+			if running.Status != models.JobStatusRunning || !running.LastRun.Equal(start) || running.Version != 1 {
+				t.Errorf("job after Start: status %s, last run %v, version %d", running.Status, running.LastRun, running.Version)
+			}
+
+			run.Status = models.JobStatusFailed
+			run.EndTime = start.Add(time.Second)
+			run.Error = "exit status 1"
+			if err := store.Runs.Finish(ctx, run); err != nil {
+				t.Fatalf("Finish failed: %v", err)
+			}
+			failed := getJob(t, store, job.ID)
+			if failed.Status != models.JobStatusFailed || failed.FailCount != 1 || failed.ConsecutiveFailures != 1 || failed.Version != 1 {
+				t.Errorf("job after a failure: status %s, fail count %d, failures in a row %d, version %d",
+					failed.Status, failed.FailCount, failed.ConsecutiveFailures, failed.Version)
+			}
+
+			// Runs don't change the version, and an edit based on the job as read before the run
+			// stores its configuration without undoing the run
+			running.Description = "edited"
+			if err := store.Jobs.Save(ctx, running, "test", ""); err != nil {
+				t.Fatalf("Save of the job read before the run failed: %v", err)
+			}
+			edited := getJob(t, store, job.ID)
+			if edited.Description != "edited" || edited.Version != 2 {
+				t.Errorf("job after the edit: description %q, version %d", edited.Description, edited.Version)
+			}
+			if edited.Status != models.JobStatusFailed || edited.FailCount != 1 || edited.ConsecutiveFailures != 1 || !edited.LastRun.Equal(start) {
+				t.Errorf("job after the edit: status %s, fail count %d, failures in a row %d, last run %v",
+					edited.Status, edited.FailCount, edited.ConsecutiveFailures, edited.LastRun)
+			}
+
+			runs, _, err := store.Runs.List(ctx, RunFilter{JobID: job.ID}, Page{})
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if len(runs) != 1 || runs[0].Status != models.JobStatusFailed || runs[0].Error != "exit status 1" {
+				t.Errorf("runs = %+v", runs)
+			}
+		})
+	}
+}
+
+func TestRunsKeepPausedJobsPaused(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			project := createProject(t, store, "Backups")
+			job := createJob(t, store, project.ID, "Nightly")
+			job.Pause("Maintenance", "ops@example.com")
+			if err := store.Jobs.Save(ctx, job, "test", ""); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+
+			run := &models.JobLog{JobID: job.ID, Status: models.JobStatusRunning, StartTime: time.Now()}
+			if err := store.Runs.Start(ctx, run); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			run.Status = models.JobStatusSuccess
+			run.EndTime = time.Now()
+			if err := store.Runs.Finish(ctx, run); err != nil {
+				t.Fatalf("Finish failed: %v", err)
+			}
+
+			got := getJob(t, store, job.ID)
+			if got.Status != models.JobStatusPaused || got.SuccessCount != 1 {
+				t.Errorf("job after a run: status %s, success count %d", got.Status, got.SuccessCount)
+			}
+
+			// Resuming is an edit of the pause state, which is stored
+			got.Resume()
+			if err := store.Jobs.Save(ctx, got, "test", ""); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			resumed := getJob(t, store, job.ID)
+			if resumed.Status != models.JobStatusIdle || resumed.PausedAt != nil || resumed.SuccessCount != 1 {
+				t.Errorf("job after Resume: status %s, paused at %v, success count %d", resumed.Status, resumed.PausedAt, resumed.SuccessCount)
+			}
+		})
+	}
+}
+
+func TestProjectsDeleteModes(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			source := createProject(t, store, "Old")
+			target := createProject(t, store, "New")
+			moved := createJob(t, store, source.ID, "Moved")
+
+			if _, err := store.Projects.Delete(ctx, source, models.ProjectDeleteRefuse, ""); !errors.Is(err, models.ErrProjectHasJobs) {
+				t.Fatalf("refusing Delete = %v, want ErrProjectHasJobs", err)
+			}
+
+			ids, err := store.Projects.Delete(ctx, source, models.ProjectDeleteMove, target.ID)
+			if err != nil {
+				t.Fatalf("moving Delete failed: %v", err)
+			}
+			if len(ids) != 1 || ids[0] != moved.ID {
+				t.Errorf("moved jobs = %v", ids)
+			}
+			if job := getJob(t, store, moved.ID); job.ProjectID != target.ID {
+				t.Errorf("project of the moved job = %s, want %s", job.ProjectID, target.ID)
+			}
+			if _, err := store.Projects.Get(ctx, source.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get of a project in the trash = %v, want ErrNotFound", err)
+			}
+
+			if _, err := store.Projects.Delete(ctx, target, models.ProjectDeleteCascade, ""); err != nil {
+				t.Fatalf("cascading Delete failed: %v", err)
+			}
+			if _, err := store.Jobs.Get(ctx, moved.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get of a job deleted with its project = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestSearchRestrictsProjects(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			billing := createProject(t, store, "Billing")
+			reports := createProject(t, store, "Reports")
+			createJob(t, store, billing.ID, "nightly invoices export")
+			createJob(t, store, reports.ID, "nightly sales export")
+
+			search := func(projects []string) []string {
+				hits, err := store.Search.Search(ctx, SearchQuery{
+					Words:    []string{"nightly", "export"},
+					Types:    []models.SearchDocType{models.SearchDocJob},
+					Projects: projects,
+					Limit:    10,
+				})
+				if err != nil {
+					t.Fatalf("Search failed: %v", err)
+				}
+				var names []string
+				for _, hit := range hits {
+					names = append(names, hit.JobName)
+				}
+				return names
+			}
+
+			if names := search(nil); len(names) != 2 {
+				t.Errorf("unrestricted search found %v, want both jobs", names)
+			}
+			if names := search([]string{billing.ID}); len(names) != 1 || names[0] != "nightly invoices export" {
+				t.Errorf("search restricted to Billing found %v", names)
+			}
+			if names := search([]string{}); len(names) != 0 {
+				t.Errorf("search restricted to no project found %v", names)
+			}
+		})
+	}
+}
+
+func TestDeliveriesFilterAndPage(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			project := createProject(t, store, "Billing")
+			job := createJob(t, store, project.ID, "invoices")
+			other := createJob(t, store, project.ID, "reminders")
+
+			start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+			for i, delivery := range []models.NotificationDelivery{
+				{JobID: job.ID, Channel: "webhook", Status: models.DeliveryStatusFailed},
+				{JobID: job.ID, Channel: "webhook", Status: models.DeliveryStatusSent},
+				{JobID: job.ID, Channel: "email", Status: models.DeliveryStatusSent},
+				{JobID: other.ID, Channel: "webhook", Status: models.DeliveryStatusSent},
+			} {
+				delivery.Event = "failure"
+				delivery.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+				if err := store.Deliveries.Create(ctx, &delivery); err != nil {
+					t.Fatalf("failed to create delivery: %v", err)
+				}
+			}
+
+			first, info, err := store.Deliveries.List(ctx, DeliveryFilter{JobID: job.ID}, Page{Limit: 2})
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if len(first) != 2 || info.Total != 3 || info.NextCursor == "" {
+				t.Fatalf("first page: %d deliveries, info %+v", len(first), info)
+			}
+			if first[0].Channel != "email" {
+				t.Errorf("first delivery = %s, want the newest (email)", first[0].Channel)
+			}
+			rest, info, err := store.Deliveries.List(ctx, DeliveryFilter{JobID: job.ID}, Page{Limit: 2, Cursor: info.NextCursor})
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if len(rest) != 1 || rest[0].Status != models.DeliveryStatusFailed || info.NextCursor != "" {
+				t.Errorf("second page: %+v, info %+v", rest, info)
+			}
+
+			webhooks, _, err := store.Deliveries.List(ctx, DeliveryFilter{JobID: job.ID, Channel: "webhook", Status: models.DeliveryStatusSent}, Page{})
+			if err != nil || len(webhooks) != 1 {
+				t.Errorf("filtered deliveries = %+v, err = %v", webhooks, err)
+			}
+
+			if _, _, err := store.Deliveries.List(ctx, DeliveryFilter{}, Page{Sort: "target"}); !errors.Is(err, ErrInvalidPage) {
+				t.Errorf("unknown sort: err = %v, want ErrInvalidPage", err)
+			}
+
+			found, err := store.Deliveries.Get(ctx, rest[0].ID)
+			if err != nil || found.JobID != job.ID || found.Status != models.DeliveryStatusFailed {
+				t.Errorf("Get = %+v, %v", found, err)
+			}
+			if _, err := store.Deliveries.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get of a missing delivery = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestTrashRestoresJobsWithTheirProject(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			project := createProject(t, store, "Ops")
+			other := createProject(t, store, "Billing")
+			job := createJob(t, store, project.ID, "Backup")
+			invoices := createJob(t, store, other.ID, "Invoices")
+			if err := store.Jobs.Delete(ctx, invoices.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Projects.Delete(ctx, project, models.ProjectDeleteCascade, ""); err != nil {
+				t.Fatal(err)
+			}
+
+			trash, err := store.Trash.List(ctx, []string{project.ID})
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if len(trash.Jobs) != 1 || trash.Jobs[0].ID != job.ID || len(trash.Projects) != 1 {
+				t.Errorf("trash of the project = %+v", trash)
+			}
+			if _, err := store.Trash.RestoreJob(ctx, job.ID, nil); !errors.Is(err, models.ErrProjectInTrash) {
+				t.Errorf("RestoreJob of a deleted project = %v, want ErrProjectInTrash", err)
+			}
+			if _, err := store.Trash.RestoreJob(ctx, invoices.ID, []string{project.ID}); !errors.Is(err, ErrNotFound) {
+				t.Errorf("RestoreJob of another project = %v, want ErrNotFound", err)
+			}
+
+			restored, jobs, err := store.Trash.RestoreProject(ctx, project.ID, nil)
+			if err != nil {
+				t.Fatalf("RestoreProject failed: %v", err)
+			}
+			if restored.ID != project.ID || len(jobs) != 1 || jobs[0].ID != job.ID {
+				t.Errorf("restored %+v with jobs %+v", restored, jobs)
+			}
+			if getJob(t, store, job.ID).DeletedWithProject != "" {
+				t.Error("restored job is still marked as deleted with its project")
+			}
+			if _, _, err := store.Trash.RestoreProject(ctx, project.ID, nil); !errors.Is(err, ErrNotFound) {
+				t.Errorf("RestoreProject outside the trash = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestJobsKeepRevisionsAndStats(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			project := createProject(t, store, "Ops")
+			job := createJob(t, store, project.ID, "Backup")
+			job.Command = "false"
+			if err := store.Jobs.Save(ctx, job, "test", "Broken on purpose"); err != nil {
+				t.Fatal(err)
+			}
+
+			revisions, err := store.Jobs.Revisions(ctx, job.ID)
+			if err != nil || len(revisions) != 2 || revisions[0].Number != 2 || revisions[0].Reason != "Broken on purpose" {
+				t.Fatalf("Revisions = %+v, %v", revisions, err)
+			}
+			first, err := store.Jobs.Revision(ctx, job.ID, 1)
+			if err != nil || first.Spec.Command != "true" {
+				t.Errorf("Revision 1 = %+v, %v", first, err)
+			}
+			if _, err := store.Jobs.Revision(ctx, job.ID, 3); !errors.Is(err, ErrNotFound) {
+				t.Errorf("missing Revision = %v, want ErrNotFound", err)
+			}
+
+			start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+			for i, status := range []models.JobStatus{models.JobStatusSuccess, models.JobStatusFailed, models.JobStatusFailed, models.JobStatusRunning} {
+				run := &models.JobLog{JobID: job.ID, Status: status, StartTime: start.Add(time.Duration(i) * time.Hour), Duration: float64(i + 1)}
+				if err := store.Runs.Create(ctx, run); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stats, err := store.Runs.JobStats(ctx, job.ID, start, start.Add(24*time.Hour))
+			if err != nil {
+				t.Fatalf("JobStats failed: %v", err)
+			}
+			if stats.TotalRuns != 3 || stats.FailCount != 2 || stats.CurrentFailureStreak != 2 || len(stats.Hourly) != 3 {
+				t.Errorf("job stats = %+v, want the 3 finished runs", stats)
+			}
+			projectStats, err := store.Runs.ProjectStats(ctx, project.ID, start.Add(time.Hour), start.Add(24*time.Hour))
+			if err != nil {
+				t.Fatalf("ProjectStats failed: %v", err)
+			}
+			if projectStats.TotalJobs != 1 || projectStats.TotalRuns != 2 || len(projectStats.Jobs) != 1 || projectStats.Jobs[0].TotalRuns != 2 {
+				t.Errorf("project stats = %+v, want the 2 failed runs", projectStats)
+			}
+		})
+	}
+}
+
+func TestAuditListsNewestFirst(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+			for i, entry := range []models.AuditEntry{
+				{Actor: "ops@example.com", ActorID: "user-1", Action: models.AuditJobCreate, ResourceType: "job", ResourceID: "a"},
+				{Actor: "ops@example.com", ActorID: "user-1", Action: models.AuditJobUpdate, ResourceType: "job", ResourceID: "a"},
+				{Actor: "dev@example.com", ActorID: "user-2", Action: models.AuditJobCreate, ResourceType: "job", ResourceID: "b"},
+			} {
+				entry.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+				entry.After = models.AuditSnapshot(map[string]int{"step": i})
+				if err := store.Audit.Append(ctx, &entry); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			entries, err := store.Audit.List(ctx, models.AuditFilter{Actor: "user-1"}, 10)
+			if err != nil || len(entries) != 2 || entries[0].Action != models.AuditJobUpdate || string(entries[0].After) != `{"step":1}` {
+				t.Errorf("entries of user-1 = %+v, %v", entries, err)
+			}
+			entries, err = store.Audit.List(ctx, models.AuditFilter{From: start.Add(time.Minute)}, 1)
+			if err != nil || len(entries) != 1 || entries[0].ResourceID != "b" {
+				t.Errorf("newest entry = %+v, %v", entries, err)
+			}
+
+			var ids []string
+			err = store.Audit.Each(ctx, models.AuditFilter{Action: string(models.AuditJobCreate)}, func(entry *models.AuditEntry) error {
+				ids = append(ids, entry.ResourceID)
+				return nil
+			})
+			if err != nil || strings.Join(ids, ",") != "b,a" {
+				t.Errorf("Each = %v, %v", ids, err)
+			}
+		})
+	}
+}
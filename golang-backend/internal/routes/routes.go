@@ -2,17 +2,24 @@
 package routes
 
 import (
+	"net/http"
+	
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 	
 	"crontab/internal/config"
 	"crontab/internal/handlers"
 	"crontab/internal/middleware"
+	"crontab/internal/repository"
+	"crontab/internal/services"
+	"crontab/pkg/logger"
+	"crontab/pkg/metrics"
+	"crontab/pkg/notify"
 	"crontab/pkg/scheduler"
 )
 
 // SetupRoutes configures all API routes
-func SetupRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, scheduler *scheduler.Scheduler) {
+func SetupRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, logger *logger.Logger, scheduler *scheduler.Scheduler, metrics *metrics.Metrics, dispatcher *notify.Dispatcher) {
 	// API group
 	api := e.Group("/api")
 	
@@ -26,27 +33,120 @@ func SetupRoutes(e *echo.Echo, db *gorm.DB, cfg *config.Config, scheduler *sched
 	protected := api.Group("")
 	protected.Use(middleware.AuthMiddleware(db))
 	
+	// Services shared by the handlers
+	store := repository.NewGormStore(db)
+	jobService := services.NewJobService(store, scheduler, logger)
+	projectService := services.NewProjectService(store, scheduler, logger)
+	runService := services.NewRunService(store)
+	searchService := services.NewSearchService(store)
+	trashService := services.NewTrashService(store, scheduler, logger)
+	
 	// Projects
-	projectHandler := handlers.NewProjectHandler(db)
+	projectHandler := handlers.NewProjectHandler(projectService)
 	protected.GET("/projects", projectHandler.GetAllProjects)
 	protected.GET("/projects/:id", projectHandler.GetProjectByID)
 	protected.POST("/projects", projectHandler.CreateProject)
 	protected.PUT("/projects/:id", projectHandler.UpdateProject)
+	protected.PATCH("/projects/:id", projectHandler.PatchProject)
 	protected.DELETE("/projects/:id", projectHandler.DeleteProject)
+	protected.GET("/projects/:id/stats", projectHandler.GetProjectStats)
 	
 	// Jobs
-	jobHandler := handlers.NewJobHandler(db, scheduler)
+	jobHandler := handlers.NewJobHandler(jobService, runService)
 	protected.GET("/jobs", jobHandler.GetAllJobs)
 	protected.GET("/jobs/project/:projectId", jobHandler.GetJobsByProject)
 	protected.GET("/jobs/:id", jobHandler.GetJobByID)
 	protected.POST("/jobs", jobHandler.CreateJob)
 	protected.PUT("/jobs/:id", jobHandler.UpdateJob)
+	protected.PATCH("/jobs/:id", jobHandler.PatchJob)
 	protected.DELETE("/jobs/:id", jobHandler.DeleteJob)
 	protected.GET("/jobs/:id/logs", jobHandler.GetJobLogs)
 	protected.POST("/jobs/:id/logs", jobHandler.CreateJobLog)
+	protected.GET("/jobs/:id/stats", jobHandler.GetJobStats)
+	protected.POST("/jobs/:id/run", jobHandler.RunJob)
+	protected.POST("/jobs/:id/pause", jobHandler.PauseJob)
+	protected.POST("/jobs/:id/resume", jobHandler.ResumeJob)
+	protected.POST("/jobs/:id/webhooks/:index/secret", jobHandler.RotateWebhookSecret)
+	
+	// Run history across jobs
+	runHandler := handlers.NewRunHandler(runService)
+	protected.GET("/runs", runHandler.GetRuns)
+	protected.GET("/runs/export", runHandler.ExportRuns)
+	
+	// Search across jobs and run output
+	searchHandler := handlers.NewSearchHandler(searchService)
+	protected.GET("/search", searchHandler.Search, middleware.RequirePermission("view"))
+	
+	// Job revisions
+	revisionHandler := handlers.NewRevisionHandler(jobService)
+	protected.GET("/jobs/:id/revisions", revisionHandler.GetJobRevisions)
+	protected.GET("/jobs/:id/revisions/diff", revisionHandler.DiffJobRevisions)
+	protected.GET("/jobs/:id/revisions/:number", revisionHandler.GetJobRevision)
+	protected.POST("/jobs/:id/revisions/:number/rollback", revisionHandler.RollbackJob)
+	
+	// Trash
+	trashHandler := handlers.NewTrashHandler(trashService)
+	protected.GET("/trash", trashHandler.GetTrash)
+	protected.POST("/trash/:type/:id/restore", trashHandler.RestoreItem)
+	
+	// Tags
+	tagHandler := handlers.NewTagHandler(db)
+	protected.GET("/tags", tagHandler.GetTags)
+	protected.POST("/tags", tagHandler.CreateTag)
+	protected.PUT("/tags/:id", tagHandler.UpdateTag)
+	protected.DELETE("/tags/:id", tagHandler.DeleteTag)
+	
+	// Audit log, admins only
+	auditHandler := handlers.NewAuditHandler(store.Audit)
+	audit := protected.Group("/audit", middleware.RequireRole("admin"))
+	audit.GET("", auditHandler.GetAuditEntries)
+	audit.GET("/export", auditHandler.ExportAuditEntries)
+	
+	// Notifications
+	notificationHandler := handlers.NewNotificationHandler(store, jobService, dispatcher)
+	protected.GET("/jobs/:id/deliveries", notificationHandler.GetJobDeliveries)
+	protected.POST("/deliveries/:id/redeliver", notificationHandler.RedeliverDelivery)
+	
+	// Notification channel routes
+	channelHandler := handlers.NewChannelHandler(db, dispatcher)
+	protected.GET("/projects/:id/channels", channelHandler.GetProjectChannels)
+	protected.POST("/projects/:id/channels", channelHandler.CreateChannel)
+	protected.GET("/channels/:id", channelHandler.GetChannelByID)
+	protected.PUT("/channels/:id", channelHandler.UpdateChannel)
+	protected.DELETE("/channels/:id", channelHandler.DeleteChannel)
+	protected.POST("/channels/:id/test", channelHandler.TestChannel)
+	protected.GET("/jobs/:id/subscriptions", channelHandler.GetJobSubscriptions)
+	protected.PUT("/jobs/:id/subscriptions", channelHandler.UpdateJobSubscriptions)
+	
+	// Alert routes
+	alertHandler := handlers.NewAlertHandler(db)
+	protected.GET("/jobs/:id/alert-rules", alertHandler.GetJobAlertRules)
+	protected.POST("/jobs/:id/alert-rules", alertHandler.CreateAlertRule)
+	protected.PUT("/alert-rules/:id", alertHandler.UpdateAlertRule)
+	protected.DELETE("/alert-rules/:id", alertHandler.DeleteAlertRule)
+	protected.GET("/jobs/:id/alerts", alertHandler.GetJobAlerts)
+	protected.GET("/alerts", alertHandler.GetAlerts)
+	
+	// Heartbeat pings, authenticated by the job's ping token
+	pingHandler := handlers.NewPingHandler(scheduler)
+	pingMethods := []string{http.MethodGet, http.MethodPost, http.MethodHead}
+	e.Match(pingMethods, "/ping/:token", pingHandler.Ping)
+	e.Match(pingMethods, "/ping/:token/start", pingHandler.PingStart)
+	e.Match(pingMethods, "/ping/:token/fail", pingHandler.PingFail)
 	
 	// Health check
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(200, map[string]string{"status": "ok"})
 	})
+	
+	// Prometheus metrics, optionally protected with a bearer token
+	if cfg.GetBool("METRICS_ENABLED", true) {
+		metricsHandler := echo.WrapHandler(metrics.Handler())
+		if token := cfg.GetString("METRICS_TOKEN", ""); token != "" {
+			metricsHandler = middleware.MetricsTokenMiddleware(token)(metricsHandler)
+		} else {
+			logger.Warn("/metrics is served without authentication and exposes job and project names; set METRICS_TOKEN or METRICS_ENABLED=false")
+		}
+		e.GET("/metrics", metricsHandler)
+	}
 }
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"crontab/internal/models"
+	"crontab/internal/repository"
+	"crontab/pkg/logger"
+)
+
+var (
+	// ErrJobPaused is returned when pausing a job that is already paused
+	ErrJobPaused = errors.New("job is already paused")
+	// ErrJobNotPaused is returned when resuming a job that is not paused
+	ErrJobNotPaused = errors.New("job is not paused")
+	// ErrHeartbeatRun is returned when running a heartbeat job, which runs elsewhere
+	ErrHeartbeatRun = errors.New("heartbeat jobs run elsewhere and report through their ping URL")
+	// ErrWebhookNotFound is returned when a job has no webhook at the given position
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrRunAsForbidden is returned when an actor without the admin role sets the account a job runs as
+	ErrRunAsForbidden = errors.New("only admins may change runAsUser and runAsGroup")
+)
+
+// JobService creates, changes and runs jobs
+type JobService struct {
+	store     repository.Store
+	scheduler Scheduler
+	logger    *logger.Logger
+}
+
+// NewJobService returns a job service. The scheduler may be nil for tools that do not run jobs.
+func NewJobService(store repository.Store, scheduler Scheduler, logger *logger.Logger) *JobService {
+	return &JobService{store: store, scheduler: scheduler, logger: logger}
+}
+
+// Get returns a job with its tags. Jobs of projects the actor may not access are not found.
+func (s *JobService) Get(ctx context.Context, actor Actor, id string) (*models.Job, error) {
+	job, err := s.store.Jobs.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanAccess(job.ProjectID) {
+		return nil, ErrNotFound
+	}
+	return job, nil
+}
+
+// List returns a page of the jobs matching filter among those the actor may access
+func (s *JobService) List(ctx context.Context, actor Actor, filter repository.JobFilter, page repository.Page) ([]models.Job, repository.PageInfo, error) {
+	tags, err := models.NormalizeTagNames(filter.Tags)
+	if err != nil {
+		return nil, repository.PageInfo{}, invalid(err)
+	}
+	filter.Tags = tags
+	filter.Projects = actor.Projects
+	return pageResult(s.store.Jobs.List(ctx, filter, page))
+}
+
+// Create validates and stores a new job and schedules it unless it is paused
+func (s *JobService) Create(ctx context.Context, actor Actor, job *models.Job) error {
+	if job.Status == "" {
+		job.Status = models.JobStatusIdle
+	}
+	if job.Timezone == "" {
+		job.Timezone = "UTC"
+	}
+
+	// Pause details, the failure counter and the ping token are maintained by the server
+	job.ConsecutiveFailures = 0
+	job.PingToken = ""
+	job.PausedReason = ""
+	job.PausedBy = ""
+	job.PausedAt = nil
+	if job.Status == models.JobStatusPaused {
+		job.Pause("Paused manually", actor.Name())
+	} else {
+		job.Status = models.JobStatusIdle
+	}
+
+	if (job.RunAsUser != "" || job.RunAsGroup != "") && !actor.Admin {
+		return ErrRunAsForbidden
+	}
+	if err := job.Validate(); err != nil {
+		return invalid(err)
+	}
+	tags, err := models.NormalizeTagNames(job.Tags)
+	if err != nil {
+		return invalid(err)
+	}
+	if !actor.CanAccess(job.ProjectID) {
+		return invalid(fmt.Errorf("project %s does not exist", job.ProjectID))
+	}
+	if job.ProjectID != "" {
+		if _, err := s.store.Projects.Get(ctx, job.ProjectID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return invalid(fmt.Errorf("project %s does not exist", job.ProjectID))
+			}
+			return err
+		}
+	}
+	job.Tags = tags
+	job.TagRefs = nil
+	job.Version = 1
+
+	if err := s.store.Jobs.Create(ctx, job, actor.Name()); err != nil {
+		return err
+	}
+
+	if job.Status != models.JobStatusPaused {
+		s.schedule(job)
+	}
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditJobCreate, "job", job.ID, nil, job)
+	return nil
+}
+
+// Update stores the user-defined fields of updated in current, the job as read by the caller,
+// and reschedules it. It returns models.ErrVersionConflict when the job changed since it was read.
+func (s *JobService) Update(ctx context.Context, actor Actor, current models.Job, updated *models.Job) (*models.Job, error) {
+	before := current
+	job := current
+	job.TagRefs = nil
+
+	job.Name = updated.Name
+	job.Type = updated.Type
+	job.Command = updated.Command
+	job.Endpoint = updated.Endpoint
+	job.HTTPMethod = updated.HTTPMethod
+	job.RequestBody = updated.RequestBody
+	job.Headers = updated.Headers
+	job.EmailNotifications = updated.EmailNotifications
+	job.Webhooks = updated.Webhooks
+	models.KeepWebhookSecrets(job.Webhooks, current.Webhooks)
+	job.Schedule = updated.Schedule
+	job.Description = updated.Description
+	// The status is maintained by the server: clients may only pause or resume the job, or send
+	// back the status they read
+	switch updated.Status {
+	case "", current.Status:
+	case models.JobStatusPaused:
+		job.Pause("Paused manually", actor.Name())
+	case models.JobStatusIdle:
+		if current.Status == models.JobStatusPaused {
+			job.Resume()
+		}
+	default:
+		return nil, invalid(fmt.Errorf("status can only be set to %s or %s", models.JobStatusIdle, models.JobStatusPaused))
+	}
+	job.Timezone = updated.Timezone
+	job.UseLocalTime = updated.UseLocalTime
+	job.WorkingDir = updated.WorkingDir
+	job.RunAsUser = updated.RunAsUser
+	job.RunAsGroup = updated.RunAsGroup
+	job.CPULimit = updated.CPULimit
+	job.MemoryLimit = updated.MemoryLimit
+	job.MaxOpenFiles = updated.MaxOpenFiles
+	job.MaxProcesses = updated.MaxProcesses
+	job.AutoPauseAfterFailures = updated.AutoPauseAfterFailures
+	job.GracePeriod = updated.GracePeriod
+	job.ExpectedDuration = updated.ExpectedDuration
+	job.UpdatedAt = time.Now()
+
+	if (job.RunAsUser != current.RunAsUser || job.RunAsGroup != current.RunAsGroup) && !actor.Admin {
+		return nil, ErrRunAsForbidden
+	}
+	if err := job.Validate(); err != nil {
+		return nil, invalid(err)
+	}
+	tags, err := models.NormalizeTagNames(updated.Tags)
+	if err != nil {
+		return nil, invalid(err)
+	}
+	job.Tags = tags
+
+	if err := s.store.Jobs.Save(ctx, &job, actor.Name(), ""); err != nil {
+		return nil, err
+	}
+
+	s.schedule(&job)
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditJobUpdate, "job", job.ID, before, job)
+	return &job, nil
+}
+
+// Pause takes a job off the schedule, recording why and by whom
+func (s *JobService) Pause(ctx context.Context, actor Actor, id, reason string) (*models.Job, error) {
+	job, err := s.Get(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == models.JobStatusPaused {
+		return nil, ErrJobPaused
+	}
+	if reason == "" {
+		reason = "Paused manually"
+	}
+
+	before := *job
+	job.Pause(reason, actor.Name())
+	if err := s.store.Jobs.Save(ctx, job, actor.Name(), ""); err != nil {
+		return nil, err
+	}
+	s.schedule(job)
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditJobPause, "job", job.ID, before, job)
+	return job, nil
+}
+
+// Resume schedules a paused job again
+func (s *JobService) Resume(ctx context.Context, actor Actor, id string) (*models.Job, error) {
+	job, err := s.Get(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.JobStatusPaused {
+		return nil, ErrJobNotPaused
+	}
+
+	before := *job
+	job.Resume()
+	if err := s.store.Jobs.Save(ctx, job, actor.Name(), ""); err != nil {
+		return nil, err
+	}
+	s.schedule(job)
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditJobResume, "job", job.ID, before, job)
+	return job, nil
+}
+
+// RotateWebhookSecret replaces the signing secret of the webhook at position index of a job and
+// returns the new secret, which is not readable afterwards
+func (s *JobService) RotateWebhookSecret(ctx context.Context, actor Actor, id string, index int) (*models.Job, models.WebhookSecret, error) {
+	job, err := s.Get(ctx, actor, id)
+	if err != nil {
+		return nil, models.WebhookSecret{}, err
+	}
+	if index < 0 || index >= len(job.Webhooks) {
+		return nil, models.WebhookSecret{}, ErrWebhookNotFound
+	}
+
+	before := *job
+	job.Webhooks = append([]models.WebhookSettings(nil), job.Webhooks...)
+	job.Webhooks[index].Secret = "" // A new one is generated when the job is saved
+	if err := s.store.Jobs.Save(ctx, job, actor.Name(), "Webhook secret rotated"); err != nil {
+		return nil, models.WebhookSecret{}, err
+	}
+	s.schedule(job)
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditJobWebhookSecret, "job", job.ID, before, job)
+	hook := job.Webhooks[index]
+	return job, models.WebhookSecret{URL: hook.URL, Secret: hook.Secret}, nil
+}
+
+// Delete moves a job to the trash and takes it off the schedule
+func (s *JobService) Delete(ctx context.Context, actor Actor, id string) error {
+	job, err := s.Get(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Jobs.Delete(ctx, id); err != nil {
+		return err
+	}
+	if s.scheduler != nil {
+		s.scheduler.UnscheduleJob(id)
+	}
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditJobDelete, "job", id, job, nil)
+	return nil
+}
+
+// Run starts a job outside of its schedule. The run happens in the background.
+func (s *JobService) Run(ctx context.Context, actor Actor, id string) (*models.Job, error) {
+	job, err := s.Get(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Type == models.JobTypeHeartbeat {
+		return nil, ErrHeartbeatRun
+	}
+	if s.scheduler != nil {
+		s.scheduler.RunNow(ctx, job.ID)
+	}
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditJobRun, "job", job.ID, nil, nil)
+	return job, nil
+}
+
+// Stats summarises the runs of a job that started in [from, to)
+func (s *JobService) Stats(ctx context.Context, actor Actor, id string, from, to time.Time) (*models.JobStats, error) {
+	job, err := s.Get(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.Runs.JobStats(ctx, job.ID, from, to)
+}
+
+// Revisions returns the configuration history of a job, newest first
+func (s *JobService) Revisions(ctx context.Context, actor Actor, id string) ([]models.JobRevision, error) {
+	if _, err := s.Get(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	return s.store.Jobs.Revisions(ctx, id)
+}
+
+// Revision returns the revision of a job with the given number
+func (s *JobService) Revision(ctx context.Context, actor Actor, id string, number int) (*models.JobRevision, error) {
+	if _, err := s.Get(ctx, actor, id); err != nil {
+		return nil, err
+	}
+	return s.store.Jobs.Revision(ctx, id, number)
+}
+
+// Rollback restores the configuration of an earlier revision of a job, which is recorded as a new
+// revision, and reschedules the job. It returns the job together with its current revision.
+func (s *JobService) Rollback(ctx context.Context, actor Actor, id string, number int) (*models.Job, *models.JobRevision, error) {
+	job, err := s.Get(ctx, actor, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	revision, err := s.store.Jobs.Revision(ctx, id, number)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before := *job
+	job.ApplySpec(revision.Spec)
+	job.UpdatedAt = time.Now()
+	if (job.RunAsUser != before.RunAsUser || job.RunAsGroup != before.RunAsGroup) && !actor.Admin {
+		return nil, nil, ErrRunAsForbidden
+	}
+	if err := job.Validate(); err != nil {
+		return nil, nil, invalid(fmt.Errorf("revision is no longer valid: %v", err))
+	}
+
+	reason := fmt.Sprintf("Rolled back to revision %d", revision.Number)
+	if err := s.store.Jobs.Save(ctx, job, actor.Name(), reason); err != nil {
+		return nil, nil, err
+	}
+	s.schedule(job)
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditJobRollback, "job", job.ID, before, job)
+
+	revisions, err := s.store.Jobs.Revisions(ctx, job.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range revisions {
+		if revisions[i].ID == job.RevisionID {
+			return job, &revisions[i], nil
+		}
+	}
+	return job, nil, nil
+}
+
+// schedule brings the scheduler in line with the job; paused jobs are taken off the schedule
+func (s *JobService) schedule(job *models.Job) {
+	if s.scheduler != nil {
+		s.scheduler.ScheduleJob(job)
+	}
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"crontab/internal/models"
+)
+
+func TestJobServiceCreate(t *testing.T) {
+	env := newTestEnv(t)
+	project := env.project(t, "Backups")
+
+	job := &models.Job{
+		ProjectID:           project.ID,
+		Name:                "Nightly",
+		Command:             "pg_dump",
+		Schedule:            "0 0 2 * * *",
+		Tags:                []string{" DB ", "db", "ops"},
+		ConsecutiveFailures: 7,
+		PingToken:           "chosen-by-client",
+	}
+	if err := env.jobs.Create(context.Background(), testActor, job); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	stored, err := env.jobs.Get(context.Background(), testActor, job.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if stored.Status != models.JobStatusIdle || stored.Timezone != "UTC" || stored.Version != 1 {
+		t.Errorf("defaults: status %s, timezone %s, version %d", stored.Status, stored.Timezone, stored.Version)
+	}
+	if stored.ConsecutiveFailures != 0 || stored.PingToken != "" {
+		t.Errorf("server maintained fields taken from the client: %d failures, ping token %q", stored.ConsecutiveFailures, stored.PingToken)
+	}
+	if len(stored.Tags) != 2 {
+		t.Errorf("tags = %v, want db and ops once", stored.Tags)
+	}
+	if _, ok := env.scheduler.scheduled[job.ID]; !ok {
+		t.Error("job not scheduled")
+	}
+	if actions := env.audit.actions(); len(actions) != 2 || actions[1] != models.AuditJobCreate {
+		t.Errorf("audit actions = %v", actions)
+	}
+	if entry := env.audit.entries[1]; entry.Actor != testActor.Email || entry.ResourceID != job.ID || entry.After == nil {
+		t.Errorf("audit entry = %+v", entry)
+	}
+}
+
+func TestJobServiceCreateValidates(t *testing.T) {
+	env := newTestEnv(t)
+	project := env.project(t, "Backups")
+
+	for name, job := range map[string]*models.Job{
+		"no name":         {ProjectID: project.ID, Command: "true", Schedule: "0 0 * * * *"},
+		"bad schedule":    {ProjectID: project.ID, Name: "a", Command: "true", Schedule: "every day"},
+		"unknown project": {ProjectID: "missing", Name: "a", Command: "true", Schedule: "0 0 * * * *"},
+		"bad tag":         {ProjectID: project.ID, Name: "a", Command: "true", Schedule: "0 0 * * * *", Tags: []string{"a,b"}},
+	} {
+		if err := env.jobs.Create(context.Background(), testActor, job); !IsValidationError(err) {
+			t.Errorf("%s: err = %v, want a validation error", name, err)
+		}
+	}
+	if len(env.scheduler.scheduled) != 0 {
+		t.Errorf("invalid jobs were scheduled: %v", env.scheduler.scheduled)
+	}
+}
+
+func TestJobServiceUpdate(t *testing.T) {
+	env := newTestEnv(t)
+	project := env.project(t, "Backups")
+	job := env.job(t, project.ID, "Nightly")
+	current, _ := env.jobs.Get(context.Background(), testActor, job.ID)
+
+	updated := *current
+	updated.Name = "Nightly backup"
+	updated.Status = models.JobStatusPaused
+	saved, err := env.jobs.Update(context.Background(), testActor, *current, &updated)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if saved.Name != "Nightly backup" || saved.Version != 2 {
+		t.Errorf("saved job: name %q, version %d", saved.Name, saved.Version)
+	}
+	if saved.PausedBy != testActor.Email || saved.PausedAt == nil {
+		t.Errorf("pausing through an update did not record who paused it: %+v", saved)
+	}
+	if env.scheduler.scheduled[job.ID] != models.JobStatusPaused {
+		t.Error("scheduler not told about the paused job")
+	}
+
+	// The caller's copy is now stale
+	if _, err := env.jobs.Update(context.Background(), testActor, *current, &updated); !errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("Update of a stale job = %v, want ErrVersionConflict", err)
+	}
+}
+
+func TestJobServiceUpdateOnlyPausesOrResumes(t *testing.T) {
+	env := newTestEnv(t)
+	project := env.project(t, "Backups")
+	job := env.job(t, project.ID, "Nightly")
+	ctx := context.Background()
+
+	for _, status := range []models.JobStatus{models.JobStatusRunning, models.JobStatusSuccess, "done"} {
+		current, _ := env.jobs.Get(ctx, testActor, job.ID)
+		updated := *current
+		updated.Status = status
+		if _, err := env.jobs.Update(ctx, testActor, *current, &updated); !IsValidationError(err) {
+			t.Errorf("Update to status %q = %v, want a validation error", status, err)
+		}
+	}
+
+	// A body without a status keeps the stored one
+	current, _ := env.jobs.Get(ctx, testActor, job.ID)
+	updated := *current
+	updated.Status = ""
+	saved, err := env.jobs.Update(ctx, testActor, *current, &updated)
+	if err != nil {
+		t.Fatalf("Update without status failed: %v", err)
+	}
+	if saved.Status != models.JobStatusIdle {
+		t.Errorf("status after an update without status = %q, want idle", saved.Status)
+	}
+
+	updated = *saved
+	updated.Status = models.JobStatusPaused
+	if saved, err = env.jobs.Update(ctx, testActor, *saved, &updated); err != nil || saved.Status != models.JobStatusPaused {
+		t.Fatalf("pausing Update = %v, status %v", err, saved)
+	}
+	updated = *saved
+	updated.Status = models.JobStatusIdle
+	if saved, err = env.jobs.Update(ctx, testActor, *saved, &updated); err != nil || saved.Status != models.JobStatusIdle || saved.PausedAt != nil {
+		t.Fatalf("resuming Update = %v, job %+v", err, saved)
+	}
+}
+
+func TestJobServiceUpdateKeepsWebhookSecrets(t *testing.T) {
+	env := newTestEnv(t)
+	project := env.project(t, "Backups")
+	job := &models.Job{
+		ProjectID: project.ID,
+		Name:      "Nightly",
+		Command:   "true",
+		Schedule:  "0 0 * * * *",
+		Webhooks:  []models.WebhookSettings{{URL: "https://hooks.example.com/a"}},
+	}
+	if err := env.jobs.Create(context.Background(), testActor, job); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	secret := job.Webhooks[0].Secret
+	current, _ := env.jobs.Get(context.Background(), testActor, job.ID)
+
+	// Clients never see the secrets, so updates come without them
+	updated := *current
+	updated.Webhooks = []models.WebhookSettings{{URL: "https://hooks.example.com/a"}, {URL: "https://hooks.example.com/b"}}
+	saved, err := env.jobs.Update(context.Background(), testActor, *current, &updated)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if saved.Webhooks[0].Secret != secret {
+		t.Error("the existing webhook lost its secret")
+	}
+	if saved.Webhooks[1].Secret == "" || saved.Webhooks[1].Secret == secret {
+		t.Errorf("the new webhook got secret %q", saved.Webhooks[1].Secret)
+	}
+
+	_, rotated, err := env.jobs.RotateWebhookSecret(context.Background(), testActor, job.ID, 0)
+	if err != nil {
+		t.Fatalf("RotateWebhookSecret failed: %v", err)
+	}
+	if rotated.Secret == "" || rotated.Secret == secret {
+		t.Errorf("rotated secret = %q", rotated.Secret)
+	}
+	if _, _, err := env.jobs.RotateWebhookSecret(context.Background(), testActor, job.ID, 2); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("rotating a missing webhook = %v, want ErrWebhookNotFound", err)
+	}
+}
+
+func TestJobServicePauseAndResume(t *testing.T) {
+	env := newTestEnv(t)
+	project := env.project(t, "Backups")
+	job := env.job(t, project.ID, "Nightly")
+	ctx := context.Background()
+
+	paused, err := env.jobs.Pause(ctx, testActor, job.ID, "")
+	if err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+	if paused.Status != models.JobStatusPaused || paused.PausedReason != "Paused manually" {
+		t.Errorf("paused job: status %s, reason %q", paused.Status, paused.PausedReason)
+	}
+	if _, err := env.jobs.Pause(ctx, testActor, job.ID, ""); !errors.Is(err, ErrJobPaused) {
+		t.Errorf("second Pause = %v, want ErrJobPaused", err)
+	}
+
+	resumed, err := env.jobs.Resume(ctx, testActor, job.ID)
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if resumed.Status == models.JobStatusPaused || resumed.PausedAt != nil {
+		t.Errorf("resumed job: status %s, paused at %v", resumed.Status, resumed.PausedAt)
+	}
+	if _, err := env.jobs.Resume(ctx, testActor, job.ID); !errors.Is(err, ErrJobNotPaused) {
+		t.Errorf("second Resume = %v, want ErrJobNotPaused", err)
+	}
+}
+
+func TestJobServiceRunAndDelete(t *testing.T) {
+	env := newTestEnv(t)
+	project := env.project(t, "Backups")
+	job := env.job(t, project.ID, "Nightly")
+	ctx := context.Background()
+
+	if _, err := env.jobs.Run(ctx, testActor, job.ID); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(env.scheduler.ran) != 1 || env.scheduler.ran[0] != job.ID {
+		t.Errorf("runs started = %v", env.scheduler.ran)
+	}
+
+	heartbeat := &models.Job{ProjectID: project.ID, Name: "Ping", Type: models.JobTypeHeartbeat, Schedule: "0 0 * * * *"}
+	if err := env.jobs.Create(ctx, testActor, heartbeat); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := env.jobs.Run(ctx, testActor, heartbeat.ID); !errors.Is(err, ErrHeartbeatRun) {
+		t.Errorf("Run of a heartbeat job = %v, want ErrHeartbeatRun", err)
+	}
+
+	if err := env.jobs.Delete(ctx, testActor, job.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if len(env.scheduler.unscheduled) != 1 || env.scheduler.unscheduled[0] != job.ID {
+		t.Errorf("unscheduled = %v", env.scheduler.unscheduled)
+	}
+	if _, err := env.jobs.Get(ctx, testActor, job.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a deleted job = %v, want ErrNotFound", err)
+	}
+	if _, err := env.jobs.Run(ctx, testActor, job.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Run of a deleted job = %v, want ErrNotFound", err)
+	}
+}
+
+func TestJobServiceRunAsNeedsAdminAndAllowlist(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	project := env.project(t, "Backups")
+	previous := models.AllowedRunAsUsers
+	models.AllowedRunAsUsers = []string{"backup"}
+	t.Cleanup(func() { models.AllowedRunAsUsers = previous })
+
+	admin := testActor
+	admin.Admin = true
+	newJob := func(user string) *models.Job {
+		return &models.Job{ProjectID: project.ID, Name: "dump", Command: "pg_dump", Schedule: "0 0 2 * * *", RunAsUser: user}
+	}
+
+	if err := env.jobs.Create(ctx, testActor, newJob("backup")); !errors.Is(err, ErrRunAsForbidden) {
+		t.Errorf("Create by a non-admin: err = %v, want ErrRunAsForbidden", err)
+	}
+	if err := env.jobs.Create(ctx, admin, newJob("root")); !IsValidationError(err) {
+		t.Errorf("Create with a user outside the allowlist: err = %v, want a validation error", err)
+	}
+	job := newJob("backup")
+	if err := env.jobs.Create(ctx, admin, job); err != nil {
+		t.Fatalf("Create by an admin failed: %v", err)
+	}
+
+	// Other fields can still be edited by everyone, as long as the account stays the same
+	current, _ := env.jobs.Get(ctx, testActor, job.ID)
+	edit := *current
+	edit.Description = "nightly dump"
+	updated, err := env.jobs.Update(ctx, testActor, *current, &edit)
+	if err != nil {
+		t.Fatalf("Update by a non-admin failed: %v", err)
+	}
+	edit = *updated
+	edit.RunAsUser = ""
+	if _, err := env.jobs.Update(ctx, testActor, *updated, &edit); !errors.Is(err, ErrRunAsForbidden) {
+		t.Errorf("Update of runAsUser by a non-admin: err = %v, want ErrRunAsForbidden", err)
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"crontab/internal/models"
+	"crontab/internal/repository"
+	"crontab/pkg/logger"
+)
+
+// ProjectDeletion is the outcome of deleting a project
+type ProjectDeletion struct {
+	Mode            models.ProjectDeleteMode `json:"mode"`
+	TargetProjectID string                   `json:"targetProjectId"`
+	JobIDs          []string                 `json:"jobIds"` // Jobs moved to the trash or to the target project
+}
+
+// ProjectService creates, changes and deletes projects
+type ProjectService struct {
+	store     repository.Store
+	scheduler Scheduler
+	logger    *logger.Logger
+}
+
+// NewProjectService returns a project service. The scheduler may be nil for tools that do not run jobs.
+func NewProjectService(store repository.Store, scheduler Scheduler, logger *logger.Logger) *ProjectService {
+	return &ProjectService{store: store, scheduler: scheduler, logger: logger}
+}
+
+// Get returns a project with its jobs. Projects the actor may not access are not found.
+func (s *ProjectService) Get(ctx context.Context, actor Actor, id string) (*models.Project, error) {
+	project, err := s.get(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	jobs, _, err := s.store.Jobs.List(ctx, repository.JobFilter{ProjectID: id}, repository.Page{})
+	if err != nil {
+		return nil, err
+	}
+	project.Jobs = jobs
+	return project, nil
+}
+
+// Stats rolls up the runs of the jobs of a project that started in [from, to)
+func (s *ProjectService) Stats(ctx context.Context, actor Actor, id string, from, to time.Time) (*models.ProjectStats, error) {
+	project, err := s.get(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.Runs.ProjectStats(ctx, project.ID, from, to)
+}
+
+// get returns a project the actor may access, without its jobs
+func (s *ProjectService) get(ctx context.Context, actor Actor, id string) (*models.Project, error) {
+	if !actor.CanAccess(id) {
+		return nil, ErrNotFound
+	}
+	return s.store.Projects.Get(ctx, id)
+}
+
+// List returns a page of the projects matching filter among those the actor may access, without
+// their jobs
+func (s *ProjectService) List(ctx context.Context, actor Actor, filter repository.ProjectFilter, page repository.Page) ([]models.Project, repository.PageInfo, error) {
+	filter.IDs = actor.Projects
+	return pageResult(s.store.Projects.List(ctx, filter, page))
+}
+
+// Create validates and stores a new project
+func (s *ProjectService) Create(ctx context.Context, actor Actor, project *models.Project) error {
+	if err := project.Validate(); err != nil {
+		return invalid(err)
+	}
+	project.Jobs = nil
+	project.Version = 1
+
+	if err := s.store.Projects.Create(ctx, project); err != nil {
+		return err
+	}
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditProjectCreate, "project", project.ID, nil, project)
+	return nil
+}
+
+// Update stores the user-defined fields of updated in current, the project as read by the caller.
+// It returns models.ErrVersionConflict when the project changed since it was read.
+func (s *ProjectService) Update(ctx context.Context, actor Actor, current models.Project, updated *models.Project) (*models.Project, error) {
+	before := current
+	project := current
+	project.Jobs = nil
+	project.Name = updated.Name
+	project.Description = updated.Description
+	project.UpdatedAt = time.Now()
+
+	if err := project.Validate(); err != nil {
+		return nil, invalid(err)
+	}
+	if err := s.store.Projects.Save(ctx, &project); err != nil {
+		return nil, err
+	}
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditProjectUpdate, "project", project.ID, before, project)
+	return &project, nil
+}
+
+// Delete moves a project to the trash and handles its jobs according to mode, which defaults to
+// refuse. It returns models.ErrProjectHasJobs when refusing to delete a project with jobs.
+func (s *ProjectService) Delete(ctx context.Context, actor Actor, id string, mode models.ProjectDeleteMode, targetProjectID string) (*ProjectDeletion, error) {
+	project, err := s.get(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == "" {
+		mode = models.ProjectDeleteRefuse
+	}
+	switch mode {
+	case models.ProjectDeleteRefuse, models.ProjectDeleteCascade:
+	case models.ProjectDeleteMove:
+		if targetProjectID == project.ID {
+			return nil, invalid(errors.New("targetProjectId must be another existing project"))
+		}
+		if _, err := s.get(ctx, actor, targetProjectID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, invalid(errors.New("targetProjectId must be another existing project"))
+			}
+			return nil, err
+		}
+	default:
+		return nil, invalid(errors.New("mode must be \"refuse\", \"cascade\" or \"move\""))
+	}
+
+	jobIDs, err := s.store.Projects.Delete(ctx, project, mode, targetProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == models.ProjectDeleteCascade && s.scheduler != nil {
+		for _, jobID := range jobIDs {
+			s.scheduler.UnscheduleJob(jobID)
+		}
+	}
+	deletion := &ProjectDeletion{Mode: mode, TargetProjectID: targetProjectID, JobIDs: jobIDs}
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditProjectDelete, "project", project.ID, project, deletion)
+	return deletion, nil
+}
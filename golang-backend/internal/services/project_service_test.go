@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"crontab/internal/models"
+	"crontab/internal/repository"
+)
+
+func TestProjectServiceCreateAndUpdate(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	if err := env.projects.Create(ctx, testActor, &models.Project{Name: "  "}); !IsValidationError(err) {
+		t.Errorf("Create without a name = %v, want a validation error", err)
+	}
+
+	project := env.project(t, "Backups")
+	env.job(t, project.ID, "Nightly")
+	current, err := env.projects.Get(ctx, testActor, project.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(current.Jobs) != 1 {
+		t.Errorf("project jobs = %d, want 1", len(current.Jobs))
+	}
+
+	updated := *current
+	updated.Name = "Database backups"
+	saved, err := env.projects.Update(ctx, testActor, *current, &updated)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if saved.Name != "Database backups" || saved.Version != 2 || saved.Jobs != nil {
+		t.Errorf("saved project: name %q, version %d, %d jobs", saved.Name, saved.Version, len(saved.Jobs))
+	}
+	if _, err := env.projects.Update(ctx, testActor, *current, &updated); !errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("Update of a stale project = %v, want ErrVersionConflict", err)
+	}
+
+	projects, info, err := env.projects.List(ctx, testActor, repository.ProjectFilter{NamePrefix: "data"}, repository.Page{})
+	if err != nil || len(projects) != 1 || info.Total != 1 {
+		t.Errorf("List = %d projects, %+v, %v", len(projects), info, err)
+	}
+	if _, _, err := env.projects.List(ctx, testActor, repository.ProjectFilter{}, repository.Page{Sort: "owner"}); !IsValidationError(err) {
+		t.Errorf("List with an unknown sort = %v, want a validation error", err)
+	}
+}
+
+func TestProjectServiceDelete(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	source := env.project(t, "Old")
+	target := env.project(t, "New")
+	moved := env.job(t, source.ID, "Moved")
+	trashed := env.job(t, target.ID, "Trashed")
+
+	if _, err := env.projects.Delete(ctx, testActor, source.ID, "", ""); !errors.Is(err, models.ErrProjectHasJobs) {
+		t.Errorf("Delete with jobs = %v, want ErrProjectHasJobs", err)
+	}
+	for name, targetID := range map[string]string{"itself": source.ID, "a missing project": "missing"} {
+		if _, err := env.projects.Delete(ctx, testActor, source.ID, models.ProjectDeleteMove, targetID); !IsValidationError(err) {
+			t.Errorf("moving the jobs to %s = %v, want a validation error", name, err)
+		}
+	}
+	if _, err := env.projects.Delete(ctx, testActor, source.ID, "archive", ""); !IsValidationError(err) {
+		t.Errorf("Delete with an unknown mode = %v, want a validation error", err)
+	}
+
+	deletion, err := env.projects.Delete(ctx, testActor, source.ID, models.ProjectDeleteMove, target.ID)
+	if err != nil {
+		t.Fatalf("moving Delete failed: %v", err)
+	}
+	if len(deletion.JobIDs) != 1 || deletion.JobIDs[0] != moved.ID {
+		t.Errorf("moved jobs = %v", deletion.JobIDs)
+	}
+
+	deletion, err = env.projects.Delete(ctx, testActor, target.ID, models.ProjectDeleteCascade, "")
+	if err != nil {
+		t.Fatalf("cascading Delete failed: %v", err)
+	}
+	if len(deletion.JobIDs) != 2 || len(env.scheduler.unscheduled) != 2 {
+		t.Errorf("jobs deleted %v, unscheduled %v", deletion.JobIDs, env.scheduler.unscheduled)
+	}
+	if _, err := env.jobs.Get(ctx, testActor, trashed.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a job deleted with its project = %v, want ErrNotFound", err)
+	}
+
+	last := env.audit.entries[len(env.audit.entries)-1]
+	if last.Action != models.AuditProjectDelete || last.ResourceID != target.ID {
+		t.Errorf("last audit entry = %s %s", last.Action, last.ResourceID)
+	}
+}
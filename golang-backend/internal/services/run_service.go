@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"crontab/internal/models"
+	"crontab/internal/repository"
+)
+
+// RunService records the runs of jobs. The scheduler uses it for the runs it executes and the
+// API for runs reported from outside.
+type RunService struct {
+	store repository.Store
+}
+
+// NewRunService returns a run service
+func NewRunService(store repository.Store) *RunService {
+	return &RunService{store: store}
+}
+
+// List returns a page of the runs matching filter among those of jobs the actor may access
+func (s *RunService) List(ctx context.Context, actor Actor, filter repository.RunFilter, page repository.Page) ([]models.JobLog, repository.PageInfo, error) {
+	filter.Projects = actor.Projects
+	return pageResult(s.store.Runs.List(ctx, filter, page))
+}
+
+// History returns a page of the runs of all jobs outside the trash the actor may access, with job
+// and project names
+func (s *RunService) History(ctx context.Context, actor Actor, filter repository.RunFilter, page repository.Page) ([]repository.RunWithJob, repository.PageInfo, error) {
+	filter.Projects = actor.Projects
+	return pageResult(s.store.Runs.ListWithJobs(ctx, filter, page))
+}
+
+// Export calls fn for every run History selects, in the order of sort, streaming them from storage
+func (s *RunService) Export(ctx context.Context, actor Actor, filter repository.RunFilter, sort string, fn func(*repository.RunWithJob) error) error {
+	filter.Projects = actor.Projects
+	err := s.store.Runs.EachWithJob(ctx, filter, sort, fn)
+	if errors.Is(err, repository.ErrInvalidPage) {
+		return invalid(err)
+	}
+	return err
+}
+
+// Start records a run that is starting and marks its job as running
+func (s *RunService) Start(ctx context.Context, run *models.JobLog) error {
+	if run.StartTime.IsZero() {
+		run.StartTime = time.Now()
+	}
+	run.Status = models.JobStatusRunning
+	return s.store.Runs.Start(ctx, run)
+}
+
+// Finish records the outcome of a run and updates the counters and status of its job
+func (s *RunService) Finish(ctx context.Context, run *models.JobLog) error {
+	return s.store.Runs.Finish(ctx, run)
+}
+
+// Report stores a run of a job that was reported from outside the scheduler
+func (s *RunService) Report(ctx context.Context, actor Actor, jobID string, run *models.JobLog) error {
+	job, err := s.store.Jobs.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if !actor.CanAccess(job.ProjectID) {
+		return ErrNotFound
+	}
+	run.JobID = jobID
+	run.CreatedAt = time.Now()
+	return s.store.Runs.Create(ctx, run)
+}
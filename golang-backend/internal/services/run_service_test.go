@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"crontab/internal/models"
+	"crontab/internal/repository"
+)
+
+func TestRunServiceStartAndFinish(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	project := env.project(t, "Backups")
+	job := env.job(t, project.ID, "Nightly")
+
+	run := &models.JobLog{JobID: job.ID}
+	if err := env.runs.Start(ctx, run); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if run.Status != models.JobStatusRunning || run.StartTime.IsZero() {
+		t.Errorf("started run: status %s, start time %v", run.Status, run.StartTime)
+	}
+	if current, _ := env.jobs.Get(ctx, testActor, job.ID); current.Status != models.JobStatusRunning {
+		t.Errorf("job status = %s, want running", current.Status)
+	}
+
+	run.Status = models.JobStatusSuccess
+	run.EndTime = time.Now()
+	if err := env.runs.Finish(ctx, run); err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	current, _ := env.jobs.Get(ctx, testActor, job.ID)
+	if current.Status != models.JobStatusIdle || current.SuccessCount != 1 {
+		t.Errorf("job after the run: status %s, success count %d", current.Status, current.SuccessCount)
+	}
+
+	runs, info, err := env.runs.List(ctx, testActor, repository.RunFilter{JobID: job.ID}, repository.Page{})
+	if err != nil || len(runs) != 1 || info.Total != 1 {
+		t.Errorf("List = %d runs, %+v, %v", len(runs), info, err)
+	}
+	history, _, err := env.runs.History(ctx, testActor, repository.RunFilter{}, repository.Page{})
+	if err != nil || len(history) != 1 || history[0].JobName != "Nightly" || history[0].ProjectName != "Backups" {
+		t.Errorf("History = %+v, %v", history, err)
+	}
+}
+
+func TestRunServiceReport(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	project := env.project(t, "Backups")
+	job := env.job(t, project.ID, "Nightly")
+
+	run := &models.JobLog{JobID: "ignored", Status: models.JobStatusFailed, StartTime: time.Now(), Trigger: models.TriggerExternal}
+	if err := env.runs.Report(ctx, testActor, job.ID, run); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if run.JobID != job.ID || run.ID == "" {
+		t.Errorf("reported run: job %s, id %q", run.JobID, run.ID)
+	}
+	if err := env.runs.Report(ctx, testActor, "missing", &models.JobLog{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Report for a missing job = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRunServiceRejectsInvalidPages(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	if _, _, err := env.runs.History(ctx, testActor, repository.RunFilter{}, repository.Page{Sort: "output"}); !IsValidationError(err) {
+		t.Errorf("History with an unknown sort = %v, want a validation error", err)
+	}
+	if _, _, err := env.runs.List(ctx, testActor, repository.RunFilter{}, repository.Page{Cursor: "not a cursor"}); !IsValidationError(err) {
+		t.Errorf("List with a bad cursor = %v, want a validation error", err)
+	}
+	err := env.runs.Export(ctx, testActor, repository.RunFilter{}, "output", func(*repository.RunWithJob) error { return nil })
+	if !IsValidationError(err) {
+		t.Errorf("Export with an unknown sort = %v, want a validation error", err)
+	}
+}
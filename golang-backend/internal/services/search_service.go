@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"crontab/internal/models"
+	"crontab/internal/repository"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchWords     = 10
+)
+
+// SearchOptions narrows down a search
+type SearchOptions struct {
+	IncludeRuns bool   // Also search the output and errors of runs
+	ProjectID   string // Only jobs of this project and their runs
+	Limit       int    // Defaults to 20, at most 100
+}
+
+// SearchService finds jobs and runs by the words they contain
+type SearchService struct {
+	store repository.Store
+}
+
+// NewSearchService returns a search service
+func NewSearchService(store repository.Store) *SearchService {
+	return &SearchService{store: store}
+}
+
+// Search returns the jobs outside the trash, and optionally their runs, containing every word of
+// text, best first, with the matching words highlighted in the fields they occur in.
+// Only jobs of the projects the actor may access are found.
+func (s *SearchService) Search(ctx context.Context, actor Actor, text string, options SearchOptions) ([]repository.SearchHit, error) {
+	var words []string
+	seen := make(map[string]bool)
+	for _, word := range models.SearchWords(text) {
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return nil, invalid(errors.New("q must contain at least one word"))
+	}
+	if len(words) > maxSearchWords {
+		return nil, invalid(fmt.Errorf("q must not contain more than %d different words", maxSearchWords))
+	}
+
+	if options.Limit == 0 {
+		options.Limit = defaultSearchLimit
+	}
+	if options.Limit < 1 || options.Limit > maxSearchLimit {
+		return nil, invalid(fmt.Errorf("limit must be between 1 and %d", maxSearchLimit))
+	}
+
+	types := []models.SearchDocType{models.SearchDocJob}
+	if options.IncludeRuns {
+		types = append(types, models.SearchDocRun)
+	}
+	hits, err := s.store.Search.Search(ctx, repository.SearchQuery{
+		Words:     words,
+		Types:     types,
+		ProjectID: options.ProjectID,
+		Projects:  actor.Projects,
+		Limit:     options.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Records with the words next to each other, in the order searched for, rank higher
+	phrase := models.SearchWords(text)
+	for i := range hits {
+		hit := &hits[i]
+		hit.Highlights = []models.SearchHighlight{}
+		inOrder := false
+		for _, field := range hit.Texts {
+			if snippet, ok := models.HighlightSearch(field.Text, seen); ok {
+				hit.Highlights = append(hit.Highlights, models.SearchHighlight{Field: field.Field, Snippet: snippet})
+			}
+			if len(phrase) > 1 && !inOrder {
+				inOrder = models.HasSearchPhrase(field.Text, phrase)
+			}
+		}
+		if inOrder {
+			hit.Score *= 2
+		}
+		hit.Texts = nil
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	return hits, nil
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"crontab/internal/models"
+)
+
+func TestSearchServiceOnlyFindsAccessibleProjects(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	billing := env.project(t, "Billing")
+	reports := env.project(t, "Reports")
+	env.job(t, billing.ID, "rotate invoices")
+	env.job(t, reports.ID, "rotate reports")
+
+	names := func(actor Actor, options SearchOptions) []string {
+		hits, err := env.search.Search(ctx, actor, "rotate", options)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		var names []string
+		for _, hit := range hits {
+			names = append(names, hit.JobName)
+		}
+		return names
+	}
+
+	if found := names(testActor, SearchOptions{}); len(found) != 2 {
+		t.Errorf("actor without project permissions found %v, want both jobs", found)
+	}
+
+	scoped := testActor
+	scoped.Projects = models.ProjectScope([]string{"view", models.ProjectPermissionPrefix + billing.ID})
+	if found := names(scoped, SearchOptions{}); len(found) != 1 || found[0] != "rotate invoices" {
+		t.Errorf("actor restricted to Billing found %v", found)
+	}
+	// Asking for another project does not widen the scope
+	if found := names(scoped, SearchOptions{ProjectID: reports.ID}); len(found) != 0 {
+		t.Errorf("actor restricted to Billing found %v in Reports", found)
+	}
+}
+
+func TestSearchServiceValidatesQuery(t *testing.T) {
+	env := newTestEnv(t)
+
+	if _, err := env.search.Search(context.Background(), testActor, " -- ", SearchOptions{}); !IsValidationError(err) {
+		t.Errorf("Search without words: err = %v, want a validation error", err)
+	}
+	if _, err := env.search.Search(context.Background(), testActor, "backup", SearchOptions{Limit: 500}); !IsValidationError(err) {
+		t.Errorf("Search with limit 500: err = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,105 @@
+// Package services holds the business rules shared by the API handlers, the scheduler and the
+// command line tools: validation, scheduling side effects, revisions and the audit log. Services
+// only talk to storage through the repository interfaces, so they run the same against the
+// database and against the in-memory store.
+package services
+
+import (
+	"context"
+	"errors"
+
+	"crontab/internal/models"
+	"crontab/internal/repository"
+	"crontab/pkg/logger"
+)
+
+// ErrNotFound is returned when a job or project does not exist or is in the trash
+var ErrNotFound = repository.ErrNotFound
+
+// ValidationError reports input that breaks a business rule
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// invalid wraps err in a ValidationError
+func invalid(err error) error {
+	return &ValidationError{Err: err}
+}
+
+// IsValidationError reports whether err is caused by invalid input
+func IsValidationError(err error) bool {
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr)
+}
+
+// pageResult passes on the result of a repository list, turning an invalid page into a ValidationError
+func pageResult[T any](records []T, info repository.PageInfo, err error) ([]T, repository.PageInfo, error) {
+	if errors.Is(err, repository.ErrInvalidPage) {
+		return nil, info, invalid(err)
+	}
+	return records, info, err
+}
+
+// Scheduler is the part of the scheduler that services keep in sync with job changes
+type Scheduler interface {
+	ScheduleJob(job *models.Job)
+	UnscheduleJob(jobID string)
+	RunNow(ctx context.Context, jobID string)
+}
+
+// Actor is who performs an operation, as recorded in revisions and the audit log
+type Actor struct {
+	UserID    string
+	Email     string
+	IP        string
+	UserAgent string
+	Projects  []string // Projects the actor is restricted to; nil for every project
+	Admin     bool     // Whether the actor has the admin role
+}
+
+// CanAccess reports whether the actor may see and change the jobs and runs of a project
+func (a Actor) CanAccess(projectID string) bool {
+	if a.Projects == nil {
+		return true
+	}
+	for _, id := range a.Projects {
+		if id == projectID {
+			return true
+		}
+	}
+	return false
+}
+
+// Name returns the email of the actor, or "api" when the request is not authenticated
+func (a Actor) Name() string {
+	if a.Email == "" {
+		return "api"
+	}
+	return a.Email
+}
+
+// audit appends an entry to the audit log. Failures are logged but never fail the operation.
+func audit(ctx context.Context, repo repository.AuditRepository, logger *logger.Logger, actor Actor, action models.AuditAction, resourceType, resourceID string, before, after interface{}) {
+	entry := models.AuditEntry{
+		ActorID:      actor.UserID,
+		Actor:        actor.Name(),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Before:       models.AuditSnapshot(before),
+		After:        models.AuditSnapshot(after),
+		IP:           actor.IP,
+		UserAgent:    actor.UserAgent,
+	}
+	if err := repo.Append(ctx, &entry); err != nil {
+		logger.Error("Failed to record audit entry %s: %v", action, err)
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"crontab/internal/config"
+	"crontab/internal/models"
+	"crontab/internal/repository"
+	"crontab/pkg/logger"
+)
+
+// fakeScheduler records the calls the services make
+type fakeScheduler struct {
+	mu          sync.Mutex
+	scheduled   map[string]models.JobStatus // Status of the job when it was last scheduled
+	unscheduled []string
+	ran         []string
+}
+
+func (s *fakeScheduler) ScheduleJob(job *models.Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduled[job.ID] = job.Status
+}
+
+func (s *fakeScheduler) UnscheduleJob(jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unscheduled = append(s.unscheduled, jobID)
+}
+
+func (s *fakeScheduler) RunNow(ctx context.Context, jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ran = append(s.ran, jobID)
+}
+
+// recordingAudit keeps the audit entries, or fails when err is set. Reads go to the wrapped repository.
+type recordingAudit struct {
+	repository.AuditRepository
+	entries []models.AuditEntry
+	err     error
+}
+
+func (a *recordingAudit) Append(ctx context.Context, entry *models.AuditEntry) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.entries = append(a.entries, *entry)
+	return nil
+}
+
+func (a *recordingAudit) actions() []models.AuditAction {
+	actions := make([]models.AuditAction, len(a.entries))
+	for i, entry := range a.entries {
+		actions[i] = entry.Action
+	}
+	return actions
+}
+
+// testEnv is a set of services over an in-memory store
+type testEnv struct {
+	store     repository.Store
+	scheduler *fakeScheduler
+	audit     *recordingAudit
+	jobs      *JobService
+	projects  *ProjectService
+	runs      *RunService
+	search    *SearchService
+}
+
+var testActor = Actor{UserID: "user-1", Email: "ops@example.com", IP: "127.0.0.1"}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	audit := &recordingAudit{AuditRepository: store.Audit}
+	store.Audit = audit
+	scheduler := &fakeScheduler{scheduled: make(map[string]models.JobStatus)}
+	logs := logger.New(config.New())
+	return &testEnv{
+		store:     store,
+		scheduler: scheduler,
+		audit:     audit,
+		jobs:      NewJobService(store, scheduler, logs),
+		projects:  NewProjectService(store, scheduler, logs),
+		runs:      NewRunService(store),
+		search:    NewSearchService(store),
+	}
+}
+
+func (e *testEnv) project(t *testing.T, name string) *models.Project {
+	t.Helper()
+	project := &models.Project{Name: name}
+	if err := e.projects.Create(context.Background(), testActor, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	return project
+}
+
+func (e *testEnv) job(t *testing.T, projectID, name string) *models.Job {
+	t.Helper()
+	job := &models.Job{
+		ProjectID: projectID,
+		Name:      name,
+		Type:      models.JobTypeShell,
+		Command:   "echo hello",
+		Schedule:  "0 0 * * * *",
+	}
+	if err := e.jobs.Create(context.Background(), testActor, job); err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	return job
+}
+
+func TestAuditFailureDoesNotFailTheOperation(t *testing.T) {
+	env := newTestEnv(t)
+	env.audit.err = errors.New("audit log unavailable")
+
+	project := &models.Project{Name: "Backups"}
+	if err := env.projects.Create(context.Background(), testActor, project); err != nil {
+		t.Fatalf("Create failed because of the audit log: %v", err)
+	}
+	if _, err := env.store.Projects.Get(context.Background(), project.ID); err != nil {
+		t.Errorf("project not stored: %v", err)
+	}
+}
+
+func TestServicesOnlyShowAccessibleProjects(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	billing := env.project(t, "Billing")
+	reports := env.project(t, "Reports")
+	invoices := env.job(t, billing.ID, "invoices")
+	sales := env.job(t, reports.ID, "sales")
+	for _, job := range []*models.Job{invoices, sales} {
+		if err := env.runs.Report(ctx, testActor, job.ID, &models.JobLog{Status: models.JobStatusSuccess}); err != nil {
+			t.Fatalf("Report failed: %v", err)
+		}
+	}
+
+	scoped := testActor
+	scoped.Projects = models.ProjectScope([]string{"view", models.ProjectPermissionPrefix + billing.ID})
+
+	if _, err := env.jobs.Get(ctx, scoped, sales.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a job of another project: err = %v, want ErrNotFound", err)
+	}
+	if _, err := env.jobs.Get(ctx, scoped, invoices.ID); err != nil {
+		t.Errorf("Get of a job of the project: %v", err)
+	}
+	if _, err := env.jobs.Pause(ctx, scoped, sales.ID, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Pause of a job of another project: err = %v, want ErrNotFound", err)
+	}
+	if err := env.jobs.Create(ctx, scoped, &models.Job{ProjectID: reports.ID, Name: "more sales", Type: models.JobTypeShell, Command: "true", Schedule: "0 0 * * * *"}); !IsValidationError(err) {
+		t.Errorf("Create in another project: err = %v, want a validation error", err)
+	}
+	if jobs, _, err := env.jobs.List(ctx, scoped, repository.JobFilter{}, repository.Page{}); err != nil || len(jobs) != 1 || jobs[0].ID != invoices.ID {
+		t.Errorf("List = %v, %v, want only invoices", jobs, err)
+	}
+
+	if _, err := env.projects.Get(ctx, scoped, reports.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of another project: err = %v, want ErrNotFound", err)
+	}
+	if projects, _, err := env.projects.List(ctx, scoped, repository.ProjectFilter{}, repository.Page{}); err != nil || len(projects) != 1 || projects[0].ID != billing.ID {
+		t.Errorf("List of projects = %v, %v, want only Billing", projects, err)
+	}
+	if _, err := env.projects.Delete(ctx, scoped, billing.ID, models.ProjectDeleteMove, reports.ID); !IsValidationError(err) {
+		t.Errorf("moving jobs to another project: err = %v, want a validation error", err)
+	}
+
+	if runs, _, err := env.runs.History(ctx, scoped, repository.RunFilter{}, repository.Page{}); err != nil || len(runs) != 1 || runs[0].JobID != invoices.ID {
+		t.Errorf("History = %+v, %v, want the run of invoices", runs, err)
+	}
+	if runs, _, err := env.runs.List(ctx, scoped, repository.RunFilter{JobID: sales.ID}, repository.Page{}); err != nil || len(runs) != 0 {
+		t.Errorf("List of the runs of sales = %+v, %v, want none", runs, err)
+	}
+	exported := 0
+	if err := env.runs.Export(ctx, scoped, repository.RunFilter{}, "", func(*repository.RunWithJob) error { exported++; return nil }); err != nil || exported != 1 {
+		t.Errorf("Export wrote %d runs, err = %v, want 1", exported, err)
+	}
+	if err := env.runs.Report(ctx, scoped, sales.ID, &models.JobLog{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Report for a job of another project: err = %v, want ErrNotFound", err)
+	}
+}
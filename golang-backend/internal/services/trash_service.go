@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+
+	"crontab/internal/models"
+	"crontab/internal/repository"
+	"crontab/pkg/logger"
+)
+
+// TrashService lists and restores deleted jobs and projects
+type TrashService struct {
+	store     repository.Store
+	scheduler Scheduler
+	logger    *logger.Logger
+}
+
+// NewTrashService returns a trash service. The scheduler may be nil for tools that do not run jobs.
+func NewTrashService(store repository.Store, scheduler Scheduler, logger *logger.Logger) *TrashService {
+	return &TrashService{store: store, scheduler: scheduler, logger: logger}
+}
+
+// List returns the deleted jobs and projects of the projects the actor may access
+func (s *TrashService) List(ctx context.Context, actor Actor) (*models.Trash, error) {
+	return s.store.Trash.List(ctx, actor.Projects)
+}
+
+// RestoreJob takes a job out of the trash and schedules it again. Jobs of projects the actor may
+// not access are not found, and models.ErrProjectInTrash is returned while the project of the
+// job is deleted.
+func (s *TrashService) RestoreJob(ctx context.Context, actor Actor, id string) (*models.Job, error) {
+	job, err := s.store.Trash.RestoreJob(ctx, id, actor.Projects)
+	if err != nil {
+		return nil, err
+	}
+	s.schedule(job)
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditJobRestore, "job", job.ID, nil, job)
+	return job, nil
+}
+
+// RestoreProject takes a project out of the trash together with the jobs deleted with it, which
+// are scheduled again and returned as the jobs of the project
+func (s *TrashService) RestoreProject(ctx context.Context, actor Actor, id string) (*models.Project, error) {
+	project, jobs, err := s.store.Trash.RestoreProject(ctx, id, actor.Projects)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		s.schedule(&jobs[i])
+	}
+	project.Jobs = jobs
+	audit(ctx, s.store.Audit, s.logger, actor, models.AuditProjectRestore, "project", project.ID, nil, project)
+	return project, nil
+}
+
+// schedule puts a restored job back on the schedule; paused jobs stay off it
+func (s *TrashService) schedule(job *models.Job) {
+	if s.scheduler != nil {
+		s.scheduler.ScheduleJob(job)
+	}
+}
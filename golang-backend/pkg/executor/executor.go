@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// Sandbox describes the execution environment of a shell job
+type Sandbox struct {
+	WorkingDir   string
+	User         string
+	Group        string
+	CPUSeconds   uint64 // RLIMIT_CPU
+	MemoryBytes  uint64 // RLIMIT_AS
+	MaxOpenFiles uint64 // RLIMIT_NOFILE
+	MaxProcesses uint64 // RLIMIT_NPROC
+}
+
+// HasLimits reports whether any resource limit is configured
+func (sb Sandbox) HasLimits() bool {
+	return sb.CPUSeconds > 0 || sb.MemoryBytes > 0 || sb.MaxOpenFiles > 0 || sb.MaxProcesses > 0
+}
+
+// Result holds the outcome of a command execution
+type Result struct {
+	Output    string
+	ExitCode  int    // Process exit code, or HTTP status code for HTTP jobs
+	Violation string // e.g. "killed: RLIMIT_CPU exceeded"
+}
+
+// Shell is the interpreter used to run job commands
+var Shell = "/bin/sh"
+
+// Run executes a shell command inside the given sandbox and waits for it to finish.
+// A non-nil error is returned when the command could not be started or exited unsuccessfully.
+func Run(ctx context.Context, command string, sb Sandbox) (Result, error) {
+	var output bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, Shell, "-c", command)
+	cmd.Dir = sb.WorkingDir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := prepare(cmd, sb); err != nil {
+		return Result{ExitCode: -1}, fmt.Errorf("failed to prepare sandbox: %v", err)
+	}
+
+	// With resource limits the command starts through the sandbox launcher (see Init), which sets
+	// them before executing the shell, so they bind the command and every process it starts
+	if err := cmd.Start(); err != nil {
+		return Result{ExitCode: -1}, fmt.Errorf("failed to start command: %v", err)
+	}
+
+	err := cmd.Wait()
+
+	result := Result{
+		Output:   output.String(),
+		ExitCode: cmd.ProcessState.ExitCode(),
+	}
+	result.Violation = detectViolation(cmd.ProcessState, sb)
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if result.Violation != "" {
+			return result, errors.New(result.Violation)
+		}
+		if errors.As(err, &exitErr) {
+			return result, fmt.Errorf("command exited with status %d", result.ExitCode)
+		}
+		return result, err
+	}
+
+	return result, nil
+}
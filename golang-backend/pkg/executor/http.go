@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"crontab/pkg/tracing"
+)
+
+// HTTPRequest describes the call made by an HTTP job
+type HTTPRequest struct {
+	Method  string
+	URL     string
+	Headers map[string]string
+	Body    string
+}
+
+// HTTPTimeout bounds the duration of a single HTTP job call
+var HTTPTimeout = 5 * time.Minute
+
+// maxResponseOutput is how much of the response body is kept as run output
+const maxResponseOutput = 64 * 1024
+
+// RunHTTP performs the request of an HTTP job. The trace context of ctx is
+// propagated to the endpoint through the W3C traceparent header.
+// Responses with a status code of 400 or above are reported as errors.
+func RunHTTP(ctx context.Context, request HTTPRequest) (Result, error) {
+	method := strings.ToUpper(request.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, HTTPTimeout)
+	defer cancel()
+
+	var body io.Reader
+	if request.Body != "" {
+		body = strings.NewReader(request.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, request.URL, body)
+	if err != nil {
+		return Result{ExitCode: -1}, fmt.Errorf("failed to build request: %v", err)
+	}
+	for key, value := range request.Headers {
+		req.Header.Set(key, value)
+	}
+	tracing.InjectHTTP(ctx, req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Result{ExitCode: -1}, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	output, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseOutput))
+	if err != nil {
+		return Result{ExitCode: resp.StatusCode}, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	result := Result{
+		Output:   string(output),
+		ExitCode: resp.StatusCode,
+	}
+	if resp.StatusCode >= 400 {
+		return result, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"crontab/pkg/tracing"
+)
+
+func TestRunHTTPForwardsRequestAndTraceContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(exporter, "crontab-test")
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	var method, token, body, traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, token, body = r.Method, r.Header.Get("X-Token"), string(data)
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte("done"))
+	}))
+	defer server.Close()
+
+	ctx, span := tracing.Tracer().Start(context.Background(), "job.command")
+	result, err := RunHTTP(ctx, HTTPRequest{
+		Method:  "post",
+		URL:     server.URL,
+		Headers: map[string]string{"X-Token": "abc"},
+		Body:    `{"ping":true}`,
+	})
+	span.End()
+	if err != nil {
+		t.Fatalf("RunHTTP: %v", err)
+	}
+
+	if result.ExitCode != http.StatusOK || result.Output != "done" {
+		t.Errorf("result = %+v, want status 200 with output \"done\"", result)
+	}
+	if method != http.MethodPost || token != "abc" || body != `{"ping":true}` {
+		t.Errorf("endpoint got method=%q token=%q body=%q", method, token, body)
+	}
+
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	want := "00-" + spans[0].SpanContext.TraceID().String() + "-" + spans[0].SpanContext.SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("traceparent = %q, want %q", traceparent, want)
+	}
+}
+
+func TestRunHTTPFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("maintenance"))
+	}))
+	defer server.Close()
+
+	result, err := RunHTTP(context.Background(), HTTPRequest{URL: server.URL})
+	if err == nil {
+		t.Fatal("RunHTTP succeeded for a 503 response")
+	}
+	if result.ExitCode != http.StatusServiceUnavailable || result.Output != "maintenance" {
+		t.Errorf("result = %+v, want status 503 with the response body", result)
+	}
+}
@@ -0,0 +1,194 @@
+//go:build linux
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// launcherName is the program name this executable is started with to launch a job with resource limits
+const launcherName = "crontab-sandbox"
+
+// Init runs the sandbox launcher when the process was started as one: it sets the resource limits
+// passed on the command line on itself and replaces itself with the job's command, so the limits
+// are in place before the job runs. Programs that run shell jobs must call Init first thing in main.
+// It returns without doing anything in every other process.
+func Init() {
+	if len(os.Args) == 0 || os.Args[0] != launcherName {
+		return
+	}
+	err := launch(os.Args[1:])
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(126)
+}
+
+// launch sets the limits in args and executes the command that follows them. It only returns on failure.
+func launch(args []string) error {
+	// CPU seconds, memory bytes, open files, processes, program path, program arguments
+	if len(args) < 6 {
+		return fmt.Errorf("expected limits and a command, got %q", args)
+	}
+	var values [4]uint64
+	for i := range values {
+		value, err := strconv.ParseUint(args[i], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid limit %q", args[i])
+		}
+		values[i] = value
+	}
+	sb := Sandbox{CPUSeconds: values[0], MemoryBytes: values[1], MaxOpenFiles: values[2], MaxProcesses: values[3]}
+	if err := setLimits(sb); err != nil {
+		return fmt.Errorf("failed to apply resource limits: %v", err)
+	}
+	return syscall.Exec(args[4], args[5:], os.Environ())
+}
+
+// prepare configures the process attributes (credentials) before it is spawned, and has it
+// started through the launcher when the sandbox sets resource limits
+func prepare(cmd *exec.Cmd, sb Sandbox) error {
+	if sb.User != "" || sb.Group != "" {
+		credential, err := lookupCredential(sb)
+		if err != nil {
+			return err
+		}
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: credential}
+	}
+
+	if sb.HasLimits() {
+		self, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to find the sandbox launcher: %v", err)
+		}
+		args := []string{
+			launcherName,
+			strconv.FormatUint(sb.CPUSeconds, 10),
+			strconv.FormatUint(sb.MemoryBytes, 10),
+			strconv.FormatUint(sb.MaxOpenFiles, 10),
+			strconv.FormatUint(sb.MaxProcesses, 10),
+			cmd.Path,
+		}
+		cmd.Args = append(args, cmd.Args...)
+		cmd.Path = self
+	}
+
+	return nil
+}
+
+// lookupCredential resolves the user and group of a sandbox to the IDs the process runs as.
+// The supplementary groups are those of the user, or none when only a group is set, so the
+// command never keeps the groups of the server.
+func lookupCredential(sb Sandbox) (*syscall.Credential, error) {
+	uid := uint32(os.Getuid())
+	gid := uint32(os.Getgid())
+	groups := []uint32{}
+
+	if sb.User != "" {
+		u, err := user.Lookup(sb.User)
+		if err != nil {
+			return nil, fmt.Errorf("unknown user %q: %v", sb.User, err)
+		}
+		id, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uid %q of user %q", u.Uid, sb.User)
+		}
+		uid = uint32(id)
+		primary, err := strconv.ParseUint(u.Gid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gid %q of user %q", u.Gid, sb.User)
+		}
+		gid = uint32(primary)
+
+		groupIDs, err := u.GroupIds()
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up the groups of user %q: %v", sb.User, err)
+		}
+		for _, groupID := range groupIDs {
+			id, err := strconv.ParseUint(groupID, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid gid %q of user %q", groupID, sb.User)
+			}
+			groups = append(groups, uint32(id))
+		}
+	}
+
+	if sb.Group != "" {
+		g, err := user.LookupGroup(sb.Group)
+		if err != nil {
+			return nil, fmt.Errorf("unknown group %q: %v", sb.Group, err)
+		}
+		id, err := strconv.ParseUint(g.Gid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gid %q of group %q", g.Gid, sb.Group)
+		}
+		gid = uint32(id)
+	}
+
+	return &syscall.Credential{Uid: uid, Gid: gid, Groups: groups}, nil
+}
+
+// setLimits sets the resource limits of the current process, which its command inherits
+func setLimits(sb Sandbox) error {
+	limits := []struct {
+		resource int
+		value    uint64
+		hard     uint64
+	}{
+		{unix.RLIMIT_NPROC, sb.MaxProcesses, sb.MaxProcesses},
+		{unix.RLIMIT_NOFILE, sb.MaxOpenFiles, sb.MaxOpenFiles},
+		// The soft CPU limit delivers SIGXCPU, the hard limit one second later SIGKILL
+		{unix.RLIMIT_CPU, sb.CPUSeconds, sb.CPUSeconds + 1},
+		// Last, as the launcher itself must not run out of memory
+		{unix.RLIMIT_AS, sb.MemoryBytes, sb.MemoryBytes},
+	}
+
+	for _, l := range limits {
+		if l.value == 0 {
+			continue
+		}
+
+		rlimit := unix.Rlimit{Cur: l.value, Max: l.hard}
+		if err := unix.Setrlimit(l.resource, &rlimit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// detectViolation inspects how a process ended to determine whether a resource limit killed it
+func detectViolation(state *os.ProcessState, sb Sandbox) string {
+	if state == nil {
+		return ""
+	}
+
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+
+	switch status.Signal() {
+	case syscall.SIGXCPU:
+		return "killed: RLIMIT_CPU exceeded"
+	case syscall.SIGXFSZ:
+		return "killed: RLIMIT_FSIZE exceeded"
+	case syscall.SIGKILL:
+		// A SIGKILL after consuming the full CPU budget comes from the hard RLIMIT_CPU
+		if sb.CPUSeconds > 0 && uint64((state.UserTime()+state.SystemTime()).Seconds()) >= sb.CPUSeconds {
+			return "killed: RLIMIT_CPU exceeded"
+		}
+	case syscall.SIGSEGV, syscall.SIGABRT:
+		// Allocation failures under RLIMIT_AS typically surface as a crash
+		if sb.MemoryBytes > 0 {
+			return fmt.Sprintf("killed: %s (RLIMIT_AS %d bytes may have been exceeded)", status.Signal(), sb.MemoryBytes)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,105 @@
+//go:build linux
+
+package executor
+
+import (
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary act as the sandbox launcher, like cmd/api does
+func TestMain(m *testing.M) {
+	Init()
+	os.Exit(m.Run())
+}
+
+func TestRunWithoutSandbox(t *testing.T) {
+	result, err := Run(context.Background(), "echo hello", Sandbox{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Output != "hello\n" || result.ExitCode != 0 {
+		t.Errorf("Run = %+v", result)
+	}
+}
+
+func TestRunReportsExitStatus(t *testing.T) {
+	result, err := Run(context.Background(), "exit 3", Sandbox{})
+	if err == nil || result.ExitCode != 3 {
+		t.Errorf("Run = %+v, %v; want exit status 3", result, err)
+	}
+}
+
+func TestLimitsApplyBeforeTheCommandRuns(t *testing.T) {
+	sb := Sandbox{CPUSeconds: 7, MemoryBytes: 512 << 20, MaxOpenFiles: 32, MaxProcesses: 4096}
+
+	// ulimit runs immediately, so it only sees the limits when they are set before the shell starts;
+	// the nested shell checks that processes started by the command inherit them
+	result, err := Run(context.Background(), "ulimit -t; ulimit -v; ulimit -n; sh -c 'ulimit -n'", sb)
+	if err != nil {
+		t.Fatalf("Run failed: %v (output %q)", err, result.Output)
+	}
+	want := "7\n524288\n32\n32\n"
+	if result.Output != want {
+		t.Errorf("limits seen by the command = %q, want %q", result.Output, want)
+	}
+}
+
+func TestRunRecordsCPULimitViolation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	result, err := Run(ctx, "while :; do :; done", Sandbox{CPUSeconds: 1})
+	if err == nil {
+		t.Fatal("Run succeeded, want the CPU limit to kill the command")
+	}
+	if result.Violation != "killed: RLIMIT_CPU exceeded" {
+		t.Errorf("Violation = %q, error %v", result.Violation, err)
+	}
+}
+
+func TestRunRejectsUnknownUser(t *testing.T) {
+	_, err := Run(context.Background(), "true", Sandbox{User: "no-such-user-crontab"})
+	if err == nil || !strings.Contains(err.Error(), "unknown user") {
+		t.Errorf("Run error = %v, want an unknown user error", err)
+	}
+}
+
+func TestRunAsUserDropsServerGroups(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("switching users needs root")
+	}
+	// Give the server a supplementary group the command must not inherit
+	previous, err := syscall.Getgroups()
+	if err != nil {
+		t.Fatalf("Getgroups failed: %v", err)
+	}
+	if err := syscall.Setgroups(append(previous, 4242)); err != nil {
+		t.Fatalf("Setgroups failed: %v", err)
+	}
+	defer syscall.Setgroups(previous)
+
+	result, err := Run(context.Background(), "id -u; id -G", Sandbox{User: "nobody"})
+	if err != nil {
+		t.Fatalf("Run failed: %v (output %q)", err, result.Output)
+	}
+	lines := strings.Split(strings.TrimSpace(result.Output), "\n")
+	if len(lines) != 2 || lines[0] == "0" {
+		t.Fatalf("command ran as %q, want nobody", result.Output)
+	}
+	for _, group := range strings.Fields(lines[1]) {
+		if group == "4242" {
+			t.Errorf("command kept a group of the server: groups %q", lines[1])
+		}
+	}
+}
+
+func TestLaunchRejectsInvalidLimits(t *testing.T) {
+	if err := launch([]string{"x", "0", "0", "0", "/bin/sh", "sh", "-c", "true"}); err == nil || !strings.Contains(err.Error(), "invalid limit") {
+		t.Errorf("launch error = %v, want an invalid limit error", err)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+package executor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+var errUnsupported = errors.New("run-as user and resource limits are only supported on Linux")
+
+// Init does nothing on platforms without resource limits
+func Init() {}
+
+// prepare rejects credential changes and resource limits on platforms without support
+func prepare(cmd *exec.Cmd, sb Sandbox) error {
+	if sb.User != "" || sb.Group != "" || sb.HasLimits() {
+		return errUnsupported
+	}
+	return nil
+}
+
+// detectViolation is a no-op on platforms without resource limits
+func detectViolation(state *os.ProcessState, sb Sandbox) string {
+	return ""
+}
@@ -2,6 +2,7 @@
 package logger
 
 import (
+	"io"
 	"log"
 	"os"
 	
@@ -11,6 +12,7 @@ import (
 // Logger provides structured logging capabilities
 type Logger struct {
 	infoLogger  *log.Logger
+	warnLogger  *log.Logger
 	errorLogger *log.Logger
 	debugLogger *log.Logger
 	isDebug     bool
@@ -22,17 +24,33 @@ func New(cfg *config.Config) *Logger {
 	
 	return &Logger{
 		infoLogger:  log.New(os.Stdout, "[INFO] ", log.Ldate|log.Ltime),
+		warnLogger:  log.New(os.Stderr, "[WARN] ", log.Ldate|log.Ltime),
 		errorLogger: log.New(os.Stderr, "[ERROR] ", log.Ldate|log.Ltime|log.Lshortfile),
 		debugLogger: log.New(os.Stdout, "[DEBUG] ", log.Ldate|log.Ltime|log.Lshortfile),
 		isDebug:     isDebug,
 	}
 }
 
+// Discard returns a Logger that drops every message
+func Discard() *Logger {
+	return &Logger{
+		infoLogger:  log.New(io.Discard, "", 0),
+		warnLogger:  log.New(io.Discard, "", 0),
+		errorLogger: log.New(io.Discard, "", 0),
+		debugLogger: log.New(io.Discard, "", 0),
+	}
+}
+
 // Info logs an informational message
 func (l *Logger) Info(msg string, args ...interface{}) {
 	l.infoLogger.Printf(msg, args...)
 }
 
+// Warn logs a message about a risky but working setup
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.warnLogger.Printf(msg, args...)
+}
+
 // Error logs an error message
 func (l *Logger) Error(msg string, args ...interface{}) {
 	l.errorLogger.Printf(msg, args...)
@@ -0,0 +1,72 @@
+// Package mergepatch implements JSON Merge Patch (RFC 7396).
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ErrNotObject is returned by ApplyObject when the patch is not a JSON object
+var ErrNotObject = errors.New("merge patch must be a JSON object")
+
+// Apply merges patch into the original document and returns the result.
+// Members of patch objects replace those of the original, null members remove them
+// and any other patch value, arrays included, replaces the original value as a whole.
+func Apply(original, patch []byte) ([]byte, error) {
+	var target interface{}
+	if len(bytes.TrimSpace(original)) > 0 {
+		if err := decode(original, &target); err != nil {
+			return nil, err
+		}
+	}
+	var p interface{}
+	if err := decode(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(target, p))
+}
+
+// ApplyObject is Apply for patches that must be objects, such as patches of API resources
+func ApplyObject(original, patch []byte) ([]byte, error) {
+	var p interface{}
+	if err := decode(patch, &p); err != nil {
+		return nil, err
+	}
+	if _, ok := p.(map[string]interface{}); !ok {
+		return nil, ErrNotObject
+	}
+	return Apply(original, patch)
+}
+
+func merge(target, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	result, ok := target.(map[string]interface{})
+	if !ok {
+		result = map[string]interface{}{}
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(result, name)
+			continue
+		}
+		result[name] = merge(result[name], value)
+	}
+	return result
+}
+
+// decode parses a JSON document, keeping numbers as written
+func decode(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("unexpected data after the JSON document")
+	}
+	return nil
+}
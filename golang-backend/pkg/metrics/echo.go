@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// EchoMiddleware records request counts and latencies per route template.
+// Requests that match no route are grouped under "unmatched" to keep the series bounded.
+func (m *Metrics) EchoMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if m == nil {
+				return next(c)
+			}
+
+			start := time.Now()
+			err := next(c)
+
+			// Let Echo write the error response so the final status code is known
+			if err != nil {
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			method := c.Request().Method
+			code := strconv.Itoa(c.Response().Status)
+
+			m.httpRequests.WithLabelValues(method, route, code).Inc()
+			m.httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import "sync"
+
+// OverflowLabel is reported for jobs and projects beyond the label cardinality limit
+const OverflowLabel = "_other"
+
+// labelLimiter caps the number of distinct values a label may take.
+// The first max values seen keep their own series, later ones share OverflowLabel.
+type labelLimiter struct {
+	max    int
+	values map[string]struct{}
+	mutex  sync.Mutex
+}
+
+func newLabelLimiter(max int) *labelLimiter {
+	return &labelLimiter{
+		max:    max,
+		values: make(map[string]struct{}),
+	}
+}
+
+// value returns the label value to use for v
+func (l *labelLimiter) value(v string) string {
+	if l.max <= 0 {
+		return v
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, exists := l.values[v]; exists {
+		return v
+	}
+	if len(l.values) >= l.max {
+		return OverflowLabel
+	}
+
+	l.values[v] = struct{}{}
+	return v
+}
+
+// forget releases a value so its slot can be reused, returning whether it was tracked
+func (l *labelLimiter) forget(v string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, exists := l.values[v]; !exists {
+		return false
+	}
+	delete(l.values, v)
+	return true
+}
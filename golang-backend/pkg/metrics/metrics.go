@@ -0,0 +1,246 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"crontab/internal/config"
+)
+
+const namespace = "crontab"
+
+// Job label modes
+const (
+	JobLabelID   = "id"   // label series with the job ID
+	JobLabelName = "name" // label series with the job name
+	JobLabelNone = "none" // drop the job label, aggregate by project only
+)
+
+// Metrics holds the Prometheus collectors of the service.
+// All methods are safe to call on a nil *Metrics, which records nothing.
+type Metrics struct {
+	registry *prometheus.Registry
+	jobLabel string
+	jobs     *labelLimiter
+	projects *labelLimiter
+
+	executions   *prometheus.CounterVec
+	runDuration  *prometheus.HistogramVec
+	scheduleLag  *prometheus.HistogramVec
+	running      prometheus.Gauge
+	queueDepth   prometheus.Gauge
+	scheduled    prometheus.Gauge
+	lastSuccess  *prometheus.GaugeVec
+	slaBreaches  *prometheus.CounterVec
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+}
+
+// New creates the collectors and registers them on a dedicated registry.
+//
+// Label cardinality is controlled through:
+//   - METRICS_JOB_LABEL: "id" (default), "name" or "none"
+//   - METRICS_MAX_JOB_SERIES: distinct job label values before falling back to "_other" (default 500, 0 = unlimited)
+//   - METRICS_MAX_PROJECT_SERIES: the same for the project label (default 100, 0 = unlimited)
+func New(cfg *config.Config) *Metrics {
+	jobLabel := strings.ToLower(cfg.GetString("METRICS_JOB_LABEL", JobLabelID))
+	if jobLabel != JobLabelName && jobLabel != JobLabelNone {
+		jobLabel = JobLabelID
+	}
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		jobLabel: jobLabel,
+		jobs:     newLabelLimiter(cfg.GetInt("METRICS_MAX_JOB_SERIES", 500)),
+		projects: newLabelLimiter(cfg.GetInt("METRICS_MAX_PROJECT_SERIES", 100)),
+
+		executions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "job_executions_total",
+			Help:      "Number of finished job executions by job, project and status.",
+		}, []string{"job", "project", "status"}),
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "job_run_duration_seconds",
+			Help:      "Duration of job executions.",
+			Buckets:   []float64{0.1, 0.5, 1, 5, 15, 30, 60, 300, 900, 1800, 3600},
+		}, []string{"job", "project"}),
+		scheduleLag: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "job_schedule_lag_seconds",
+			Help:      "Delay between the scheduled time of a run and its actual start.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 15, 60},
+		}, []string{"project"}),
+		running: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "jobs_running",
+			Help:      "Number of job executions currently in progress.",
+		}),
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "job_queue_depth",
+			Help:      "Number of due executions waiting for a free execution slot.",
+		}),
+		scheduled: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "scheduled_entries",
+			Help:      "Number of jobs registered with the cron scheduler.",
+		}),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "job_last_success_timestamp_seconds",
+			Help:      "Unix timestamp of the last successful execution of a job.",
+		}, []string{"job", "project"}),
+		slaBreaches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "job_sla_breaches_total",
+			Help:      "Number of executions still running past their job's expected duration.",
+		}, []string{"job", "project"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route and status code.",
+		}, []string{"method", "route", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.executions,
+		m.runDuration,
+		m.scheduleLag,
+		m.running,
+		m.queueDepth,
+		m.scheduled,
+		m.lastSuccess,
+		m.slaBreaches,
+		m.httpRequests,
+		m.httpDuration,
+	)
+
+	return m
+}
+
+// Registry returns the registry holding all collectors
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler returns the HTTP handler serving the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// jobLabelValue maps a job to its label value according to the cardinality settings
+func (m *Metrics) jobLabelValue(jobID, jobName string) string {
+	switch m.jobLabel {
+	case JobLabelNone:
+		return ""
+	case JobLabelName:
+		return m.jobs.value(jobName)
+	default:
+		return m.jobs.value(jobID)
+	}
+}
+
+// ObserveRun records a finished execution
+func (m *Metrics) ObserveRun(jobID, jobName, projectID, status string, duration time.Duration, finishedAt time.Time) {
+	if m == nil {
+		return
+	}
+
+	job, project := m.jobLabelValue(jobID, jobName), m.projects.value(projectID)
+	m.executions.WithLabelValues(job, project, status).Inc()
+	m.runDuration.WithLabelValues(job, project).Observe(duration.Seconds())
+
+	if status == "success" {
+		m.lastSuccess.WithLabelValues(job, project).Set(float64(finishedAt.Unix()))
+	}
+}
+
+// SLABreached counts an execution that outlived its job's expected duration
+func (m *Metrics) SLABreached(jobID, jobName, projectID string) {
+	if m == nil {
+		return
+	}
+	m.slaBreaches.WithLabelValues(m.jobLabelValue(jobID, jobName), m.projects.value(projectID)).Inc()
+}
+
+// ObserveScheduleLag records how late a scheduled run started
+func (m *Metrics) ObserveScheduleLag(projectID string, lag time.Duration) {
+	if m == nil {
+		return
+	}
+	if lag < 0 {
+		lag = 0
+	}
+	m.scheduleLag.WithLabelValues(m.projects.value(projectID)).Observe(lag.Seconds())
+}
+
+// RunStarted increments the running executions gauge
+func (m *Metrics) RunStarted() {
+	if m == nil {
+		return
+	}
+	m.running.Inc()
+}
+
+// RunFinished decrements the running executions gauge
+func (m *Metrics) RunFinished() {
+	if m == nil {
+		return
+	}
+	m.running.Dec()
+}
+
+// Enqueued increments the queue depth gauge
+func (m *Metrics) Enqueued() {
+	if m == nil {
+		return
+	}
+	m.queueDepth.Inc()
+}
+
+// Dequeued decrements the queue depth gauge
+func (m *Metrics) Dequeued() {
+	if m == nil {
+		return
+	}
+	m.queueDepth.Dec()
+}
+
+// SetScheduledEntries sets the number of jobs registered with the cron scheduler
+func (m *Metrics) SetScheduledEntries(count int) {
+	if m == nil {
+		return
+	}
+	m.scheduled.Set(float64(count))
+}
+
+// ForgetJob drops the per-job series of a deleted job and frees its label slot.
+// It only applies when jobs are labelled by ID, since names may be shared.
+func (m *Metrics) ForgetJob(jobID string) {
+	if m == nil || m.jobLabel != JobLabelID {
+		return
+	}
+	if !m.jobs.forget(jobID) {
+		return
+	}
+
+	labels := prometheus.Labels{"job": jobID}
+	m.executions.DeletePartialMatch(labels)
+	m.runDuration.DeletePartialMatch(labels)
+	m.lastSuccess.DeletePartialMatch(labels)
+	m.slaBreaches.DeletePartialMatch(labels)
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"crontab/internal/config"
+)
+
+func TestProjectLabelIsBounded(t *testing.T) {
+	t.Setenv("METRICS_MAX_PROJECT_SERIES", "2")
+	m := New(config.New())
+
+	for _, project := range []string{"p1", "p2", "p3", "p4"} {
+		m.ObserveRun("job-"+project, "", project, "success", time.Second, time.Now())
+		m.ObserveScheduleLag(project, time.Second)
+	}
+
+	if got := testutil.CollectAndCount(m.scheduleLag); got != 3 {
+		t.Errorf("%d schedule lag series, want p1, p2 and %s", got, OverflowLabel)
+	}
+	if got := testutil.ToFloat64(m.executions.WithLabelValues("job-p4", OverflowLabel, "success")); got != 1 {
+		t.Errorf("executions of job-p4 under %s = %v, want 1", OverflowLabel, got)
+	}
+}
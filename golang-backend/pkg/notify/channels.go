@@ -0,0 +1,287 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"crontab/internal/models"
+)
+
+// chatExcerptSize keeps chat messages well below the size limits of Slack, Teams and Discord
+const chatExcerptSize = 1000
+
+// fact is a labelled value shown in chat messages
+type fact struct {
+	Name  string
+	Value string
+}
+
+// facts returns the run details shown by every chat format
+func (e Event) facts() []fact {
+	facts := []fact{
+		{"Project", e.ProjectName()},
+		{"Status", string(e.Log.Status)},
+	}
+	if e.Alert != nil {
+		facts = append(facts, fact{"Alert", fmt.Sprintf("%s (%s)", e.Alert.Message, e.Alert.Status)})
+	}
+	if !e.Log.StartTime.IsZero() {
+		facts = append(facts, fact{"Started", e.Log.StartTime.UTC().Format(time.RFC3339)})
+	}
+	if e.Log.Duration > 0 {
+		facts = append(facts, fact{"Duration", fmt.Sprintf("%.2fs", e.Log.Duration)})
+	}
+	if e.Log.Trigger != "" {
+		facts = append(facts, fact{"Trigger", string(e.Log.Trigger)})
+	}
+	if e.Log.Violation != "" {
+		facts = append(facts, fact{"Violation", e.Log.Violation})
+	}
+	return facts
+}
+
+// chatDetails returns the error, or the output when there is none, shortened for chat messages
+func (e Event) chatDetails() string {
+	details := e.Log.Error
+	if details == "" {
+		details = e.Log.Output
+	}
+	if len(details) > chatExcerptSize {
+		details = "…" + details[len(details)-chatExcerptSize:]
+	}
+	return details
+}
+
+// colorInt converts the event color to the integer form used by Discord
+func (e Event) colorInt() int {
+	value, _ := strconv.ParseInt(strings.TrimPrefix(e.Color(), "#"), 16, 32)
+	return int(value)
+}
+
+// slackMessage formats an event for a Slack incoming webhook
+func slackMessage(event Event) map[string]interface{} {
+	fields := []map[string]interface{}{}
+	for _, f := range event.facts() {
+		fields = append(fields, map[string]interface{}{
+			"title": f.Name,
+			"value": f.Value,
+			"short": true,
+		})
+	}
+
+	attachment := map[string]interface{}{
+		"color":  event.Color(),
+		"title":  event.Title(),
+		"fields": fields,
+		"ts":     time.Now().Unix(),
+	}
+	if link := event.JobURL(); link != "" {
+		attachment["title_link"] = link
+	}
+	if details := event.chatDetails(); details != "" {
+		attachment["text"] = "```" + details + "```"
+	}
+
+	return map[string]interface{}{
+		"text":        event.Title(),
+		"attachments": []interface{}{attachment},
+	}
+}
+
+// teamsMessage formats an event as a Microsoft Teams connector MessageCard
+func teamsMessage(event Event) map[string]interface{} {
+	facts := []map[string]string{}
+	for _, f := range event.facts() {
+		facts = append(facts, map[string]string{"name": f.Name, "value": f.Value})
+	}
+
+	section := map[string]interface{}{
+		"activityTitle": event.Title(),
+		"facts":         facts,
+		"markdown":      true,
+	}
+	if details := event.chatDetails(); details != "" {
+		section["text"] = "<pre>" + html.EscapeString(details) + "</pre>"
+	}
+
+	message := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    event.Title(),
+		"themeColor": strings.TrimPrefix(event.Color(), "#"),
+		"sections":   []interface{}{section},
+	}
+	if link := event.JobURL(); link != "" {
+		message["potentialAction"] = []interface{}{map[string]interface{}{
+			"@type": "OpenUri",
+			"name":  "View job",
+			"targets": []map[string]string{
+				{"os": "default", "uri": link},
+			},
+		}}
+	}
+
+	return message
+}
+
+// discordMessage formats an event as a Discord webhook embed
+func discordMessage(event Event) map[string]interface{} {
+	fields := []map[string]interface{}{}
+	for _, f := range event.facts() {
+		fields = append(fields, map[string]interface{}{
+			"name":   f.Name,
+			"value":  f.Value,
+			"inline": true,
+		})
+	}
+
+	embed := map[string]interface{}{
+		"title":     event.Title(),
+		"color":     event.colorInt(),
+		"fields":    fields,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+	if link := event.JobURL(); link != "" {
+		embed["url"] = link
+	}
+	if details := event.chatDetails(); details != "" {
+		embed["description"] = "```" + details + "```"
+	}
+
+	return map[string]interface{}{
+		"embeds": []interface{}{embed},
+	}
+}
+
+// channelPayload builds the body posted to an HTTP based channel
+func channelPayload(channel models.NotificationChannel, eventID string, event Event) ([]byte, error) {
+	switch channel.Type {
+	case models.ChannelTypeSlack:
+		return json.Marshal(slackMessage(event))
+	case models.ChannelTypeTeams:
+		return json.Marshal(teamsMessage(event))
+	case models.ChannelTypeDiscord:
+		return json.Marshal(discordMessage(event))
+	default:
+		return json.Marshal(NewWebhookPayload(eventID, event))
+	}
+}
+
+// channelWebhook returns the request settings of an HTTP based channel
+func channelWebhook(channel models.NotificationChannel) models.WebhookSettings {
+	return models.WebhookSettings{
+		URL:     channel.Settings.URL,
+		Method:  channel.Settings.Method,
+		Headers: channel.Settings.Headers,
+		Secret:  channel.Settings.Secret,
+	}
+}
+
+// channelTarget returns the URL of an HTTP based channel as recorded with its deliveries. The
+// URLs of chat channels are credentials and only recorded with their host.
+func channelTarget(channel models.NotificationChannel) string {
+	if channel.Type == models.ChannelTypeWebhook {
+		return channel.Settings.URL
+	}
+	return models.RedactURL(channel.Settings.URL)
+}
+
+// sendToChannel delivers an event to a channel, returning the last recorded delivery
+func (d *Dispatcher) sendToChannel(ctx context.Context, channel models.NotificationChannel, event Event, maxAttempts int) (*models.NotificationDelivery, error) {
+	eventID := models.NewID()
+	template := models.NotificationDelivery{
+		EventID:   eventID,
+		JobID:     event.Job.ID,
+		JobLogID:  event.Log.ID,
+		ChannelID: channel.ID,
+		Channel:   string(channel.Type),
+		Event:     string(event.Type),
+	}
+
+	if channel.Type == models.ChannelTypeEmail {
+		template.Target = strings.Join(channel.Settings.Recipients, ", ")
+		template.Attempt = 1
+
+		if d.email == nil {
+			err := fmt.Errorf("SMTP is not configured")
+			d.record(ctx, &template, err)
+			return &template, err
+		}
+
+		err := d.email.Send(ctx, channel.Settings.Recipients, event)
+		d.record(ctx, &template, err)
+		return &template, err
+	}
+
+	body, err := channelPayload(channel, eventID, event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %v", err)
+	}
+	template.Target = channelTarget(channel)
+	template.Payload = string(body)
+
+	delivery := d.deliverWebhook(ctx, channelWebhook(channel), template, maxAttempts)
+	if delivery.Status == models.DeliveryStatusFailed {
+		return delivery, fmt.Errorf("%s", delivery.Error)
+	}
+	return delivery, nil
+}
+
+// sendSubscriptions delivers the events to every enabled channel the job subscribed to.
+// A channel receives at most one message per run: the first event in the list it wants.
+func (d *Dispatcher) sendSubscriptions(ctx context.Context, event Event, eventTypes []EventType) {
+	var subscriptions []models.JobChannelSubscription
+	if err := d.db.WithContext(ctx).Preload("Channel").Where("job_id = ?", event.Job.ID).Find(&subscriptions).Error; err != nil {
+		d.logger.Error("Failed to load notification subscriptions for job %s: %v", event.Job.Name, err)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		if subscription.Channel == nil || !subscription.Channel.Enabled {
+			continue
+		}
+
+		for _, eventType := range eventTypes {
+			if !subscription.Wants(string(eventType)) {
+				continue
+			}
+
+			channelEvent := event
+			channelEvent.Type = eventType
+			d.sendToChannel(ctx, *subscription.Channel, channelEvent, d.webhooks.MaxAttempts())
+			break
+		}
+	}
+}
+
+// SendTest sends a sample notification to a channel once and returns the recorded delivery
+func (d *Dispatcher) SendTest(ctx context.Context, channel models.NotificationChannel) (*models.NotificationDelivery, error) {
+	now := time.Now()
+	event := Event{
+		Type: EventTest,
+		Job: models.Job{
+			ID:        "test",
+			Name:      "Test notification",
+			ProjectID: channel.ProjectID,
+			Type:      models.JobTypeShell,
+		},
+		Log: models.JobLog{
+			ID:        "test",
+			JobID:     "test",
+			Status:    models.JobStatusSuccess,
+			StartTime: now,
+			EndTime:   now,
+			Trigger:   models.TriggerManual,
+			Output:    "This is a test notification sent from CronTab.",
+		},
+		BaseURL: d.baseURL,
+	}
+	d.db.WithContext(ctx).First(&event.Project, "id = ?", channel.ProjectID)
+
+	return d.sendToChannel(ctx, channel, event, 1)
+}
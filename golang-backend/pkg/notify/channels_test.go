@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"crontab/internal/config"
+	"crontab/internal/models"
+	"crontab/pkg/logger"
+)
+
+// receivedRequest is a request received by the local webhook server
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// startWebhookServer runs a local HTTP server answering with status and returns the requests it receives
+func startWebhookServer(t *testing.T, status int) (*httptest.Server, <-chan receivedRequest) {
+	t.Helper()
+	requests := make(chan receivedRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- receivedRequest{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func testDispatcher(t *testing.T) (*Dispatcher, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "notify.db")), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Project{}, &models.NotificationDelivery{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	cfg := config.New()
+	cfg.Set("WEBHOOK_RETRY_BACKOFF_SECONDS", "0")
+	return NewDispatcher(db, logger.New(cfg), cfg), db
+}
+
+func webhookChannel(url string) models.NotificationChannel {
+	channel := models.NotificationChannel{
+		ID:        "channel-1",
+		ProjectID: "project-1",
+		Name:      "Ops",
+		Type:      models.ChannelTypeWebhook,
+		Enabled:   true,
+		Settings: models.ChannelSettings{
+			URL:     url,
+			Method:  "POST",
+			Headers: map[string]string{"X-Team": "ops"},
+		},
+	}
+	channel.BeforeSave(nil) // Generates the signing secret
+	return channel
+}
+
+func TestSendTestDeliversSignedPayloadToWebhookChannel(t *testing.T) {
+	server, requests := startWebhookServer(t, http.StatusOK)
+	dispatcher, db := testDispatcher(t)
+	channel := webhookChannel(server.URL)
+
+	delivery, err := dispatcher.SendTest(context.Background(), channel)
+	if err != nil {
+		t.Fatalf("SendTest failed: %v", err)
+	}
+	if delivery.Status != models.DeliveryStatusSent || delivery.ChannelID != channel.ID {
+		t.Errorf("delivery = %+v", delivery)
+	}
+
+	request := <-requests
+	timestamp, err := strconv.ParseInt(request.header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("bad %s header: %v", HeaderTimestamp, err)
+	}
+	if got, want := request.header.Get(HeaderSignature), Sign(channel.Settings.Secret, timestamp, request.body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if request.header.Get("X-Team") != "ops" {
+		t.Errorf("custom header missing: %v", request.header)
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if payload.Type != "run."+string(EventTest) {
+		t.Errorf("type = %q, want run.%s", payload.Type, EventTest)
+	}
+
+	var recorded []models.NotificationDelivery
+	db.Find(&recorded, "channel_id = ?", channel.ID)
+	if len(recorded) != 1 || recorded[0].Status != models.DeliveryStatusSent {
+		t.Errorf("recorded deliveries = %+v", recorded)
+	}
+}
+
+func TestSendTestRecordsFailedWebhookDelivery(t *testing.T) {
+	server, requests := startWebhookServer(t, http.StatusInternalServerError)
+	dispatcher, _ := testDispatcher(t)
+
+	delivery, err := dispatcher.SendTest(context.Background(), webhookChannel(server.URL))
+	if err == nil {
+		t.Fatal("SendTest succeeded against a failing server")
+	}
+	if delivery.Status != models.DeliveryStatusFailed || delivery.ResponseStatus != http.StatusInternalServerError {
+		t.Errorf("delivery = %+v", delivery)
+	}
+	if len(requests) != 1 {
+		t.Errorf("%d requests, want a single attempt", len(requests))
+	}
+}
+
+func TestTeamsMessageEscapesDetails(t *testing.T) {
+	message := teamsMessage(Event{Type: EventFailure, Log: models.JobLog{Output: "<b>1 < 2 & done</b>"}})
+
+	section := message["sections"].([]interface{})[0].(map[string]interface{})
+	if got, want := section["text"], "<pre>&lt;b&gt;1 &lt; 2 &amp; done&lt;/b&gt;</pre>"; got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+}
+
+func TestChatChannelDeliveriesDoNotRecordTheURL(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // Deliveries fail with an error that quotes the URL
+	dispatcher, db := testDispatcher(t)
+	channel := models.NotificationChannel{
+		ID:       "channel-2",
+		Name:     "Chat",
+		Type:     models.ChannelTypeTeams,
+		Enabled:  true,
+		Settings: models.ChannelSettings{URL: server.URL + "/webhookb2/secret-token"},
+	}
+
+	if _, err := dispatcher.SendTest(context.Background(), channel); err == nil {
+		t.Fatal("SendTest succeeded against a closed server")
+	}
+
+	var recorded []models.NotificationDelivery
+	db.Find(&recorded, "channel_id = ?", channel.ID)
+	if len(recorded) == 0 {
+		t.Fatal("no delivery recorded")
+	}
+	for _, delivery := range recorded {
+		if strings.Contains(delivery.Target, "secret-token") || strings.Contains(delivery.Error, "secret-token") {
+			t.Errorf("delivery records the channel URL: target %q, error %q", delivery.Target, delivery.Error)
+		}
+		if delivery.Target != server.URL+"/"+models.RedactedValue {
+			t.Errorf("target = %q, want the host only", delivery.Target)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"crontab/internal/config"
+)
+
+// SMTP TLS modes
+const (
+	SMTPTLSNone     = "none"     // plain connection, e.g. a local SMTP sink
+	SMTPTLSStartTLS = "starttls" // upgrade with STARTTLS when the server offers it
+	SMTPTLSImplicit = "tls"      // TLS from the first byte (port 465)
+)
+
+// SMTPConfig holds the connection settings of the mail server
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	TLSMode  string
+	Timeout  time.Duration
+}
+
+// SMTPConfigFromConfig reads the SMTP_* settings
+func SMTPConfigFromConfig(cfg *config.Config) SMTPConfig {
+	return SMTPConfig{
+		Host:     cfg.GetString("SMTP_HOST", ""),
+		Port:     cfg.GetInt("SMTP_PORT", 587),
+		Username: cfg.GetString("SMTP_USERNAME", ""),
+		Password: cfg.GetString("SMTP_PASSWORD", ""),
+		From:     cfg.GetString("SMTP_FROM", "crontab@localhost"),
+		TLSMode:  strings.ToLower(cfg.GetString("SMTP_TLS", SMTPTLSStartTLS)),
+		Timeout:  time.Duration(cfg.GetInt("SMTP_TIMEOUT_SECONDS", 30)) * time.Second,
+	}
+}
+
+// EmailNotifier renders run notifications and sends them over SMTP
+type EmailNotifier struct {
+	config SMTPConfig
+}
+
+// NewEmailNotifier creates an EmailNotifier
+func NewEmailNotifier(config SMTPConfig) *EmailNotifier {
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &EmailNotifier{config: config}
+}
+
+// Send renders the event and mails it to the recipients
+func (n *EmailNotifier) Send(ctx context.Context, recipients []string, event Event) error {
+	message, err := n.buildMessage(recipients, event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+	defer cancel()
+
+	return n.deliver(ctx, recipients, message)
+}
+
+// buildMessage renders a multipart/alternative message with text and HTML bodies
+func (n *EmailNotifier) buildMessage(recipients []string, event Event) ([]byte, error) {
+	subject, err := renderSubject(event)
+	if err != nil {
+		return nil, err
+	}
+	text, err := renderText(event)
+	if err != nil {
+		return nil, err
+	}
+	html, err := renderHTML(event)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		qp.Close()
+	}
+	parts.Close()
+
+	var message bytes.Buffer
+	headers := []string{
+		"From: " + headerValue(n.config.From),
+		"To: " + headerValue(strings.Join(recipients, ", ")),
+		"Subject: " + encodeHeader(subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + parts.Boundary(),
+	}
+	message.WriteString(strings.Join(headers, "\r\n"))
+	message.WriteString("\r\n\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+// deliver opens an SMTP session and transmits the message
+func (n *EmailNotifier) deliver(ctx context.Context, recipients []string, message []byte) error {
+	address := net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port))
+	dialer := &net.Dialer{}
+
+	var conn net.Conn
+	var err error
+	if n.config.TLSMode == SMTPTLSImplicit {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: n.config.Host}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %v", err)
+	}
+	defer client.Close()
+
+	if n.config.TLSMode == SMTPTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: n.config.Host}); err != nil {
+				return fmt.Errorf("STARTTLS failed: %v", err)
+			}
+		}
+	}
+
+	if n.config.Username != "" {
+		auth := smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+
+	if err := client.Mail(n.config.From); err != nil {
+		return fmt.Errorf("MAIL FROM rejected: %v", err)
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("RCPT TO %s rejected: %v", recipient, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA rejected: %v", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to write message: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message rejected: %v", err)
+	}
+
+	return client.Quit()
+}
+
+// headerValue replaces control characters with spaces, so that a value cannot end its header
+// line and inject further headers or a body
+func headerValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, value)
+}
+
+// encodeHeader makes a value safe for a header line and encodes it when it is not ASCII (RFC 2047)
+func encodeHeader(value string) string {
+	value = headerValue(value)
+	for _, r := range value {
+		if r > 127 {
+			return mime.QEncoding.Encode("utf-8", value)
+		}
+	}
+	return value
+}
@@ -0,0 +1,228 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"crontab/internal/models"
+)
+
+// smtpMessage is a message received by the SMTP sink
+type smtpMessage struct {
+	from       string
+	recipients []string
+	data       []byte
+}
+
+// startSMTPSink runs a minimal SMTP server on a local port and returns its address and the
+// messages it receives
+func startSMTPSink(t *testing.T) (string, int, <-chan smtpMessage) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan smtpMessage, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, messages)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, messages
+}
+
+func serveSMTP(conn net.Conn, messages chan<- smtpMessage) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 sink ready")
+
+	var message smtpMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO", "RSET", "NOOP":
+			tp.PrintfLine("250 ok")
+		case "MAIL":
+			message = smtpMessage{from: strings.TrimPrefix(line, "MAIL FROM:")}
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			message.recipients = append(message.recipients, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			message.data = data
+			messages <- message
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// receive waits for the next message of the sink
+func receive(t *testing.T, messages <-chan smtpMessage) smtpMessage {
+	t.Helper()
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+		return smtpMessage{}
+	}
+}
+
+func sinkNotifier(host string, port int) *EmailNotifier {
+	return NewEmailNotifier(SMTPConfig{
+		Host:    host,
+		Port:    port,
+		From:    "crontab@example.com",
+		TLSMode: SMTPTLSNone,
+		Timeout: 5 * time.Second,
+	})
+}
+
+func failedRun(name string) Event {
+	return Event{
+		Type: EventFailure,
+		Job:  models.Job{ID: "job-1", Name: name},
+		Log: models.JobLog{
+			ID:        "run-1",
+			Status:    models.JobStatusFailed,
+			StartTime: time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC),
+			Duration:  1.5,
+			Output:    "backup started\nORA-01555: snapshot too old",
+			Error:     "exit status 1",
+		},
+	}
+}
+
+// textPart returns the decoded text/plain part of a multipart/alternative message
+func textPart(t *testing.T, msg *mail.Message) string {
+	t.Helper()
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("bad Content-Type: %v", err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatalf("no text/plain part: %v", err)
+		}
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain") {
+			text, err := io.ReadAll(quotedprintable.NewReader(part))
+			if err != nil {
+				t.Fatalf("failed to decode text part: %v", err)
+			}
+			return string(text)
+		}
+	}
+}
+
+func TestEmailNotifierDeliversToSink(t *testing.T) {
+	host, port, messages := startSMTPSink(t)
+
+	err := sinkNotifier(host, port).Send(context.Background(), []string{"ops@example.com", "dev@example.com"}, failedRun("Nightly backup"))
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	message := receive(t, messages)
+	if got := strings.Join(message.recipients, ","); got != "ops@example.com,dev@example.com" {
+		t.Errorf("recipients = %q", got)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(message.data))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	if subject := msg.Header.Get("Subject"); !strings.Contains(subject, "Nightly backup") {
+		t.Errorf("Subject = %q, want the job name", subject)
+	}
+	if text := textPart(t, msg); !strings.Contains(text, "ORA-01555") {
+		t.Errorf("text body does not contain the output excerpt:\n%s", text)
+	}
+}
+
+func TestEmailNotifierEncodesNonASCIISubject(t *testing.T) {
+	host, port, messages := startSMTPSink(t)
+
+	if err := sinkNotifier(host, port).Send(context.Background(), []string{"ops@example.com"}, failedRun("Sauvegarde nocturne é")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(receive(t, messages).data))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("failed to decode Subject: %v", err)
+	}
+	if !strings.Contains(subject, "Sauvegarde nocturne é") {
+		t.Errorf("Subject = %q", subject)
+	}
+}
+
+func TestEmailNotifierRejectsHeaderInjection(t *testing.T) {
+	host, port, messages := startSMTPSink(t)
+
+	name := "Backup\r\nBcc: attacker@example.com\r\n\r\nInjected body"
+	if err := sinkNotifier(host, port).Send(context.Background(), []string{"ops@example.com"}, failedRun(name)); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	message := receive(t, messages)
+	msg, err := mail.ReadMessage(bytes.NewReader(message.data))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	if bcc := msg.Header.Get("Bcc"); bcc != "" {
+		t.Errorf("injected Bcc header: %q", bcc)
+	}
+	if subject := msg.Header.Get("Subject"); !strings.Contains(subject, "Injected body") {
+		t.Errorf("Subject = %q, want the whole name on one line", subject)
+	}
+	if len(message.recipients) != 1 {
+		t.Errorf("recipients = %v", message.recipients)
+	}
+	if _, err := msg.Header.AddressList("To"); err != nil {
+		t.Errorf("To header damaged: %v", err)
+	}
+}
+
+func TestEncodeHeaderStripsLineBreaks(t *testing.T) {
+	for _, value := range []string{"a\r\nb", "a\nb", "a\rb", "é\r\nBcc: x", "a\x00b"} {
+		if encoded := encodeHeader(value); strings.ContainsAny(encoded, "\r\n\x00") {
+			t.Errorf("encodeHeader(%q) = %q contains control characters", value, encoded)
+		}
+	}
+}
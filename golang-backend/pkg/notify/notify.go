@@ -0,0 +1,422 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"crontab/internal/config"
+	"crontab/internal/models"
+	"crontab/internal/repository"
+	"crontab/pkg/logger"
+)
+
+// EventType identifies why a notification is sent
+type EventType string
+
+const (
+	EventSuccess  EventType = models.NotifyOnSuccess
+	EventFailure  EventType = models.NotifyOnFailure
+	EventRecovery EventType = models.NotifyOnRecovery
+	EventSlow     EventType = models.NotifyOnSlow
+	EventAlert    EventType = models.NotifyOnAlert
+	EventPaused   EventType = models.NotifyOnPaused
+	EventTest     EventType = "test"
+)
+
+// outputExcerptSize is how many trailing bytes of the run output are included in notifications
+const outputExcerptSize = 2000
+
+// Event describes a finished run to notify about
+type Event struct {
+	Type    EventType
+	Job     models.Job
+	Project models.Project
+	Log     models.JobLog
+	Alert   *models.Alert // Set for alert and alert recovery events
+	BaseURL string        // Public URL of the UI, used for links
+}
+
+// Title returns a one line summary of the event
+func (e Event) Title() string {
+	switch e.Type {
+	case EventFailure:
+		if e.Log.Status == models.JobStatusMissed {
+			return fmt.Sprintf("Job %q missed its heartbeat", e.Job.Name)
+		}
+		return fmt.Sprintf("Job %q failed", e.Job.Name)
+	case EventAlert:
+		return fmt.Sprintf("Alert on job %q: %s", e.Job.Name, e.Alert.Message)
+	case EventRecovery:
+		if e.Alert != nil {
+			return fmt.Sprintf("Job %q recovered: %s", e.Job.Name, e.Alert.Message)
+		}
+		return fmt.Sprintf("Job %q recovered", e.Job.Name)
+	case EventSlow:
+		return fmt.Sprintf("Job %q is running slow", e.Job.Name)
+	case EventPaused:
+		return fmt.Sprintf("Job %q was paused: %s", e.Job.Name, e.Job.PausedReason)
+	case EventTest:
+		return "Test notification from CronTab"
+	default:
+		return fmt.Sprintf("Job %q succeeded", e.Job.Name)
+	}
+}
+
+// Color returns the hex color associated with the event
+func (e Event) Color() string {
+	switch e.Type {
+	case EventFailure, EventAlert:
+		return "#dc2626"
+	case EventSlow, EventPaused:
+		return "#d97706"
+	case EventTest:
+		return "#2563eb"
+	default:
+		return "#16a34a"
+	}
+}
+
+// ProjectName returns the project name, falling back to its ID
+func (e Event) ProjectName() string {
+	if e.Project.Name != "" {
+		return e.Project.Name
+	}
+	return e.Job.ProjectID
+}
+
+// OutputExcerpt returns the tail of the run output
+func (e Event) OutputExcerpt() string {
+	return excerpt(e.Log.Output)
+}
+
+// ErrorExcerpt returns the tail of the run error
+func (e Event) ErrorExcerpt() string {
+	return excerpt(e.Log.Error)
+}
+
+// JobURL returns a link to the job in the UI, or "" when no base URL is configured
+func (e Event) JobURL() string {
+	if e.BaseURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/jobs/%s", e.BaseURL, e.Job.ID)
+}
+
+func excerpt(s string) string {
+	if len(s) <= outputExcerptSize {
+		return s
+	}
+	return "…" + s[len(s)-outputExcerptSize:]
+}
+
+// Errors returned by Redeliver
+var (
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrNotRedeliverable = errors.New("only webhook deliveries can be redelivered")
+	ErrWebhookNotFound  = errors.New("the webhook or channel of this delivery no longer exists")
+)
+
+// Dispatcher sends notifications about finished runs and records every delivery attempt
+type Dispatcher struct {
+	db       *gorm.DB
+	store    repository.Store
+	logger   *logger.Logger
+	email    *EmailNotifier
+	webhooks *WebhookNotifier
+	baseURL  string
+}
+
+// NewDispatcher creates a Dispatcher. Email is only sent when SMTP_HOST is configured.
+func NewDispatcher(db *gorm.DB, logger *logger.Logger, cfg *config.Config) *Dispatcher {
+	d := &Dispatcher{
+		db:       db,
+		store:    repository.NewGormStore(db),
+		logger:   logger,
+		webhooks: NewWebhookNotifier(cfg),
+		baseURL:  cfg.GetString("APP_BASE_URL", ""),
+	}
+
+	if smtpConfig := SMTPConfigFromConfig(cfg); smtpConfig.Host != "" {
+		d.email = NewEmailNotifier(smtpConfig)
+	}
+
+	return d
+}
+
+// RunFinished notifies about a finished run in the background
+func (d *Dispatcher) RunFinished(job models.Job, jobLog models.JobLog) {
+	if d == nil {
+		return
+	}
+
+	eventType := EventSuccess
+	if jobLog.Status == models.JobStatusFailed || jobLog.Status == models.JobStatusMissed {
+		eventType = EventFailure
+	}
+
+	go d.Dispatch(context.Background(), eventType, job, jobLog)
+}
+
+// Dispatch builds the event and delivers it to every channel the job subscribed to
+func (d *Dispatcher) Dispatch(ctx context.Context, eventType EventType, job models.Job, jobLog models.JobLog) {
+	event := Event{
+		Type:    eventType,
+		Job:     job,
+		Log:     jobLog,
+		BaseURL: d.baseURL,
+	}
+	d.db.WithContext(ctx).First(&event.Project, "id = ?", job.ProjectID)
+
+	d.sendEmail(ctx, event)
+	d.sendWebhooks(ctx, event)
+
+	// Jobs with alert rules are told about recoveries when their alerts resolve
+	channelEvents := []EventType{eventType}
+	if eventType == EventSuccess && !models.HasAlertRules(d.db.WithContext(ctx), job.ID) && d.recovered(ctx, jobLog) {
+		channelEvents = []EventType{EventRecovery, EventSuccess}
+	}
+	d.sendSubscriptions(ctx, event, channelEvents)
+}
+
+// RunSlow notifies the job's channels subscribed to "slow" that a run is still going past its
+// expected duration, in the background
+func (d *Dispatcher) RunSlow(job models.Job, jobLog models.JobLog) {
+	if d == nil {
+		return
+	}
+
+	go func() {
+		ctx := context.Background()
+		event := Event{
+			Type:    EventSlow,
+			Job:     job,
+			Log:     jobLog,
+			BaseURL: d.baseURL,
+		}
+		d.db.WithContext(ctx).First(&event.Project, "id = ?", job.ProjectID)
+		d.sendSubscriptions(ctx, event, []EventType{EventSlow})
+	}()
+}
+
+// AlertsChanged notifies about alerts that started firing or resolved after a run, in the background.
+// Alerts are only sent to channels: firing alerts as "alert" events, resolved ones as "recovery" events.
+func (d *Dispatcher) AlertsChanged(job models.Job, jobLog models.JobLog, alerts []models.Alert) {
+	if d == nil || len(alerts) == 0 {
+		return
+	}
+
+	go func() {
+		ctx := context.Background()
+		event := Event{
+			Job:     job,
+			Log:     jobLog,
+			BaseURL: d.baseURL,
+		}
+		d.db.WithContext(ctx).First(&event.Project, "id = ?", job.ProjectID)
+
+		for i := range alerts {
+			alertEvent := event
+			alertEvent.Alert = &alerts[i]
+			eventType := EventAlert
+			if alerts[i].Status == models.AlertStatusResolved {
+				eventType = EventRecovery
+			}
+			d.sendSubscriptions(ctx, alertEvent, []EventType{eventType})
+		}
+	}()
+}
+
+// JobPaused notifies the job's channels subscribed to "paused" that it was paused automatically, in the background
+func (d *Dispatcher) JobPaused(job models.Job, jobLog models.JobLog) {
+	if d == nil {
+		return
+	}
+
+	go func() {
+		ctx := context.Background()
+		event := Event{
+			Type:    EventPaused,
+			Job:     job,
+			Log:     jobLog,
+			BaseURL: d.baseURL,
+		}
+		d.db.WithContext(ctx).First(&event.Project, "id = ?", job.ProjectID)
+		d.sendSubscriptions(ctx, event, []EventType{EventPaused})
+	}()
+}
+
+// recovered reports whether the run before the given one failed
+func (d *Dispatcher) recovered(ctx context.Context, jobLog models.JobLog) bool {
+	var previous models.JobLog
+	err := d.db.WithContext(ctx).
+		Where("job_id = ? AND id <> ? AND start_time < ? AND status IN ?", jobLog.JobID, jobLog.ID, jobLog.StartTime,
+			models.FinishedStatuses).
+		Order("start_time DESC").
+		First(&previous).Error
+	return err == nil && previous.Status != models.JobStatusSuccess
+}
+
+// sendEmail delivers the event by email when the job's settings ask for it
+func (d *Dispatcher) sendEmail(ctx context.Context, event Event) {
+	settings := event.Job.EmailNotifications
+	if settings == nil || len(settings.Recipients) == 0 {
+		return
+	}
+	if (event.Type == EventSuccess && !settings.OnSuccess) || (event.Type == EventFailure && !settings.OnFailure) {
+		return
+	}
+
+	if d.email == nil {
+		d.logger.Debug("Skipping email notification for job %s: SMTP is not configured", event.Job.Name)
+		return
+	}
+
+	err := d.email.Send(ctx, settings.Recipients, event)
+	d.record(ctx, &models.NotificationDelivery{
+		EventID:  models.NewID(),
+		JobID:    event.Job.ID,
+		JobLogID: event.Log.ID,
+		Channel:  "email",
+		Event:    string(event.Type),
+		Target:   strings.Join(settings.Recipients, ", "),
+		Attempt:  1,
+	}, err)
+}
+
+// sendWebhooks posts the event to every webhook of the job subscribed to it
+func (d *Dispatcher) sendWebhooks(ctx context.Context, event Event) {
+	for _, hook := range event.Job.Webhooks {
+		if (event.Type == EventSuccess && !hook.OnSuccess) || (event.Type == EventFailure && !hook.OnFailure) {
+			continue
+		}
+
+		eventID := models.NewID()
+		body, err := json.Marshal(NewWebhookPayload(eventID, event))
+		if err != nil {
+			d.logger.Error("Failed to encode webhook payload for job %s: %v", event.Job.Name, err)
+			continue
+		}
+
+		template := models.NotificationDelivery{
+			EventID:  eventID,
+			JobID:    event.Job.ID,
+			JobLogID: event.Log.ID,
+			Channel:  "webhook",
+			Event:    string(event.Type),
+			Target:   hook.URL,
+			Payload:  string(body),
+		}
+		d.deliverWebhook(ctx, hook, template, d.webhooks.MaxAttempts())
+	}
+}
+
+// deliverWebhook posts a payload, retrying retryable failures with exponential backoff.
+// Every attempt is recorded; the last recorded delivery is returned.
+func (d *Dispatcher) deliverWebhook(ctx context.Context, hook models.WebhookSettings, template models.NotificationDelivery, maxAttempts int) *models.NotificationDelivery {
+	var delivery models.NotificationDelivery
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(d.webhooks.Backoff(attempt - 1)):
+			case <-ctx.Done():
+				return &delivery
+			}
+		}
+
+		delivery = template
+		delivery.ID = models.NewID()
+		delivery.Attempt = attempt
+
+		response, err := d.webhooks.Post(ctx, hook, "run."+template.Event, delivery.ID, []byte(template.Payload))
+		if err != nil && hook.URL != template.Target {
+			// Transport errors quote the URL, which is not recorded in full
+			err = errors.New(strings.ReplaceAll(err.Error(), hook.URL, template.Target))
+		}
+		delivery.ResponseStatus = response.Status
+		delivery.ResponseBody = response.Body
+		d.record(ctx, &delivery, err)
+
+		if err == nil || !response.Retryable() {
+			break
+		}
+	}
+
+	return &delivery
+}
+
+// Redeliver sends the payload of a past webhook delivery again, once.
+// The payload is signed with the webhook's current secret.
+func (d *Dispatcher) Redeliver(ctx context.Context, deliveryID string) (*models.NotificationDelivery, error) {
+	found, err := d.store.Deliveries.Get(ctx, deliveryID)
+	if err != nil {
+		return nil, ErrDeliveryNotFound
+	}
+	original := *found
+	if original.ChannelID != "" {
+		return d.redeliverChannel(ctx, original)
+	}
+	if original.Channel != "webhook" {
+		return nil, ErrNotRedeliverable
+	}
+
+	job, err := d.store.Jobs.Get(ctx, original.JobID)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	for _, hook := range job.Webhooks {
+		if hook.URL != original.Target {
+			continue
+		}
+
+		template := original
+		template.RedeliveryOf = original.ID
+		template.ResponseStatus = 0
+		template.ResponseBody = ""
+		template.Error = ""
+		return d.deliverWebhook(ctx, hook, template, 1), nil
+	}
+
+	return nil, ErrWebhookNotFound
+}
+
+// redeliverChannel sends the payload of a past channel delivery again, once
+func (d *Dispatcher) redeliverChannel(ctx context.Context, original models.NotificationDelivery) (*models.NotificationDelivery, error) {
+	var channel models.NotificationChannel
+	if err := d.db.WithContext(ctx).First(&channel, "id = ?", original.ChannelID).Error; err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	if channel.Type == models.ChannelTypeEmail {
+		return nil, ErrNotRedeliverable
+	}
+
+	template := original
+	template.RedeliveryOf = original.ID
+	template.Target = channelTarget(channel)
+	template.ResponseStatus = 0
+	template.ResponseBody = ""
+	template.Error = ""
+	return d.deliverWebhook(ctx, channelWebhook(channel), template, 1), nil
+}
+
+// record stores a delivery attempt
+func (d *Dispatcher) record(ctx context.Context, delivery *models.NotificationDelivery, sendErr error) {
+	delivery.Status = models.DeliveryStatusSent
+	delivery.CreatedAt = time.Now()
+	if sendErr != nil {
+		delivery.Status = models.DeliveryStatusFailed
+		delivery.Error = sendErr.Error()
+		d.logger.Error("Failed to send %s notification for job %s: %v", delivery.Channel, delivery.JobID, sendErr)
+	}
+
+	if err := d.store.Deliveries.Create(ctx, delivery); err != nil {
+		d.logger.Error("Failed to record notification delivery: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+var subjectTemplate = texttemplate.Must(texttemplate.New("subject").Parse(
+	`[CronTab] {{.Title}}`))
+
+var textTemplate = texttemplate.Must(texttemplate.New("text").Parse(`Job:       {{.Job.Name}}
+Project:   {{.ProjectName}}
+Status:    {{.Log.Status}}
+Started:   {{.Log.StartTime.Format "2006-01-02 15:04:05 MST"}}
+Duration:  {{printf "%.2f" .Log.Duration}}s
+Trigger:   {{.Log.Trigger}}
+{{- if .Alert}}
+Alert:     {{.Alert.Message}} ({{.Alert.Status}})
+{{- end}}
+{{- if .Log.Violation}}
+Violation: {{.Log.Violation}}
+{{- end}}
+{{- if .JobURL}}
+Details:   {{.JobURL}}
+{{- end}}
+{{if .ErrorExcerpt}}
+Error:
+{{.ErrorExcerpt}}
+{{end}}{{if .OutputExcerpt}}
+Output:
+{{.OutputExcerpt}}
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #1f2937;">
+  <h2 style="color: {{.Color}};">{{.Title}}</h2>
+  <table cellpadding="4">
+    <tr><td><strong>Project</strong></td><td>{{.ProjectName}}</td></tr>
+    <tr><td><strong>Status</strong></td><td>{{.Log.Status}}</td></tr>
+    <tr><td><strong>Started</strong></td><td>{{.Log.StartTime.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+    <tr><td><strong>Duration</strong></td><td>{{printf "%.2f" .Log.Duration}}s</td></tr>
+    <tr><td><strong>Trigger</strong></td><td>{{.Log.Trigger}}</td></tr>
+    {{- if .Alert}}
+    <tr><td><strong>Alert</strong></td><td>{{.Alert.Message}} ({{.Alert.Status}})</td></tr>
+    {{- end}}
+    {{- if .Log.Violation}}
+    <tr><td><strong>Violation</strong></td><td>{{.Log.Violation}}</td></tr>
+    {{- end}}
+  </table>
+  {{- if .ErrorExcerpt}}
+  <h3>Error</h3>
+  <pre style="background: #fef2f2; padding: 8px; white-space: pre-wrap;">{{.ErrorExcerpt}}</pre>
+  {{- end}}
+  {{- if .OutputExcerpt}}
+  <h3>Output</h3>
+  <pre style="background: #f3f4f6; padding: 8px; white-space: pre-wrap;">{{.OutputExcerpt}}</pre>
+  {{- end}}
+  {{- if .JobURL}}
+  <p><a href="{{.JobURL}}">View job</a></p>
+  {{- end}}
+</body>
+</html>
+`))
+
+func renderSubject(event Event) (string, error) {
+	var buf bytes.Buffer
+	err := subjectTemplate.Execute(&buf, event)
+	return buf.String(), err
+}
+
+func renderText(event Event) (string, error) {
+	var buf bytes.Buffer
+	err := textTemplate.Execute(&buf, event)
+	return buf.String(), err
+}
+
+func renderHTML(event Event) (string, error) {
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, event)
+	return buf.String(), err
+}
@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"crontab/internal/config"
+	"crontab/internal/models"
+)
+
+// WebhookPayloadVersion is the version of the JSON event schema sent to webhooks
+const WebhookPayloadVersion = "1"
+
+// Headers sent with every webhook request
+const (
+	HeaderEvent     = "X-Crontab-Event"
+	HeaderDelivery  = "X-Crontab-Delivery"
+	HeaderTimestamp = "X-Crontab-Timestamp"
+	HeaderSignature = "X-Crontab-Signature"
+)
+
+// maxResponseBody is how much of a webhook response is stored with the delivery
+const maxResponseBody = 4096
+
+// WebhookPayload is the versioned event body posted to webhooks
+type WebhookPayload struct {
+	Version   string        `json:"version"`
+	ID        string        `json:"id"`
+	Type      string        `json:"type"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Job       WebhookJob    `json:"job"`
+	Run       WebhookRun    `json:"run"`
+	Alert     *models.Alert `json:"alert,omitempty"`
+}
+
+// WebhookJob identifies the job of an event
+type WebhookJob struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	ProjectID string `json:"projectId"`
+	Type      string `json:"type"`
+	Schedule  string `json:"schedule"`
+}
+
+// WebhookRun describes the run of an event
+type WebhookRun struct {
+	ID            string    `json:"id"`
+	Status        string    `json:"status"`
+	Trigger       string    `json:"trigger"`
+	StartTime     time.Time `json:"startTime"`
+	EndTime       time.Time `json:"endTime"`
+	Duration      float64   `json:"duration"`
+	OutputExcerpt string    `json:"outputExcerpt"`
+	ErrorExcerpt  string    `json:"errorExcerpt"`
+	Violation     string    `json:"violation,omitempty"`
+	TraceID       string    `json:"traceId,omitempty"`
+	SLABreached   bool      `json:"slaBreached"`
+}
+
+// NewWebhookPayload builds the payload of an event
+func NewWebhookPayload(eventID string, event Event) WebhookPayload {
+	return WebhookPayload{
+		Version:   WebhookPayloadVersion,
+		ID:        eventID,
+		Type:      "run." + string(event.Type),
+		CreatedAt: time.Now().UTC(),
+		Job: WebhookJob{
+			ID:        event.Job.ID,
+			Name:      event.Job.Name,
+			ProjectID: event.Job.ProjectID,
+			Type:      string(event.Job.Type),
+			Schedule:  event.Job.Schedule,
+		},
+		Run: WebhookRun{
+			ID:            event.Log.ID,
+			Status:        string(event.Log.Status),
+			Trigger:       string(event.Log.Trigger),
+			StartTime:     event.Log.StartTime,
+			EndTime:       event.Log.EndTime,
+			Duration:      event.Log.Duration,
+			OutputExcerpt: event.OutputExcerpt(),
+			ErrorExcerpt:  event.ErrorExcerpt(),
+			Violation:     event.Log.Violation,
+			TraceID:       event.Log.TraceID,
+			SLABreached:   event.Log.SLABreached,
+		},
+		Alert: event.Alert,
+	}
+}
+
+// Sign computes the signature header value of a payload: "sha256=" followed by the
+// hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookResponse is the outcome of a single webhook request
+type WebhookResponse struct {
+	Status int
+	Body   string
+}
+
+// Retryable reports whether a failed request should be attempted again
+func (r WebhookResponse) Retryable() bool {
+	return r.Status == 0 || r.Status >= 500 || r.Status == http.StatusRequestTimeout || r.Status == http.StatusTooManyRequests
+}
+
+// WebhookNotifier posts signed payloads and retries failed deliveries with exponential backoff
+type WebhookNotifier struct {
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// NewWebhookNotifier creates a WebhookNotifier from the WEBHOOK_* settings
+func NewWebhookNotifier(cfg *config.Config) *WebhookNotifier {
+	return &WebhookNotifier{
+		client:      &http.Client{Timeout: time.Duration(cfg.GetInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second},
+		maxAttempts: cfg.GetInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		backoff:     time.Duration(cfg.GetInt("WEBHOOK_RETRY_BACKOFF_SECONDS", 2)) * time.Second,
+	}
+}
+
+// MaxAttempts returns how many times a delivery is tried
+func (n *WebhookNotifier) MaxAttempts() int {
+	if n.maxAttempts < 1 {
+		return 1
+	}
+	return n.maxAttempts
+}
+
+// Backoff returns the delay before the given retry (1 for the first retry)
+func (n *WebhookNotifier) Backoff(retry int) time.Duration {
+	return n.backoff * time.Duration(1<<uint(retry-1))
+}
+
+// Post sends the body to the webhook once
+func (n *WebhookNotifier) Post(ctx context.Context, hook models.WebhookSettings, eventType, deliveryID string, body []byte) (WebhookResponse, error) {
+	method := strings.ToUpper(hook.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return WebhookResponse{}, fmt.Errorf("failed to build request: %v", err)
+	}
+
+	timestamp := time.Now().Unix()
+	for key, value := range hook.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CronTab-Webhook/"+WebhookPayloadVersion)
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderDelivery, deliveryID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if hook.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return WebhookResponse{}, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	response := WebhookResponse{Status: resp.StatusCode, Body: string(responseBody)}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return response, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return response, nil
+}
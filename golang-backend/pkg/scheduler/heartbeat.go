@@ -0,0 +1,180 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"crontab/internal/models"
+)
+
+// PingKind is the kind of signal a heartbeat job sends to its ping URL
+type PingKind string
+
+const (
+	PingSuccess PingKind = "success"
+	PingStart   PingKind = "start"
+	PingFail    PingKind = "fail"
+)
+
+// ErrUnknownPingToken is returned when no heartbeat job has the given ping token
+var ErrUnknownPingToken = errors.New("unknown ping token")
+
+// createHeartbeatCheck returns a function that, at each scheduled time of a heartbeat job,
+// waits for its grace period and records a missed run when no ping arrived
+func (s *Scheduler) createHeartbeatCheck(job *models.Job) func() {
+	jobID := job.ID
+	grace := job.HeartbeatGrace()
+	return func() {
+		scheduledAt := s.scheduledTime(jobID)
+		if scheduledAt.IsZero() {
+			scheduledAt = time.Now()
+		}
+		time.AfterFunc(grace, func() {
+			s.checkHeartbeat(context.Background(), jobID, scheduledAt)
+		})
+	}
+}
+
+// checkHeartbeat records a missed run when the job did not ping within its grace period around scheduledAt
+func (s *Scheduler) checkHeartbeat(ctx context.Context, jobID string, scheduledAt time.Time) {
+	db := s.db.WithContext(ctx)
+
+	var job models.Job
+	if err := db.First(&job, "id = ?", jobID).Error; err != nil {
+		s.logger.Error("Failed to find heartbeat job %s: %v", jobID, err)
+		return
+	}
+	if job.Type != models.JobTypeHeartbeat || job.Status == models.JobStatusPaused {
+		return
+	}
+
+	grace := job.HeartbeatGrace()
+	var pings int64
+	err := db.Model(&models.JobLog{}).
+		Where(map[string]interface{}{"job_id": job.ID, "trigger": models.TriggerExternal}). // trigger is a reserved word, let gorm quote it
+		Where("start_time >= ? AND start_time <= ?", scheduledAt.Add(-grace), scheduledAt.Add(grace)).
+		Count(&pings).Error
+	if err != nil {
+		s.logger.Error("Failed to look up pings of %s: %v", job.Name, err)
+		return
+	}
+	if err := s.closeStaleRuns(ctx, db, &job, time.Now()); err != nil {
+		s.logger.Error("Failed to close stale runs of %s: %v", job.Name, err)
+	}
+	if pings > 0 {
+		return
+	}
+
+	// The missed run is opened and finished like any other, so that it counts as a failure
+	jobLog := models.JobLog{
+		JobID:     job.ID,
+		StartTime: scheduledAt,
+		Status:    models.JobStatusRunning,
+		Trigger:   models.TriggerSchedule,
+	}
+	if err := s.runs.Start(ctx, &jobLog); err != nil {
+		s.logger.Error("Failed to record missed run of %s: %v", job.Name, err)
+		return
+	}
+	jobLog.EndTime = time.Now()
+	jobLog.Status = models.JobStatusMissed
+	jobLog.Error = fmt.Sprintf("No ping received within %s of %s", grace, scheduledAt.UTC().Format(time.RFC3339))
+
+	s.logger.Error("Heartbeat job missed its ping: %s", job.Name)
+	s.finishRun(ctx, db, &job, jobLog)
+}
+
+// closeStaleRuns records the runs of a heartbeat job that signalled their start but were not completed
+// by their deadline (see models.Job.HeartbeatDeadline) as missed, so that later pings don't complete them
+func (s *Scheduler) closeStaleRuns(ctx context.Context, db *gorm.DB, job *models.Job, now time.Time) error {
+	var open []models.JobLog
+	err := db.Where(map[string]interface{}{"job_id": job.ID, "trigger": models.TriggerExternal, "status": models.JobStatusRunning}).
+		Order("start_time").
+		Find(&open).Error
+	if err != nil {
+		return err
+	}
+
+	for _, jobLog := range open {
+		deadline := job.HeartbeatDeadline(jobLog.StartTime)
+		if !now.After(deadline) {
+			continue
+		}
+		jobLog.EndTime = deadline
+		jobLog.Duration = deadline.Sub(jobLog.StartTime).Seconds()
+		jobLog.Status = models.JobStatusMissed
+		jobLog.Error = fmt.Sprintf("No completion ping received by %s", deadline.UTC().Format(time.RFC3339))
+
+		s.logger.Error("Heartbeat job did not complete its run: %s", job.Name)
+		s.finishRun(ctx, db, job, jobLog)
+	}
+	return nil
+}
+
+// Ping records a signal sent by a heartbeat job to its ping URL. A start ping opens a running log;
+// success and fail pings complete the open log, or record an instant run when the job did not signal its start.
+// Open logs past their deadline are recorded as missed first.
+func (s *Scheduler) Ping(ctx context.Context, token string, kind PingKind, body string) (*models.JobLog, error) {
+	db := s.db.WithContext(ctx)
+
+	var job models.Job
+	if err := db.First(&job, "ping_token = ? AND type = ?", token, models.JobTypeHeartbeat).Error; err != nil {
+		return nil, ErrUnknownPingToken
+	}
+
+	now := time.Now()
+	if kind == PingStart {
+		jobLog := models.JobLog{
+			JobID:     job.ID,
+			StartTime: now,
+			Status:    models.JobStatusRunning,
+			Trigger:   models.TriggerExternal,
+			Output:    body,
+		}
+		if err := s.runs.Start(ctx, &jobLog); err != nil {
+			return nil, fmt.Errorf("failed to record ping: %v", err)
+		}
+		return &jobLog, nil
+	}
+
+	// A start ping older than its deadline does not belong to this run
+	if err := s.closeStaleRuns(ctx, db, &job, now); err != nil {
+		return nil, fmt.Errorf("failed to record ping: %v", err)
+	}
+
+	var jobLog models.JobLog
+	err := db.Where(map[string]interface{}{"job_id": job.ID, "trigger": models.TriggerExternal, "status": models.JobStatusRunning}).
+		Order("start_time DESC").
+		First(&jobLog).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The job did not signal its start: open the run now, so it is recorded like any other
+		jobLog = models.JobLog{
+			JobID:     job.ID,
+			StartTime: now,
+			Status:    models.JobStatusRunning,
+			Trigger:   models.TriggerExternal,
+		}
+		err = s.runs.Start(ctx, &jobLog)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record ping: %v", err)
+	}
+
+	jobLog.EndTime = now
+	jobLog.Duration = now.Sub(jobLog.StartTime).Seconds()
+	if body != "" {
+		jobLog.Output = body
+	}
+	jobLog.Status = models.JobStatusSuccess
+	if kind == PingFail {
+		jobLog.Status = models.JobStatusFailed
+		jobLog.Error = "Job reported a failure"
+	}
+
+	s.finishRun(ctx, db, &job, jobLog)
+	return &jobLog, nil
+}
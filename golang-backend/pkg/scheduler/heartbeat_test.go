@@ -0,0 +1,183 @@
+package scheduler
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"crontab/internal/config"
+	"crontab/internal/migrations"
+	"crontab/internal/models"
+	"crontab/pkg/logger"
+	"crontab/pkg/metrics"
+)
+
+// testScheduler returns a scheduler over a migrated SQLite database and a project stored in it.
+// Its logs are dropped: under the race detector every write to a file orders the goroutines
+// involved, which would hide races between the scheduler's goroutines.
+func testScheduler(t *testing.T) (*Scheduler, *gorm.DB, models.Project) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "scheduler.db")), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if _, err := migrations.New(db, nil).Up(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	project := models.Project{Name: "Backups"}
+	if err := db.Create(&project).Error; err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	cfg := config.New()
+	return New(db, logger.Discard(), metrics.New(cfg)), db, project
+}
+
+// heartbeatJob returns a scheduler over a migrated SQLite database and a heartbeat job stored in it
+func heartbeatJob(t *testing.T) (*Scheduler, *gorm.DB, models.Job) {
+	t.Helper()
+
+	s, db, project := testScheduler(t)
+	job := models.Job{
+		ProjectID: project.ID,
+		Name:      "offsite backup",
+		Type:      models.JobTypeHeartbeat,
+		Schedule:  "0 0 3 * * *",
+		Status:    models.JobStatusIdle,
+		Timezone:  "UTC",
+	}
+	if err := db.Create(&job).Error; err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+	return s, db, job
+}
+
+func reload(t *testing.T, db *gorm.DB, id string) models.Job {
+	t.Helper()
+	var job models.Job
+	if err := db.First(&job, "id = ?", id).Error; err != nil {
+		t.Fatalf("failed to load job: %v", err)
+	}
+	return job
+}
+
+func TestPingWithoutStartRecordsRun(t *testing.T) {
+	s, db, job := heartbeatJob(t)
+
+	run, err := s.Ping(context.Background(), job.PingToken, PingSuccess, "42 files")
+	if err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if run.Status != models.JobStatusSuccess || run.Trigger != models.TriggerExternal || run.Output != "42 files" {
+		t.Errorf("run = %+v", run)
+	}
+
+	stored := reload(t, db, job.ID)
+	if stored.SuccessCount != 1 || stored.Status != models.JobStatusIdle {
+		t.Errorf("job after ping: successCount = %d, status = %s", stored.SuccessCount, stored.Status)
+	}
+	if !stored.LastRun.Equal(run.StartTime) {
+		t.Errorf("lastRun = %v, want %v", stored.LastRun, run.StartTime)
+	}
+	// Runs are bookkeeping, not edits
+	if stored.Version != job.Version {
+		t.Errorf("version = %d, want %d", stored.Version, job.Version)
+	}
+
+	var runs int64
+	db.Model(&models.JobLog{}).Where("job_id = ?", job.ID).Count(&runs)
+	if runs != 1 {
+		t.Errorf("got %d logs, want 1", runs)
+	}
+}
+
+func TestPingCompletesStartedRun(t *testing.T) {
+	s, db, job := heartbeatJob(t)
+	ctx := context.Background()
+
+	started, err := s.Ping(ctx, job.PingToken, PingStart, "")
+	if err != nil {
+		t.Fatalf("start ping failed: %v", err)
+	}
+	if reload(t, db, job.ID).Status != models.JobStatusRunning {
+		t.Error("job is not running after the start ping")
+	}
+
+	finished, err := s.Ping(ctx, job.PingToken, PingFail, "disk full")
+	if err != nil {
+		t.Fatalf("fail ping failed: %v", err)
+	}
+	if finished.ID != started.ID || finished.Status != models.JobStatusFailed {
+		t.Errorf("fail ping recorded %+v, want run %s failed", finished, started.ID)
+	}
+	if stored := reload(t, db, job.ID); stored.FailCount != 1 {
+		t.Errorf("failCount = %d, want 1", stored.FailCount)
+	}
+
+	if _, err := s.Ping(ctx, "wrong", PingSuccess, ""); err != ErrUnknownPingToken {
+		t.Errorf("unknown token: err = %v, want ErrUnknownPingToken", err)
+	}
+}
+
+func TestPingRecordsStaleStartAsMissed(t *testing.T) {
+	s, db, job := heartbeatJob(t)
+	ctx := context.Background()
+
+	// A start ping from three days ago was never completed
+	stale, err := s.Ping(ctx, job.PingToken, PingStart, "")
+	if err != nil {
+		t.Fatalf("start ping failed: %v", err)
+	}
+	startedAt := time.Now().Add(-72 * time.Hour)
+	db.Model(&stale).Update("start_time", startedAt)
+
+	finished, err := s.Ping(ctx, job.PingToken, PingSuccess, "")
+	if err != nil {
+		t.Fatalf("success ping failed: %v", err)
+	}
+	if finished.ID == stale.ID || finished.Duration > 60 {
+		t.Errorf("success ping completed the stale run: %+v", finished)
+	}
+
+	var missed models.JobLog
+	db.First(&missed, "id = ?", stale.ID)
+	if missed.Status != models.JobStatusMissed {
+		t.Errorf("stale run status = %s, want missed", missed.Status)
+	}
+	if deadline := job.HeartbeatDeadline(startedAt); !missed.EndTime.Equal(deadline) {
+		t.Errorf("stale run ended at %v, want its deadline %v", missed.EndTime, deadline)
+	}
+	if stored := reload(t, db, job.ID); stored.FailCount != 1 || stored.SuccessCount != 1 {
+		t.Errorf("job counts: %d failed, %d succeeded, want 1 each", stored.FailCount, stored.SuccessCount)
+	}
+}
+
+func TestCheckHeartbeatRecordsMissedRun(t *testing.T) {
+	s, db, job := heartbeatJob(t)
+	scheduledAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	s.checkHeartbeat(context.Background(), job.ID, scheduledAt)
+
+	var missed models.JobLog
+	if err := db.First(&missed, "job_id = ?", job.ID).Error; err != nil {
+		t.Fatalf("missed run not recorded: %v", err)
+	}
+	if missed.Status != models.JobStatusMissed || missed.Trigger != models.TriggerSchedule {
+		t.Errorf("missed run = %+v", missed)
+	}
+	stored := reload(t, db, job.ID)
+	if stored.Status != models.JobStatusMissed || stored.FailCount != 1 || stored.ConsecutiveFailures != 1 {
+		t.Errorf("job after a missed run: status %s, failCount %d, consecutiveFailures %d", stored.Status, stored.FailCount, stored.ConsecutiveFailures)
+	}
+	if !stored.LastRun.Equal(scheduledAt) {
+		t.Errorf("lastRun = %v, want %v", stored.LastRun, scheduledAt)
+	}
+}
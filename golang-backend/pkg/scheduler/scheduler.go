@@ -2,15 +2,25 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 	
 	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 	
 	"crontab/internal/models"
+	"crontab/internal/repository"
+	"crontab/internal/services"
+	"crontab/pkg/executor"
 	"crontab/pkg/logger"
+	"crontab/pkg/metrics"
+	"crontab/pkg/notify"
+	"crontab/pkg/tracing"
 )
 
 // Scheduler manages cron jobs in the system
@@ -18,23 +28,55 @@ type Scheduler struct {
 	cron      *cron.Cron
 	db        *gorm.DB
 	logger    *logger.Logger
+	metrics   *metrics.Metrics
+	notifier  *notify.Dispatcher
+	runs      *services.RunService
 	jobIDs    map[string]cron.EntryID
+	slots     chan struct{} // Limits concurrent executions, nil when unlimited
 	mutex     sync.Mutex
+	alertMu   sync.Mutex // Serialises alert evaluation so concurrent runs cannot open duplicate alerts
+	trashRetention time.Duration // Age after which deleted jobs and projects are purged, 0 = never
 	isRunning bool
 }
 
+// trashPurgeInterval is how often the trash is checked for items past their retention
+const trashPurgeInterval = time.Hour
+
 // New creates a new Scheduler instance
-func New(db *gorm.DB, logger *logger.Logger) *Scheduler {
+func New(db *gorm.DB, logger *logger.Logger, metrics *metrics.Metrics) *Scheduler {
 	cronOptions := cron.WithSeconds()
 	return &Scheduler{
 		cron:      cron.New(cronOptions),
 		db:        db,
 		logger:    logger,
+		metrics:   metrics,
+		runs:      services.NewRunService(repository.NewGormStore(db)),
 		jobIDs:    make(map[string]cron.EntryID),
 		isRunning: false,
 	}
 }
 
+// SetNotifier sets the dispatcher informed about every finished run
+func (s *Scheduler) SetNotifier(notifier *notify.Dispatcher) {
+	s.notifier = notifier
+}
+
+// SetMaxConcurrent limits how many jobs may execute at the same time.
+// Due runs beyond the limit wait in a queue. Must be called before Start; 0 means unlimited.
+func (s *Scheduler) SetMaxConcurrent(max int) {
+	if max <= 0 {
+		s.slots = nil
+		return
+	}
+	s.slots = make(chan struct{}, max)
+}
+
+// SetTrashRetention sets how long deleted jobs and projects stay restorable before they are
+// purged. Must be called before Start; 0 keeps them forever.
+func (s *Scheduler) SetTrashRetention(retention time.Duration) {
+	s.trashRetention = retention
+}
+
 // Start initializes and starts the scheduler
 func (s *Scheduler) Start() {
 	s.logger.Info("Starting scheduler")
@@ -52,8 +94,29 @@ func (s *Scheduler) Start() {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 	
+	lastPurge := time.Time{}
 	for range ticker.C {
 		s.RefreshJobs()
+		
+		if s.trashRetention > 0 && time.Since(lastPurge) >= trashPurgeInterval {
+			s.PurgeTrash()
+			lastPurge = time.Now()
+		}
+	}
+}
+
+// PurgeTrash permanently deletes jobs and projects that have been in the trash longer than the retention
+func (s *Scheduler) PurgeTrash() {
+	if s.trashRetention <= 0 {
+		return
+	}
+	
+	result, err := models.PurgeTrash(s.db, time.Now().Add(-s.trashRetention))
+	if err != nil {
+		s.logger.Error("Failed to purge trash: %v", err)
+	}
+	if result.Jobs > 0 || result.Projects > 0 {
+		s.logger.Info("Purged %d jobs and %d projects from the trash", result.Jobs, result.Projects)
 	}
 }
 
@@ -129,10 +192,13 @@ func (s *Scheduler) RefreshJobs() {
 			if entryID, exists := s.jobIDs[jobID]; exists {
 				s.cron.Remove(entryID)
 				delete(s.jobIDs, jobID)
+				s.metrics.ForgetJob(jobID)
 				s.logger.Debug("Removed job from scheduler: %s", jobID)
 			}
 		}
 	}
+	
+	s.metrics.SetScheduledEntries(len(s.jobIDs))
 }
 
 // ScheduleJob adds a job to the scheduler
@@ -143,6 +209,20 @@ func (s *Scheduler) ScheduleJob(job *models.Job) {
 	s.scheduleJobInternal(job)
 }
 
+// UnscheduleJob removes a job from the scheduler, e.g. when it is moved to the trash
+func (s *Scheduler) UnscheduleJob(jobID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	
+	if entryID, exists := s.jobIDs[jobID]; exists {
+		s.cron.Remove(entryID)
+		delete(s.jobIDs, jobID)
+		s.logger.Debug("Removed job from scheduler: %s", jobID)
+	}
+	s.metrics.ForgetJob(jobID)
+	s.metrics.SetScheduledEntries(len(s.jobIDs))
+}
+
 // scheduleJobInternal is the internal implementation of ScheduleJob (not thread-safe)
 func (s *Scheduler) scheduleJobInternal(job *models.Job) {
 	// Remove existing job if it's already scheduled
@@ -154,6 +234,14 @@ func (s *Scheduler) scheduleJobInternal(job *models.Job) {
 	// Only schedule if the job isn't paused
 	if job.Status == models.JobStatusPaused {
 		s.logger.Debug("Skipping scheduling of paused job: %s", job.Name)
+		s.metrics.SetScheduledEntries(len(s.jobIDs))
+		return
+	}
+	
+	// Jobs in the trash never run
+	if job.DeletedAt.Valid {
+		s.logger.Debug("Skipping scheduling of deleted job: %s", job.Name)
+		s.metrics.SetScheduledEntries(len(s.jobIDs))
 		return
 	}
 	
@@ -164,11 +252,13 @@ func (s *Scheduler) scheduleJobInternal(job *models.Job) {
 	entryID, err := s.cron.AddFunc(job.Schedule, jobFn)
 	if err != nil {
 		s.logger.Error("Failed to schedule job %s: %v", job.Name, err)
+		s.metrics.SetScheduledEntries(len(s.jobIDs))
 		return
 	}
 	
 	// Store the entry ID for future reference
 	s.jobIDs[job.ID] = entryID
+	s.metrics.SetScheduledEntries(len(s.jobIDs))
 	s.logger.Info("Scheduled job: %s with schedule %s", job.Name, job.Schedule)
 	
 	// Update the next run time in the database
@@ -180,102 +270,272 @@ func (s *Scheduler) scheduleJobInternal(job *models.Job) {
 
 // createJobExecutor returns a function that executes the job
 func (s *Scheduler) createJobExecutor(job *models.Job) func() {
+	jobID := job.ID
+	if job.Type == models.JobTypeHeartbeat {
+		return s.createHeartbeatCheck(job)
+	}
 	return func() {
-		s.executeJob(job.ID)
+		s.dispatch(context.Background(), jobID, s.scheduledTime(jobID), models.TriggerSchedule)
+	}
+}
+
+// RunNow executes a job immediately in the background, outside of its schedule.
+// The trace in ctx is continued by the execution.
+func (s *Scheduler) RunNow(ctx context.Context, jobID string) {
+	ctx = tracing.Detach(ctx)
+	go s.dispatch(ctx, jobID, time.Time{}, models.TriggerManual)
+}
+
+// dispatch waits for a free execution slot and runs the job
+func (s *Scheduler) dispatch(ctx context.Context, jobID string, scheduledAt time.Time, trigger models.RunTrigger) {
+	if s.slots != nil {
+		s.metrics.Enqueued()
+		s.slots <- struct{}{}
+		s.metrics.Dequeued()
+		defer func() { <-s.slots }()
 	}
+	
+	s.executeJob(ctx, jobID, scheduledAt, trigger)
+}
+
+// scheduledTime returns the time the cron entry of a job was due to fire
+func (s *Scheduler) scheduledTime(jobID string) time.Time {
+	s.mutex.Lock()
+	entryID, exists := s.jobIDs[jobID]
+	s.mutex.Unlock()
+	
+	if !exists {
+		return time.Time{}
+	}
+	return s.cron.Entry(entryID).Prev
 }
 
-// executeJob runs a job and records its result
-func (s *Scheduler) executeJob(jobID string) {
+// executeJob runs a job and records its result.
+// scheduledAt is the time the run was due, or zero for runs not triggered by cron.
+func (s *Scheduler) executeJob(ctx context.Context, jobID string, scheduledAt time.Time, trigger models.RunTrigger) {
+	ctx, span := tracing.Tracer().Start(ctx, "job.execute", trace.WithAttributes(
+		attribute.String("job.id", jobID),
+		attribute.String("job.trigger", string(trigger)),
+	))
+	defer span.End()
+	
+	db := s.db.WithContext(ctx)
+	
 	var job models.Job
-	if err := s.db.First(&job, "id = ?", jobID).Error; err != nil {
+	if err := db.First(&job, "id = ?", jobID).Error; err != nil {
 		s.logger.Error("Failed to find job %s: %v", jobID, err)
+		span.SetStatus(codes.Error, "job not found")
 		return
 	}
+	span.SetAttributes(
+		attribute.String("job.name", job.Name),
+		attribute.String("job.type", string(job.Type)),
+		attribute.String("project.id", job.ProjectID),
+	)
+	
+	if !scheduledAt.IsZero() {
+		s.metrics.ObserveScheduleLag(job.ProjectID, time.Since(scheduledAt))
+	}
+	
+	s.metrics.RunStarted()
+	defer s.metrics.RunFinished()
 	
 	// Create a new log entry
 	jobLog := models.JobLog{
 		JobID:     job.ID,
 		StartTime: time.Now(),
 		Status:    models.JobStatusRunning,
+		Trigger:   trigger,
+		TraceID:   tracing.TraceID(ctx),
 	}
 	
-	// Start by marking job as running
-	s.db.Model(&job).Updates(map[string]interface{}{
-		"status":   models.JobStatusRunning,
-		"last_run": jobLog.StartTime,
-	})
-	
-	// Save initial log
-	if err := s.db.Create(&jobLog).Error; err != nil {
+	// Save the initial log and mark the job as running
+	if err := s.runs.Start(ctx, &jobLog); err != nil {
 		s.logger.Error("Failed to create job log for %s: %v", job.Name, err)
 	}
 	
 	s.logger.Info("Executing job: %s (%s)", job.Name, job.ID)
 	
-	// Execute the command (this is simplified - in a real system you'd use os/exec or similar)
-	output, err := s.runCommand(job.Command)
+	// Report the run as slow once it outlives the job's expected duration; it keeps running.
+	// The timer works on copies, since the run goes on updating job and jobLog.
+	var slaTimer *time.Timer
+	if job.ExpectedDuration > 0 {
+		slowJob, slowLog := job, jobLog
+		slaTimer = time.AfterFunc(time.Duration(job.ExpectedDuration)*time.Second, func() {
+			s.slaBreached(db, slowJob, slowLog)
+		})
+	}
+	
+	// Execute the command inside the job's sandbox, or call the endpoint of HTTP jobs
+	result, err := s.runCommand(ctx, &job)
+	
+	// Stop returns false once the SLA timer has fired
+	if slaTimer != nil && !slaTimer.Stop() {
+		jobLog.SLABreached = true
+	}
 	
 	// Record end time and calculate duration
 	jobLog.EndTime = time.Now()
 	jobLog.Duration = jobLog.EndTime.Sub(jobLog.StartTime).Seconds()
-	jobLog.Output = output
+	jobLog.Output = result.Output
+	jobLog.Violation = result.Violation
 	
 	// Update status based on result
 	if err != nil {
 		jobLog.Status = models.JobStatusFailed
 		jobLog.Error = err.Error()
 		
-		// Update job in db
-		s.db.Model(&job).Updates(map[string]interface{}{
-			"status":      models.JobStatusFailed,
-			"fail_count":  gorm.Expr("fail_count + 1"),
-		})
-		
 		s.logger.Error("Job failed: %s - %v", job.Name, err)
+		span.SetStatus(codes.Error, err.Error())
 	} else {
 		jobLog.Status = models.JobStatusSuccess
 		
-		// Update job in db
-		s.db.Model(&job).Updates(map[string]interface{}{
-			"status":         models.JobStatusIdle,
-			"success_count":  gorm.Expr("success_count + 1"),
-		})
-		
 		s.logger.Info("Job completed successfully: %s", job.Name)
 	}
 	
-	// Update log entry
-	s.db.Save(&jobLog)
+	s.finishRun(ctx, db, &job, jobLog)
+}
+
+// finishRun stores a completed run and updates everything derived from it: the job's counters and status,
+// metrics, notifications, auto-pause, run statistics, alerts and the next run time
+func (s *Scheduler) finishRun(ctx context.Context, db *gorm.DB, job *models.Job, jobLog models.JobLog) {
+	// Update the log entry and the job's counters and status
+	if err := s.runs.Finish(ctx, &jobLog); err != nil {
+		s.logger.Error("Failed to record run of %s: %v", job.Name, err)
+	}
+	
+	s.metrics.ObserveRun(job.ID, job.Name, job.ProjectID, string(jobLog.Status),
+		jobLog.EndTime.Sub(jobLog.StartTime), jobLog.EndTime)
 	
-	// Calculate and update average runtime
-	var avgRuntime float64
-	s.db.Model(&models.JobLog{}).
-		Where("job_id = ? AND status = ?", job.ID, models.JobStatusSuccess).
-		Select("AVG(duration)").
-		Scan(&avgRuntime)
+	// Send notifications in the background
+	s.notifier.RunFinished(*job, jobLog)
 	
-	s.db.Model(&job).Update("average_runtime", avgRuntime)
+	if jobLog.Status != models.JobStatusSuccess {
+		s.autoPause(db, job, jobLog)
+	}
+	
+	// Fold the run into the job's running aggregates
+	stats, statsErr := models.RecordRun(db, job.ID, jobLog.Status, jobLog.Duration, jobLog.StartTime)
+	if statsErr != nil {
+		s.logger.Error("Failed to record run stats for %s: %v", job.Name, statsErr)
+	} else {
+		db.Model(job).Update("average_runtime", stats.AverageDuration())
+	}
+	
+	// Evaluate the job's alert rules and notify about alerts that fired or resolved
+	s.alertMu.Lock()
+	alerts, alertErr := models.EvaluateAlertRules(db, jobLog)
+	s.alertMu.Unlock()
+	if alertErr != nil {
+		s.logger.Error("Failed to evaluate alert rules for %s: %v", job.Name, alertErr)
+	}
+	s.notifier.AlertsChanged(*job, jobLog, alerts)
 	
 	// Update the next run time
-	if entryID, exists := s.jobIDs[job.ID]; exists {
+	s.mutex.Lock()
+	entryID, exists := s.jobIDs[job.ID]
+	s.mutex.Unlock()
+	if exists {
 		entry := s.cron.Entry(entryID)
 		if !entry.Next.IsZero() {
-			s.db.Model(&job).Update("next_run", entry.Next)
+			db.Model(job).Update("next_run", entry.Next)
 		}
 	}
 }
 
-// runCommand simulates running a command (in a real app, this would use os/exec)
-func (s *Scheduler) runCommand(command string) (string, error) {
-	// This is a simplified simulation
-	s.logger.Debug("Simulating command execution: %s", command)
+// slaBreached marks a run that is still going past its job's expected duration and notifies about it
+func (s *Scheduler) slaBreached(db *gorm.DB, job models.Job, jobLog models.JobLog) {
+	jobLog.SLABreached = true
+	if err := db.Model(&jobLog).Update("sla_breached", true).Error; err != nil {
+		s.logger.Error("Failed to mark SLA breach of %s: %v", job.Name, err)
+	}
+	
+	jobLog.Duration = time.Since(jobLog.StartTime).Seconds()
+	s.logger.Info("Job %s is still running after its expected duration of %ds", job.Name, job.ExpectedDuration)
+	s.metrics.SLABreached(job.ID, job.Name, job.ProjectID)
+	s.notifier.RunSlow(job, jobLog)
+}
+
+// autoPause pauses a job whose failures in a row reached its AutoPauseAfterFailures threshold
+func (s *Scheduler) autoPause(db *gorm.DB, job *models.Job, jobLog models.JobLog) {
+	if job.AutoPauseAfterFailures <= 0 {
+		return
+	}
+	
+	var current models.Job
+	if err := db.Select("id", "status", "consecutive_failures").First(&current, "id = ?", job.ID).Error; err != nil {
+		s.logger.Error("Failed to read failure count of %s: %v", job.Name, err)
+		return
+	}
+	if current.Status == models.JobStatusPaused || current.ConsecutiveFailures < job.AutoPauseAfterFailures {
+		return
+	}
+	
+	job.ConsecutiveFailures = current.ConsecutiveFailures
+	job.Pause(fmt.Sprintf("Paused automatically after %d consecutive failures", current.ConsecutiveFailures), models.PausedByScheduler)
+	
+	err := db.Model(job).Updates(map[string]interface{}{
+		"status":        job.Status,
+		"paused_reason": job.PausedReason,
+		"paused_by":     job.PausedBy,
+		"paused_at":     job.PausedAt,
+	}).Error
+	if err != nil {
+		s.logger.Error("Failed to pause job %s: %v", job.Name, err)
+		return
+	}
+	
+	s.ScheduleJob(job)
+	s.logger.Info("Job %s paused after %d consecutive failures", job.Name, current.ConsecutiveFailures)
+	s.notifier.JobPaused(*job, jobLog)
+}
+
+// runCommand executes the job's shell command with its working directory, credentials and resource limits.
+// HTTP jobs call their endpoint instead.
+func (s *Scheduler) runCommand(ctx context.Context, job *models.Job) (executor.Result, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "job.command", trace.WithAttributes(
+		attribute.String("job.type", string(job.Type)),
+	))
+	defer span.End()
+	
+	if job.Type == models.JobTypeHTTP {
+		s.logger.Debug("Calling endpoint for job %s: %s %s", job.Name, job.HTTPMethod, job.Endpoint)
+		
+		result, err := executor.RunHTTP(ctx, executor.HTTPRequest{
+			Method:  job.HTTPMethod,
+			URL:     job.Endpoint,
+			Headers: job.Headers,
+			Body:    job.RequestBody,
+		})
+		span.SetAttributes(attribute.Int("http.status_code", result.ExitCode))
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return result, err
+	}
+	
+	// Jobs stored before the account was removed from the allowlist must not run as it
+	if err := job.CheckRunAs(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return executor.Result{ExitCode: -1}, err
+	}
 	
-	// Simulate success most of the time, occasional failure
-	if time.Now().Unix()%10 == 0 {
-		return "", fmt.Errorf("simulated command failure")
+	sandbox := executor.Sandbox{
+		WorkingDir:   job.WorkingDir,
+		User:         job.RunAsUser,
+		Group:        job.RunAsGroup,
+		CPUSeconds:   uint64(job.CPULimit),
+		MemoryBytes:  uint64(job.MemoryLimit) * 1024 * 1024,
+		MaxOpenFiles: uint64(job.MaxOpenFiles),
+		MaxProcesses: uint64(job.MaxProcesses),
 	}
 	
-	time.Sleep(time.Duration(100+time.Now().UnixNano()%1000) * time.Millisecond)
-	return fmt.Sprintf("Command executed successfully: %s", command), nil
+	s.logger.Debug("Running command for job %s: %s", job.Name, job.Command)
+	
+	result, err := executor.Run(ctx, job.Command, sandbox)
+	span.SetAttributes(attribute.Int("process.exit_code", result.ExitCode))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return result, err
 }
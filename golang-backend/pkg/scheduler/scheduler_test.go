@@ -0,0 +1,39 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"crontab/internal/models"
+)
+
+// Run with -race: the SLA timer fires while the run is still updating its log
+func TestSlowRunIsReportedWhileRunning(t *testing.T) {
+	s, db, project := testScheduler(t)
+	job := models.Job{
+		ProjectID:        project.ID,
+		Name:             "slow export",
+		Command:          "sleep 2",
+		Schedule:         "0 0 3 * * *",
+		Status:           models.JobStatusIdle,
+		Timezone:         "UTC",
+		ExpectedDuration: 1,
+	}
+	if err := db.Create(&job).Error; err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	s.executeJob(context.Background(), job.ID, time.Time{}, models.TriggerManual)
+
+	var run models.JobLog
+	if err := db.First(&run, "job_id = ?", job.ID).Error; err != nil {
+		t.Fatalf("run not recorded: %v", err)
+	}
+	if run.Status != models.JobStatusSuccess || !run.SLABreached {
+		t.Errorf("run: status %s, slaBreached %v, want a successful run past its SLA", run.Status, run.SLABreached)
+	}
+	if run.Duration < 2 {
+		t.Errorf("duration = %v, want the whole run", run.Duration)
+	}
+}
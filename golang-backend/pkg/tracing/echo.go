@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EchoMiddleware starts a server span for every request, continuing any incoming W3C trace context
+func EchoMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			route := c.Path()
+			if route == "" {
+				route = req.URL.Path
+			}
+
+			ctx, span := Tracer().Start(ctx, fmt.Sprintf("%s %s", req.Method, route),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethod(req.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(req.URL.Path),
+				))
+			defer span.End()
+
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				span.RecordError(err)
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPStatusCode(status))
+			if status >= 500 {
+				span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+			}
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey stores the active span on a gorm statement
+const spanKey = "tracing:span"
+
+// GormPlugin creates a client span for every statement executed with a traced context
+// (db.WithContext(ctx)). Statements without a parent span are not traced.
+type GormPlugin struct{}
+
+// Name implements gorm.Plugin
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize implements gorm.Plugin
+func (p GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	registrations := []error{
+		cb.Create().Before("gorm:create").Register("tracing:before_create", p.before("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", p.before("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", p.before("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", p.before("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", p.before("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", p.before("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", p.after),
+	}
+
+	for _, err := range registrations {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p GormPlugin) before(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		if ctx == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			return
+		}
+
+		name := "db." + operation
+		if tx.Statement.Table != "" {
+			name += " " + tx.Statement.Table
+		}
+
+		ctx, span := Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(tx.Dialector.Name()),
+				semconv.DBOperation(operation),
+				semconv.DBSQLTable(tx.Statement.Table),
+			))
+		tx.Statement.Context = ctx
+		tx.InstanceSet(spanKey, span)
+	}
+}
+
+func (p GormPlugin) after(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBStatement(tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+	if tx.Error != nil && tx.Error != gorm.ErrRecordNotFound {
+		span.RecordError(tx.Error)
+		span.SetStatus(codes.Error, tx.Error.Error())
+	}
+}
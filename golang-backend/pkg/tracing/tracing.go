@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"crontab/internal/config"
+)
+
+// instrumentationName identifies the spans created by this service
+const instrumentationName = "crontab"
+
+// Setup configures the global tracer provider and W3C trace context propagation.
+//
+// Spans are exported over OTLP/HTTP when TRACING_ENABLED is true:
+//   - OTEL_EXPORTER_OTLP_ENDPOINT: collector host:port (default "localhost:4318")
+//   - OTEL_EXPORTER_OTLP_INSECURE: use plain HTTP (default true)
+//   - OTEL_SERVICE_NAME: service name resource attribute (default "crontab-api")
+//   - TRACING_SAMPLE_RATIO: fraction of root traces to sample (default 1.0)
+//
+// The returned function flushes and shuts down the provider.
+func Setup(cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.GetBool("TRACING_ENABLED", false) {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(cfg.GetString("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318")),
+	}
+	if cfg.GetBool("OTEL_EXPORTER_OTLP_INSECURE", true) {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+	}
+
+	ratio := 1.0
+	if value := cfg.GetString("TRACING_SAMPLE_RATIO", ""); value != "" {
+		if _, err := fmt.Sscanf(value, "%g", &ratio); err != nil {
+			return nil, fmt.Errorf("invalid TRACING_SAMPLE_RATIO %q: %v", value, err)
+		}
+	}
+
+	provider := NewProvider(exporter, cfg.GetString("OTEL_SERVICE_NAME", "crontab-api"),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))))
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewProvider creates a tracer provider that batches spans to the given exporter.
+// Tests can pass an in-memory exporter (go.opentelemetry.io/otel/sdk/trace/tracetest)
+// and install the provider with otel.SetTracerProvider.
+func NewProvider(exporter sdktrace.SpanExporter, serviceName string, options ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	options = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	}, options...)
+
+	return sdktrace.NewTracerProvider(options...)
+}
+
+// Tracer returns the tracer used for all spans of the service
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// TraceID returns the hex trace ID of the span in ctx, or "" when there is none
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// InjectHTTP writes the trace context of ctx into the headers of an outgoing request
+func InjectHTTP(ctx context.Context, req *http.Request) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+}
+
+// Detach returns a context that keeps the span of ctx but not its cancellation,
+// so work started by a request can continue after the response was sent
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx))
+}
@@ -0,0 +1,200 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// incomingParent is the W3C traceparent of a caller outside the service
+const incomingParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// installProvider makes an in-memory exporter the destination of all spans for the test
+func installProvider(t *testing.T) (*tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(exporter, "crontab-test")
+
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return exporter, provider
+}
+
+// endedSpans flushes the provider and returns the spans exported so far
+func endedSpans(t *testing.T, exporter *tracetest.InMemoryExporter, provider *sdktrace.TracerProvider) tracetest.SpanStubs {
+	t.Helper()
+
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+	return exporter.GetSpans()
+}
+
+func findSpan(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("span %q not exported, got %d spans", name, len(spans))
+	return tracetest.SpanStub{}
+}
+
+func TestEchoMiddlewareContinuesIncomingTrace(t *testing.T) {
+	exporter, provider := installProvider(t)
+
+	e := echo.New()
+	e.Use(EchoMiddleware())
+
+	var handlerTraceID string
+	e.GET("/jobs/:id", func(c echo.Context) error {
+		handlerTraceID = TraceID(c.Request().Context())
+		return c.NoContent(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/42", nil)
+	req.Header.Set("traceparent", incomingParent)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	span := findSpan(t, endedSpans(t, exporter, provider), "GET /jobs/:id")
+	if span.SpanKind != trace.SpanKindServer {
+		t.Errorf("span kind = %v, want server", span.SpanKind)
+	}
+	if got := span.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace ID = %s, want the incoming trace", got)
+	}
+	if got := span.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span ID = %s, want the incoming span", got)
+	}
+	if handlerTraceID != span.SpanContext.TraceID().String() {
+		t.Errorf("handler saw trace %q, want %s", handlerTraceID, span.SpanContext.TraceID())
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("status = %v, want error for a 500 response", span.Status.Code)
+	}
+}
+
+func TestGormPluginTracesStatementsUnderParent(t *testing.T) {
+	exporter, provider := installProvider(t)
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "tracing.db")), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatalf("install plugin: %v", err)
+	}
+
+	type widget struct {
+		ID   uint
+		Name string
+	}
+	if err := db.AutoMigrate(&widget{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// Statements without a parent span are not traced
+	if err := db.Create(&widget{Name: "untraced"}).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if spans := endedSpans(t, exporter, provider); len(spans) != 0 {
+		t.Fatalf("got %d spans for untraced statements, want 0", len(spans))
+	}
+
+	ctx, parent := Tracer().Start(context.Background(), "job.execute")
+	if err := db.WithContext(ctx).Create(&widget{Name: "traced"}).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	var found widget
+	if err := db.WithContext(ctx).First(&found, "name = ?", "traced").Error; err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	parent.End()
+
+	spans := endedSpans(t, exporter, provider)
+	for _, name := range []string{"db.create widgets", "db.query widgets"} {
+		span := findSpan(t, spans, name)
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s: parent = %s, want %s", name, span.Parent.SpanID(), parent.SpanContext().SpanID())
+		}
+		if span.SpanKind != trace.SpanKindClient {
+			t.Errorf("%s: span kind = %v, want client", name, span.SpanKind)
+		}
+	}
+}
+
+func TestInjectHTTPPropagatesTraceContext(t *testing.T) {
+	exporter, provider := installProvider(t)
+
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	ctx, span := Tracer().Start(context.Background(), "job.command")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	InjectHTTP(ctx, req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	span.End()
+
+	exported := findSpan(t, endedSpans(t, exporter, provider), "job.command")
+	want := "00-" + exported.SpanContext.TraceID().String() + "-" + exported.SpanContext.SpanID().String() + "-01"
+	if got := <-received; got != want {
+		t.Errorf("traceparent = %q, want %q", got, want)
+	}
+}
+
+func TestDetachKeepsSpanWithoutCancellation(t *testing.T) {
+	installProvider(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, span := Tracer().Start(ctx, "POST /api/jobs/:id/run")
+	defer span.End()
+
+	detached := Detach(ctx)
+	cancel()
+
+	if detached.Err() != nil {
+		t.Errorf("detached context was cancelled with its parent: %v", detached.Err())
+	}
+	if TraceID(detached) != span.SpanContext().TraceID().String() {
+		t.Errorf("TraceID(detached) = %q, want %s", TraceID(detached), span.SpanContext().TraceID())
+	}
+	if TraceID(context.Background()) != "" {
+		t.Errorf("TraceID without a span = %q, want empty", TraceID(context.Background()))
+	}
+}